@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"lib/apierr"
+	"lib/interrupt"
+	"lib/logging"
+	"lib/picker"
+	"lib/queue"
+	"lib/util"
+
+	"github.com/jessevdk/go-flags"
+	"github.com/pkg/errors"
+)
+
+// Exit codes let scripts tell kinds of failures apart.
+const (
+	exitFailure     = 1
+	exitUsage       = 2
+	exitNotFound    = 3
+	exitAuth        = 4
+	exitForbidden   = 5
+	exitRateLimited = 6
+	exitValidation  = 7
+	exitConflict    = 8
+	exitUnreachable = 9
+	exitInterrupted = 130
+)
+
+// report prints err along with a hint what to do about it
+// and returns exit code for the kind of failure.
+func report(err error) int {
+	code, hint := classify(err)
+	if code == exitInterrupted {
+		// user has been told already
+		return code
+	}
+	logging.Errorf("%s", err)
+	if hint != "" {
+		fmt.Fprintln(os.Stderr, hint)
+	}
+	return code
+}
+
+func classify(err error) (int, string) {
+	if interrupt.Canceled(err) || errors.Cause(err) == picker.ErrCanceled {
+		return exitInterrupted, ""
+	}
+	switch errors.Cause(err).(type) {
+	case *flags.Error, util.UsageError:
+		return exitUsage, ""
+	}
+
+	if e := apierr.Of(err); e != nil {
+		switch e.Kind {
+		case apierr.Unauthorized:
+			return exitAuth, fmt.Sprintf("%s rejected stored credentials. "+
+				"Run 'jigit config --logout' to enter them again.", e.Service)
+		case apierr.Forbidden:
+			return exitForbidden, fmt.Sprintf("Your %s account has no permission for it, "+
+				"ask project maintainers for access.", e.Service)
+		case apierr.NotFound:
+			return exitNotFound, "Check issue ID or project name, " +
+				"it may also be hidden from your account."
+		case apierr.RateLimited:
+			return exitRateLimited, fmt.Sprintf("%s limits rate of requests, "+
+				"wait a bit before trying again.", e.Service)
+		case apierr.Validation:
+			return exitValidation, fmt.Sprintf("%s rejected provided values, fix them and try again.", e.Service)
+		case apierr.Conflict:
+			return exitConflict, "It has been changed meanwhile, fetch it again and retry."
+		}
+	}
+	if queue.Unreachable(err) {
+		return exitUnreachable, "Check network connection and addresses shown by 'jigit config --get'."
+	}
+	return exitFailure, ""
+}
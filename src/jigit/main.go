@@ -4,40 +4,100 @@ import (
 	"fmt"
 	"os"
 
+	"lib/interrupt"
+	"lib/logging"
+	"lib/pager"
+	"lib/theme"
+	"subcmd/attach"
 	"subcmd/commit"
 	"subcmd/config"
+	"subcmd/field"
+	"subcmd/importer"
 	"subcmd/link"
 	"subcmd/list"
 	newp "subcmd/new"
+	"subcmd/queue"
+	"subcmd/recovery"
+	"subcmd/search"
+	"subcmd/tui"
+	"subcmd/users"
 
 	"github.com/jessevdk/go-flags"
+	"golang.org/x/crypto/ssh/terminal"
 )
 
 var cfg struct {
-	SubAdd     newp.Cmd   `command:"add" description:"create new issue"`
-	SubLs      list.Cmd   `command:"ls" description:"list projects or issues at JIRA or GitLab"`
-	SubLn      link.Cmd   `command:"ln" description:"link GitLab issue with JIRA ticket (or vice versa)"`
-	SubConfig  config.Cmd `command:"config" description:"configuration stuff"`
-	SubCommit  commit.Cmd `command:"commit" description:"create, update or delete comments on task"`
-	SubVersion VersionCmd `command:"version" description:"print current jigit version"`
+	Verbose []bool `short:"v" long:"verbose" description:"report progress, repeat (-vv) to trace HTTP requests"`
+	Quiet   bool   `short:"q" long:"quiet" description:"report errors only"`
+	LogFile string `long:"log-file" value-name:"PATH" description:"append JSON log of all levels to file"`
+	NoPager bool   `long:"no-pager" description:"print long output to stdout instead of pager"`
+	Color   string `long:"color" default:"auto" optional:"yes" optional-value:"always" choice:"auto" choice:"always" choice:"never" description:"color output, auto colors terminals unless NO_COLOR is set"`
+
+	SubAdd     newp.Cmd     `command:"add" description:"create new issue"`
+	SubLs      list.Cmd     `command:"ls" description:"list projects or issues at JIRA or GitLab"`
+	SubSearch  search.Cmd   `command:"search" description:"search Jira tickets with JQL or saved queries"`
+	SubLn      link.Cmd     `command:"ln" description:"link GitLab issue with JIRA ticket (or vice versa)"`
+	SubConfig  config.Cmd   `command:"config" description:"configuration stuff"`
+	SubCommit  commit.Cmd   `command:"commit" description:"create, update or delete comments on task"`
+	SubAttach  attach.Cmd   `command:"attach" description:"attach files to issue in both GitLab and Jira"`
+	SubField   field.Cmd    `command:"field" description:"list or set Jira fields of ticket by their names"`
+	SubImport  importer.Cmd `command:"import" description:"import issues from one tracker into another and link them"`
+	SubQueue   queue.Cmd    `command:"queue" description:"manage operations postponed while offline"`
+	SubRecover recovery.Cmd `command:"recover" description:"resume or roll back operations interrupted by crash or network failure"`
+	SubUsers   users.Cmd    `command:"users" description:"map GitLab users to Jira users"`
+	SubTui     tui.Cmd      `command:"tui" description:"browse issues with linked Jira tickets and act on them in terminal UI"`
+	SubVersion VersionCmd   `command:"version" description:"print current jigit version"`
 }
 
 func main() {
-	if _, err := flags.Parse(&cfg); err != nil {
-		os.Exit(1)
+	code := run()
+	logging.Close()
+	os.Exit(code)
+}
+
+// run executes command and returns exit code.
+func run() int {
+	// commands started by the parser take context from interrupt package
+	interrupt.Notify()
+	// errors are reported along with hints and exit codes below
+	parser := flags.NewParser(&cfg, flags.HelpFlag|flags.PassDoubleDash)
+	// global flags are known only once arguments are parsed
+	parser.CommandHandler = func(cmd flags.Commander, args []string) error {
+		level := logging.Verbosity(len(cfg.Verbose), cfg.Quiet)
+		if err := logging.Setup(level, cfg.LogFile); err != nil {
+			return err
+		}
+		if cfg.NoPager {
+			pager.Disable()
+		}
+		fd := int(os.Stdout.Fd())
+		tty := terminal.IsTerminal(fd)
+		columns, _, _ := terminal.GetSize(fd)
+		if err := theme.Setup(cfg.Color, tty, columns); err != nil {
+			return err
+		}
+		return cmd.Execute(args)
+	}
+	if _, err := parser.Parse(); err != nil {
+		if e, ok := err.(*flags.Error); ok && e.Type == flags.ErrHelp {
+			fmt.Println(e.Message)
+			return 0
+		}
+		return report(err)
 	}
 
 	var err error
 	switch {
 	case cfg.SubLs.Active:
-		err = list.Process(cfg.SubLs)
+		err = list.Process(interrupt.Context(), cfg.SubLs)
 	case cfg.SubConfig.Active:
 		err = config.Process(cfg.SubConfig)
 	}
 
 	if err != nil {
-		fmt.Println(err)
+		return report(err)
 	}
+	return 0
 }
 
 var (
@@ -0,0 +1,253 @@
+// Package apierr classifies requests rejected by GitLab or Jira, so commands
+// could tell user what went wrong and what to do about it.
+package apierr
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"lib/transport"
+)
+
+// Kind is a class of failure user acts upon differently.
+type Kind int
+
+const (
+	Unknown Kind = iota
+	NotFound
+	Unauthorized
+	Forbidden
+	RateLimited
+	Validation
+	Conflict
+)
+
+var kindNames = map[Kind]string{
+	Unknown:      "request failed",
+	NotFound:     "not found",
+	Unauthorized: "unauthorized",
+	Forbidden:    "forbidden",
+	RateLimited:  "rate limited",
+	Validation:   "validation failed",
+	Conflict:     "conflict",
+}
+
+func (k Kind) String() string {
+	return kindNames[k]
+}
+
+// Error is a request rejected by GitLab or Jira.
+type Error struct {
+	Kind      Kind
+	Service   string
+	Status    int
+	RequestID string
+	// Message is what server said about the failure, if anything.
+	Message string
+	// Fields holds validation errors by field name.
+	Fields map[string]string
+	// Wait is how long server asked to wait before the next request.
+	Wait time.Duration
+	// Err is the original error of client library.
+	Err error
+}
+
+func (e *Error) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = e.Kind.String()
+	}
+	// GitLab puts status into messages itself
+	if status := strconv.Itoa(e.Status); e.Status != 0 && !strings.HasPrefix(msg, status) {
+		msg += fmt.Sprintf(" (HTTP %s)", status)
+	}
+	msg = e.Service + ": " + msg
+	if len(e.Fields) > 0 {
+		names := make([]string, 0, len(e.Fields))
+		for name := range e.Fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			msg += fmt.Sprintf("\n\t%s: %s", name, e.Fields[name])
+		}
+	}
+	if e.Wait > 0 {
+		msg += fmt.Sprintf(", try again in %s", e.Wait.Round(time.Second))
+	}
+	if e.RequestID != "" {
+		msg += fmt.Sprintf(" [request ID %s]", e.RequestID)
+	}
+	return msg
+}
+
+// Errorf returns error of kind which isn't tied to a response, e.g. when
+// search for an item by name finds nothing.
+func Errorf(service string, kind Kind, format string, args ...interface{}) *Error {
+	return &Error{Kind: kind, Service: service, Message: fmt.Sprintf(format, args...)}
+}
+
+// Of returns typed error err is caused by, nil if request wasn't rejected.
+// Errors wrapped with github.com/pkg/errors are unwrapped.
+func Of(err error) *Error {
+	for err != nil {
+		if e, ok := err.(*Error); ok {
+			return e
+		}
+		c, ok := err.(interface{ Cause() error })
+		if !ok {
+			return nil
+		}
+		err = c.Cause()
+	}
+	return nil
+}
+
+// Is reports if err is a request rejected for the reason of kind.
+func Is(err error, kind Kind) bool {
+	e := Of(err)
+	return e != nil && e.Kind == kind
+}
+
+// Check turns response of service which has status other than expected into
+// typed error. Network failures are returned as they are, so they could be
+// told apart from rejected requests.
+func Check(service string, resp *http.Response, err error, expected int) error {
+	if err != nil {
+		if e, ok := err.(*url.Error); ok {
+			if r, ok := e.Err.(*transport.RetryError); ok {
+				return fromRetry(service, r, err)
+			}
+		}
+		if resp == nil {
+			return err
+		}
+		return New(service, resp, "", err)
+	}
+	if resp.StatusCode == expected {
+		return nil
+	}
+	return New(service, resp, "", nil)
+}
+
+// New returns error of failed response. If message is empty, it's read from
+// response body, which both GitLab and Jira fill with JSON description.
+func New(service string, resp *http.Response, message string, err error) *Error {
+	e := &Error{
+		Kind:      kindOf(resp.StatusCode),
+		Service:   service,
+		Status:    resp.StatusCode,
+		RequestID: requestID(resp.Header),
+		Message:   message,
+		Err:       err,
+	}
+	if message == "" && resp.Body != nil {
+		e.Message, e.Fields = parseBody(resp)
+	}
+	if e.Message == "" && len(e.Fields) == 0 && err != nil && e.Kind == Unknown {
+		e.Message = err.Error()
+	}
+	return e
+}
+
+// fromRetry returns error of request which kept failing after retries.
+func fromRetry(service string, r *transport.RetryError, err error) *Error {
+	e := &Error{
+		Kind:    kindOf(r.Status),
+		Service: service,
+		Status:  r.Status,
+		Wait:    r.Wait,
+		Err:     err,
+	}
+	// Jira throttles with 503 and Retry-After
+	if r.Status == http.StatusServiceUnavailable && r.Wait > 0 {
+		e.Kind = RateLimited
+	}
+	if r.Attempts > 1 {
+		e.Message = fmt.Sprintf("gave up after %d attempts", r.Attempts)
+	}
+	return e
+}
+
+func kindOf(status int) Kind {
+	switch status {
+	case http.StatusNotFound:
+		return NotFound
+	case http.StatusUnauthorized:
+		return Unauthorized
+	case http.StatusForbidden:
+		return Forbidden
+	case http.StatusTooManyRequests:
+		return RateLimited
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return Validation
+	case http.StatusConflict:
+		return Conflict
+	}
+	return Unknown
+}
+
+func requestID(h http.Header) string {
+	// GitLab, Jira Server and Jira Cloud respectively
+	for _, key := range []string{"X-Request-Id", "X-Arequestid", "Atl-Traceid"} {
+		if id := h.Get(key); id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+// parseBody reads error description of GitLab:
+//
+//	{"message": "404 Project Not Found"}
+//	{"message": {"title": ["can't be blank"]}}
+//	{"error": "invalid_token"}
+//
+// or Jira:
+//
+//	{"errorMessages": ["Issue does not exist"], "errors": {"summary": "required"}}
+func parseBody(resp *http.Response) (string, map[string]string) {
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil || len(data) == 0 {
+		return "", nil
+	}
+	var body struct {
+		Message       json.RawMessage `json:"message"`
+		Error         string          `json:"error"`
+		ErrorMessages []string        `json:"errorMessages"`
+		Errors        json.RawMessage `json:"errors"`
+	}
+	if err := json.Unmarshal(data, &body); err != nil {
+		// HTML pages of proxies aren't worth showing
+		return "", nil
+	}
+
+	messages := body.ErrorMessages
+	var fields map[string]string
+	json.Unmarshal(body.Errors, &fields)
+	var text string
+	var byField map[string][]string
+	switch {
+	case json.Unmarshal(body.Message, &text) == nil && text != "":
+		messages = append(messages, text)
+	case json.Unmarshal(body.Message, &byField) == nil:
+		for name, errs := range byField {
+			if fields == nil {
+				fields = make(map[string]string)
+			}
+			fields[name] = strings.Join(errs, ", ")
+		}
+	}
+	if body.Error != "" {
+		messages = append(messages, body.Error)
+	}
+	return strings.Join(messages, "; "), fields
+}
@@ -0,0 +1,49 @@
+package apierr
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func response(status int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"X-Request-Id": {"abc"}},
+		Body:       ioutil.NopCloser(strings.NewReader(body)),
+	}
+}
+
+func TestCheckGitLabValidation(t *testing.T) {
+	err := Check("GitLab", response(400, `{"message": {"title": ["can't be blank"]}}`), nil, http.StatusCreated)
+	e := Of(err)
+	if e == nil || e.Kind != Validation {
+		t.Fatalf("expected validation error, got %#v", err)
+	}
+	if e.Fields["title"] != "can't be blank" || e.RequestID != "abc" {
+		t.Errorf("unexpected details: %#v", e)
+	}
+}
+
+func TestCheckJiraNotFound(t *testing.T) {
+	body := `{"errorMessages": ["Issue does not exist"], "errors": {}}`
+	err := Check("Jira", response(404, body), errors.New("request failed"), http.StatusOK)
+	if !Is(err, NotFound) {
+		t.Fatalf("expected not found error, got %#v", err)
+	}
+	if msg := err.Error(); msg != "Jira: Issue does not exist (HTTP 404) [request ID abc]" {
+		t.Errorf("unexpected message %q", msg)
+	}
+}
+
+func TestCheckNetworkError(t *testing.T) {
+	failure := errors.New("connection refused")
+	if err := Check("GitLab", nil, failure, http.StatusOK); err != failure {
+		t.Errorf("expected network error to be returned as is, got %#v", err)
+	}
+	if err := Check("GitLab", response(200, ""), nil, http.StatusOK); err != nil {
+		t.Errorf("unexpected error %s", err)
+	}
+}
@@ -0,0 +1,147 @@
+// Package attach copies files referenced from issue texts between GitLab and Jira,
+// so screenshots and logs survive mirroring of descriptions and comments.
+package attach
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"lib/git"
+	"lib/jira"
+
+	"github.com/pkg/errors"
+)
+
+var reMdRef = regexp.MustCompile(`(!?)\[([^\]]*)\]\(([^)\s]+)\)`)
+
+// ToJira copies GitLab uploads referenced from markdown text into attachments of
+// Jira issue and returns text with references pointing to the attachments.
+// References which can't be copied are rewritten into absolute GitLab links,
+// so returned text is usable even along with an error.
+func ToJira(ctx context.Context, gitc *git.Git, jirac *jira.Jira, p *git.Project, key, text string) (string, error) {
+	var (
+		copied = make(map[string]*jira.Attachment)
+		failed = make([]string, 0)
+	)
+	for _, ref := range git.FindUploads(text) {
+		a, ok := copied[ref.URL]
+		if !ok {
+			buf := new(bytes.Buffer)
+			err := gitc.Download(ctx, p, &ref, buf)
+			if err == nil {
+				a, err = jirac.Attach(ctx, key, ref.Filename(), buf)
+			}
+			if err != nil {
+				failed = append(failed, ref.Filename()+": "+err.Error())
+				text = strings.Replace(text, ref.Match, git.AbsoluteUploads(p, ref.Match), 1)
+				continue
+			}
+			copied[ref.URL] = a
+		}
+		text = strings.Replace(text, ref.Match, reference(ref.Image, ref.Alt, jirac.AttachmentRef(a, ref.Image)), 1)
+	}
+	return text, failure(failed)
+}
+
+// Description copies GitLab uploads referenced from description of just created
+// Jira issue and points the description to attachments. Issue is expected to be
+// created with absolute GitLab links, see git.AbsoluteUploads.
+func Description(ctx context.Context, gitc *git.Git, jirac *jira.Jira, p *git.Project, key, text string) error {
+	if len(git.FindUploads(text)) == 0 {
+		return nil
+	}
+	text, copyErr := ToJira(ctx, gitc, jirac, p, key, text)
+	if err := jirac.SetDescription(ctx, key, text); err != nil {
+		return err
+	}
+	return copyErr
+}
+
+// ToGit copies attachments of Jira ticket referenced from markdown text into
+// uploads of GitLab project and returns text with references pointing to the uploads.
+// References which can't be copied are rewritten into links to Jira.
+func ToGit(ctx context.Context, gitc *git.Git, jirac *jira.Jira, pid int, ticket *jira.Issue, text string) (string, error) {
+	if len(ticket.Attachments) == 0 {
+		return text, nil
+	}
+	dir, err := ioutil.TempDir("", "jigit")
+	if err != nil {
+		return text, err
+	}
+	defer os.RemoveAll(dir)
+
+	var (
+		uploaded = make(map[string]string)
+		failed   = make([]string, 0)
+	)
+	text = reMdRef.ReplaceAllStringFunc(text, func(ref string) string {
+		m := reMdRef.FindStringSubmatch(ref)
+		a := findAttachment(ticket.Attachments, m[3])
+		if a == nil {
+			return ref
+		}
+		url, ok := uploaded[a.ID]
+		if !ok {
+			upload, err := uploadAttachment(ctx, gitc, jirac, pid, a, dir)
+			if err != nil {
+				failed = append(failed, a.Filename+": "+err.Error())
+				return reference(m[1] == "!", m[2], a.Content)
+			}
+			url = upload.URL
+			uploaded[a.ID] = url
+		}
+		return reference(m[1] == "!", m[2], url)
+	})
+	return text, failure(failed)
+}
+
+// Jira markup refers to attachments by file name, prefixed with ^ in links,
+// ADF documents by URL.
+func findAttachment(attachments []jira.Attachment, ref string) *jira.Attachment {
+	for i := range attachments {
+		a := &attachments[i]
+		if ref == a.Filename || ref == "^"+a.Filename || ref == a.Content {
+			return a
+		}
+	}
+	return nil
+}
+
+// GitLab uploads only files from disk, keeping their names.
+func uploadAttachment(ctx context.Context, gitc *git.Git, jirac *jira.Jira, pid int, a *jira.Attachment, dir string) (*git.Upload, error) {
+	path := filepath.Join(dir, a.ID, filepath.Base(a.Filename))
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	err = jirac.Download(ctx, a, f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return gitc.UploadFile(ctx, pid, path)
+}
+
+func reference(image bool, alt, url string) string {
+	if image {
+		return "![" + alt + "](" + url + ")"
+	}
+	return "[" + alt + "](" + url + ")"
+}
+
+func failure(failed []string) error {
+	if len(failed) == 0 {
+		return nil
+	}
+	return errors.Errorf("can't copy %s", strings.Join(failed, "; "))
+}
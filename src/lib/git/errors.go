@@ -0,0 +1,22 @@
+package git
+
+import (
+	"net/http"
+
+	"lib/apierr"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// check turns failed request or response with status other than expected
+// into typed error. GitLab client reads error description itself.
+func check(resp *gitlab.Response, err error, expected int) error {
+	var r *http.Response
+	if resp != nil {
+		r = resp.Response
+	}
+	if e, ok := err.(*gitlab.ErrorResponse); ok && r != nil {
+		return apierr.New("GitLab", r, e.Message, err)
+	}
+	return apierr.Check("GitLab", r, err, expected)
+}
@@ -0,0 +1,114 @@
+package git
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Scopes of issue listings.
+const (
+	ScopeCreatedByMe  = "created_by_me"
+	ScopeAssignedToMe = "assigned_to_me"
+	ScopeAll          = "all"
+)
+
+// IssueFilter narrows down issue listings, zero values are ignored.
+// Issues are listed in the project if ProjectID is set, in the group
+// if Group is set, and globally otherwise.
+type IssueFilter struct {
+	ProjectID int
+	Group     string
+
+	State         string // opened or closed, both if empty
+	Labels        []string
+	Milestone     string
+	Author        string // GitLab username
+	Assignee      string // GitLab username
+	Search        string
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	UpdatedAfter  time.Time
+	UpdatedBefore time.Time
+	Confidential  *bool  // only confidential or only public issues if set
+	OrderBy       string // created_at or updated_at
+	Sort          string // asc or desc
+	Scope         string
+
+	Limit int // 0 means all matching issues
+}
+
+// issueQuery is query string of issue listing endpoints.
+type issueQuery struct {
+	listQuery
+	State         string     `url:"state,omitempty"`
+	Labels        string     `url:"labels,omitempty"`
+	Milestone     string     `url:"milestone,omitempty"`
+	AuthorID      int        `url:"author_id,omitempty"`
+	AssigneeID    int        `url:"assignee_id,omitempty"`
+	Search        string     `url:"search,omitempty"`
+	CreatedAfter  *time.Time `url:"created_after,omitempty"`
+	CreatedBefore *time.Time `url:"created_before,omitempty"`
+	UpdatedAfter  *time.Time `url:"updated_after,omitempty"`
+	UpdatedBefore *time.Time `url:"updated_before,omitempty"`
+	Confidential  *bool      `url:"confidential,omitempty"`
+	OrderBy       string     `url:"order_by,omitempty"`
+	Sort          string     `url:"sort,omitempty"`
+	Scope         string     `url:"scope,omitempty"`
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// ListIssues returns issues matching the filter.
+func (git *Git) ListIssues(ctx context.Context, f *IssueFilter) ([]*Issue, error) {
+	issues := make([]*Issue, 0)
+	err := git.EachIssue(ctx, f, func(page []*Issue) error {
+		issues = append(issues, page...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return issues, nil
+}
+
+func (git *Git) newIssueQuery(ctx context.Context, f *IssueFilter) (*issueQuery, error) {
+	q := &issueQuery{
+		listQuery:     listQuery{PerPage: pageSize(f.Limit)},
+		State:         f.State,
+		Labels:        strings.Join(f.Labels, ","),
+		Milestone:     f.Milestone,
+		Search:        f.Search,
+		CreatedAfter:  optionalTime(f.CreatedAfter),
+		CreatedBefore: optionalTime(f.CreatedBefore),
+		UpdatedAfter:  optionalTime(f.UpdatedAfter),
+		UpdatedBefore: optionalTime(f.UpdatedBefore),
+		Confidential:  f.Confidential,
+		OrderBy:       f.OrderBy,
+		Sort:          f.Sort,
+		Scope:         f.Scope,
+	}
+	// API of our GitLab filters people by IDs only
+	if f.Author != "" {
+		u, err := git.UserByLogin(ctx, strings.TrimPrefix(f.Author, "@"))
+		if err != nil {
+			return nil, errors.Wrap(err, "can't resolve author")
+		}
+		q.AuthorID = u.ID
+	}
+	if f.Assignee != "" {
+		u, err := git.UserByLogin(ctx, strings.TrimPrefix(f.Assignee, "@"))
+		if err != nil {
+			return nil, errors.Wrap(err, "can't resolve assignee")
+		}
+		q.AssigneeID = u.ID
+	}
+	return q, nil
+}
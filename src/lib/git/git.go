@@ -2,16 +2,21 @@ package git
 
 import (
 	"bytes"
+	"context"
 	"encoding/gob"
 	"fmt"
 	"io"
 	"net/http"
-	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"lib/apierr"
+	"lib/logging"
+	"lib/parallel"
 	"lib/storage"
+	"lib/transport"
 	"lib/util"
 	"subcmd/config"
 
@@ -33,9 +38,9 @@ const (
 
 func convertState(state string) (ist IssueState) {
 	switch strings.ToUpper(state) {
-	case "OPEN":
+	case "OPEN", "OPENED":
 		ist = IssueStateOpen
-	case "CLOSE":
+	case "CLOSE", "CLOSED":
 		ist = IssueStateClose
 	case "REOPEN":
 		ist = IssueStateReopen
@@ -73,19 +78,58 @@ func NewWithStorage(store *storage.Storage) (*Git, error) {
 	return &Git{cfg: cfg, storage: store}, nil
 }
 
-func (git *Git) User() (*User, error) {
-	git.InitClient()
-
-	u, resp, err := git.client.Users.CurrentUser()
-	if err != nil {
+func (git *Git) User(ctx context.Context) (*User, error) {
+	if err := git.InitClient(); err != nil {
 		return nil, err
 	}
-	if resp.StatusCode != http.StatusOK {
-		return nil, errors.New("bad response HTTP code")
+
+	u, resp, err := git.client.Users.CurrentUser(gitlab.WithContext(ctx))
+	if err = check(resp, err, http.StatusOK); err != nil {
+		return nil, err
 	}
 	return stripUser(u), nil
 }
 
+// UserByLogin looks up GitLab user by username.
+func (git *Git) UserByLogin(ctx context.Context, login string) (*User, error) {
+	if err := git.InitClient(); err != nil {
+		return nil, err
+	}
+
+	opt := &gitlab.ListUsersOptions{Username: gitlab.String(login)}
+	users, resp, err := git.client.Users.ListUsers(opt, gitlab.WithContext(ctx))
+	if err = check(resp, err, http.StatusOK); err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, apierr.Errorf("GitLab", apierr.NotFound, "user '%s' not found", login)
+	}
+	return stripUser(users[0]), nil
+}
+
+// ListUsers returns all active GitLab users visible to current user.
+func (git *Git) ListUsers(ctx context.Context) ([]*User, error) {
+	if err := git.InitClient(); err != nil {
+		return nil, err
+	}
+
+	q := &struct {
+		listQuery
+		Active bool `url:"active,omitempty"`
+	}{listQuery{PerPage: 100}, true}
+
+	var page []*gitlab.User
+	users := make([]*User, 0)
+	err := git.paginate(ctx, "users", q, &page, func() error {
+		users = append(users, compactUsers(page)...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
 // Lazy gitlab client initialization
 func (git *Git) InitClient() error {
 	if git.ready {
@@ -98,13 +142,19 @@ func (git *Git) InitClient() error {
 
 	var key []byte
 	if git.cfg.Storage.Encrypt {
-		key = util.AskPassphrase()
+		var err error
+		if key, err = util.AskPassphrase(); err != nil {
+			return err
+		}
 	}
 
 	login, pass, err := git.credentials(key)
 	if err != nil {
 		// unauthorized, ask credentials
-		login, pass = util.AskCredentials(git.endpoint)
+		login, pass, err = util.AskCredentials(git.endpoint)
+		if err != nil {
+			return err
+		}
 		encLogin, encPass := []byte(login), []byte(pass)
 
 		// encode creds if needed
@@ -129,9 +179,11 @@ func (git *Git) InitClient() error {
 		}
 	}
 
-	fmt.Printf("Connecting to '%s'\n", git.endpoint)
+	logging.Infof("Connecting to '%s'", git.endpoint)
 
-	git.client, err = gitlab.NewBasicAuthClient(nil, git.endpoint, login, pass)
+	// timeout is applied to every attempt of retried requests
+	httpClient := &http.Client{Transport: transport.New("GitLab", git.cfg.GitLabTimeout())}
+	git.client, err = gitlab.NewBasicAuthClient(httpClient, git.endpoint, login, pass)
 	if err != nil {
 		return err
 	}
@@ -139,12 +191,12 @@ func (git *Git) InitClient() error {
 	return nil
 }
 
-func (git *Git) Project(name string) (*Project, error) {
-	fmt.Printf("Fetching GitLab project\n")
+func (git *Git) Project(ctx context.Context, name string) (*Project, error) {
+	logging.Infof("Fetching GitLab project %s", name)
 
 	proj, err := git.storage.Get(storage.BucketGitProjectCache, []byte(name))
 	if err != nil {
-		return git.fetchRemoteProject(name)
+		return git.fetchRemoteProject(ctx, name)
 	}
 
 	project := new(Project)
@@ -154,48 +206,54 @@ func (git *Git) Project(name string) (*Project, error) {
 	return project, nil
 }
 
-func (git *Git) Issue(id int) (*Issue, error) {
-	fmt.Printf("Fetching GitLab issue '%d'\n", id)
+func (git *Git) Issue(ctx context.Context, id int) (*Issue, error) {
+	if err := git.InitClient(); err != nil {
+		return nil, err
+	}
+	logging.Infof("Fetching GitLab issue #%d", id)
 	opt := &gitlab.ListIssuesOptions{IIDs: []int{id}}
 
-	issue, resp, err := git.client.Issues.ListIssues(opt)
-	if err != nil {
+	issue, resp, err := git.client.Issues.ListIssues(opt, gitlab.WithContext(ctx))
+	if err = check(resp, err, http.StatusOK); err != nil {
 		return nil, err
 	}
-	if resp.StatusCode != http.StatusOK {
-		return nil, errors.New("bad status returned")
-	}
 	if len(issue) > 1 {
 		return nil, errors.New("server respond with wrong issues count")
 	}
 	if len(issue) == 0 {
-		return nil, errors.New("issue not found")
+		return nil, apierr.Errorf("GitLab", apierr.NotFound, "issue %d not found", id)
 	}
 
 	return compactIssues(issue)[0], nil
 }
 
-func (git *Git) CreateIssue(issue *Issue) (*Issue, error) {
-	util.Debug("Creating issue at project %v", issue.ProjectID)
+func (git *Git) CreateIssue(ctx context.Context, issue *Issue) (*Issue, error) {
+	if err := git.InitClient(); err != nil {
+		return nil, err
+	}
+	logging.Debugf("Creating issue at project %v", issue.ProjectID)
 	opt := &gitlab.CreateIssueOptions{
 		Title:       gitlab.String(issue.Title),
 		Labels:      gitlab.Labels(issue.Labels),
 		Description: gitlab.String(issue.Description),
-		//AssigneeIDs: git.InitClient()
+	}
+	if issue.AssigneeUsername != "" {
+		u, err := git.UserByLogin(ctx, issue.AssigneeUsername)
+		if err != nil {
+			return nil, errors.Wrap(err, "can't resolve assignee")
+		}
+		opt.AssigneeIDs = []int{u.ID}
 	}
 
-	newIssue, resp, err := git.client.Issues.CreateIssue(issue.ProjectID, opt)
-	if err != nil {
+	newIssue, resp, err := git.client.Issues.CreateIssue(issue.ProjectID, opt, gitlab.WithContext(ctx))
+	if err = check(resp, err, http.StatusCreated); err != nil {
 		return nil, err
 	}
-	if resp.StatusCode != http.StatusCreated {
-		return nil, errors.Errorf("unexpected HTTP status %d returned", resp.StatusCode)
-	}
 	return compactIssues([]*gitlab.Issue{newIssue})[0], nil
 }
 
 //func (git *Git) DeleteIssue(pid, iid int) error {
-//	util.Debug("Removing issue %d at project %v", iid, pid)
+//	logging.Debugf("Removing issue %d at project %v", iid, pid)
 //	resp, err := git.client.Issues.DeleteIssue(pid, iid)
 //	if err != nil {
 //		return err
@@ -206,182 +264,170 @@ func (git *Git) CreateIssue(issue *Issue) (*Issue, error) {
 //	return nil
 //}
 
-func (git *Git) UpdateIssue(issue *Issue) (*Issue, error) {
+func (git *Git) UpdateIssue(ctx context.Context, issue *Issue) (*Issue, error) {
+	if err := git.InitClient(); err != nil {
+		return nil, err
+	}
 	opt := &gitlab.UpdateIssueOptions{
 		Title:       gitlab.String(issue.Title),
 		Description: gitlab.String(issue.Description),
 		Labels:      issue.Labels,
 		StateEvent:  gitlab.String(strings.ToLower(string(issue.State))),
 	}
-	newIssue, resp, err := git.client.Issues.UpdateIssue(issue.ProjectID, issue.IID, opt)
-	if err != nil {
+	newIssue, resp, err := git.client.Issues.UpdateIssue(issue.ProjectID, issue.IID, opt, gitlab.WithContext(ctx))
+	if err = check(resp, err, http.StatusOK); err != nil {
 		return nil, err
 	}
-	if resp.StatusCode != http.StatusOK {
-		return nil, errors.New("bad status returned")
-	}
 	return compactIssues([]*gitlab.Issue{newIssue})[0], nil
 }
 
-func (git *Git) Comment(pid, issueID int, message string) (int, error) {
-	git.InitClient()
+func (git *Git) Comment(ctx context.Context, pid, issueID int, message string) (int, error) {
+	if err := git.InitClient(); err != nil {
+		return 0, err
+	}
 
 	opt := &gitlab.CreateIssueNoteOptions{Body: gitlab.String(message)}
-	c, resp, err := git.client.Notes.CreateIssueNote(pid, issueID, opt)
-	if err != nil {
+	c, resp, err := git.client.Notes.CreateIssueNote(pid, issueID, opt, gitlab.WithContext(ctx))
+	if err = check(resp, err, http.StatusCreated); err != nil {
 		return 0, err
 	}
-	if resp.StatusCode != http.StatusCreated {
-		return 0, errors.Errorf("unexpected response code %d", resp.StatusCode)
-	}
 	return c.ID, nil
 }
 
-func (git *Git) DeleteComment(pid, issueID, commentID int) error {
-	git.InitClient()
-
-	resp, err := git.client.Notes.DeleteIssueNote(pid, issueID, commentID)
-	if err != nil {
+func (git *Git) DeleteComment(ctx context.Context, pid, issueID, commentID int) error {
+	if err := git.InitClient(); err != nil {
 		return err
 	}
-	if resp.StatusCode != http.StatusOK {
-		fmt.Println(resp.Status)
-		return errors.Errorf("unexpected response code %d", resp.StatusCode)
+
+	resp, err := git.client.Notes.DeleteIssueNote(pid, issueID, commentID, gitlab.WithContext(ctx))
+	if err = check(resp, err, http.StatusOK); err != nil {
+		return err
 	}
 	return nil
 }
 
-func (git *Git) fetchRemoteProject(name string) (*Project, error) {
+func (git *Git) fetchRemoteProject(ctx context.Context, name string) (*Project, error) {
 	if err := git.InitClient(); err != nil {
 		return nil, err
 	}
-	p, resp, err := git.client.Projects.GetProject(name)
-	if err != nil {
+	p, resp, err := git.client.Projects.GetProject(name, gitlab.WithContext(ctx))
+	if err = check(resp, err, http.StatusOK); err != nil {
 		return nil, err
 	}
-	if resp.StatusCode != http.StatusOK {
-		return nil, errors.New("bad status returned")
-	}
 	return newProject(p), nil
 }
 
-func (git *Git) ListProjects(limit int, noCache bool) ([]*Project, error) {
-	fmt.Printf("Fetching GitLab projects\n")
+func (git *Git) ListProjects(ctx context.Context, limit int, noCache bool) ([]*Project, error) {
+	logging.Infof("Fetching GitLab projects")
 
 	projects, err := git.loadProjects()
 	if err != nil {
-		fmt.Println("cache error:", err)
+		logging.Debugf("cache: can't load projects: %s", err)
 	}
 	if noCache || projects == nil || len(projects) == 0 {
 		if err = git.InitClient(); err != nil {
 			return nil, err
 		}
 
-		opt := &gitlab.ListProjectsOptions{
-			//Owned : gitlab.Bool(true),
-			Membership: gitlab.Bool(true),
-			OrderBy:    gitlab.String("last_activity_at"),
-		}
-		opt.PerPage = limit // todo make configurable
-
-		proj, resp, err := git.client.Projects.ListProjects(opt)
+		projects = make([]*Project, 0)
+		err = git.EachProject(ctx, limit, func(page []*Project) error {
+			projects = append(projects, page...)
+			return nil
+		})
 		if err != nil {
 			return nil, err
 		}
-		if resp.StatusCode != http.StatusOK {
-			fmt.Printf("Request ended with %d %s", resp.StatusCode, resp.Status)
-			return nil, errors.New("bad response")
-		}
-		projects = compactProjects(proj)
 		err = git.storeProjects(projects)
 		if err != nil {
-			fmt.Println("DEBUG: storing error:", err)
+			logging.Debugf("cache: can't store projects: %s", err)
 		}
-		fmt.Printf("Fetched %s.\n\n",
-			util.Plural(len(projects), "project", ""))
+		logging.Infof("Fetched %s", util.Plural(len(projects), "project", ""))
+	}
+	if limit > 0 && len(projects) > limit {
+		projects = projects[:limit]
 	}
-
 	return projects, nil
 }
 
-func (git *Git) ListProjectIssues(pid int, all bool) ([]*Issue, error) {
-	git.InitClient()
+func (git *Git) ListProjectIssues(ctx context.Context, pid int, all bool) ([]*Issue, error) {
+	if err := git.InitClient(); err != nil {
+		return nil, err
+	}
 
-	name, _ := git.ProjectNameByID(pid)
-	fmt.Printf("Fetching GitLab issues by project %q <%d>\n", name, pid)
-	opt := new(gitlab.ListProjectIssuesOptions)
+	name, _ := git.ProjectNameByID(ctx, pid)
+	logging.Infof("Fetching GitLab issues by project %q <%d>", name, pid)
+	f := &IssueFilter{ProjectID: pid}
 	if !all {
-		opt.State = gitlab.String("opened")
+		f.State = "opened"
 	}
-
-	issues, resp, err := git.client.Issues.ListProjectIssues(pid, opt)
+	issues, err := git.ListIssues(ctx, f)
 	if err != nil {
 		return nil, err
 	}
-	if resp.StatusCode != http.StatusOK {
-		fmt.Printf("Request ended with %d %s", resp.StatusCode, resp.Status)
-		return nil, errors.New("bad response")
-	}
-	fmt.Printf("Fetched %s for project %q.\n\n",
-		util.Plural(len(issues), "issue", ""), name)
+	logging.Infof("Fetched %s for project %q", util.Plural(len(issues), "issue", ""), name)
 
-	return compactIssues(issues), nil
+	return issues, nil
 }
 
-func (git *Git) ListAssignedIssues(all bool) ([]*Issue, error) {
-	git.InitClient()
+// ListIssueNotes returns all comments on issue, including system ones.
+func (git *Git) ListIssueNotes(ctx context.Context, pid, issueID int) ([]*Comment, error) {
+	notes := make([]*Comment, 0)
+	err := git.EachNote(ctx, pid, issueID, func(page []*Comment) error {
+		notes = append(notes, page...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return notes, nil
+}
+
+func (git *Git) ListAssignedIssues(ctx context.Context, all bool) ([]*Issue, error) {
+	if err := git.InitClient(); err != nil {
+		return nil, err
+	}
 
-	fmt.Printf("Fetching assigned to you GitLab issues\n")
-	opt := new(gitlab.ListIssuesOptions)
+	logging.Infof("Fetching GitLab issues assigned to you")
+	f := &IssueFilter{Scope: ScopeAssignedToMe}
 	if !all {
-		opt.State = gitlab.String("opened")
+		f.State = "opened"
 	}
-	issues, resp, err := git.client.Issues.ListIssues(opt)
+	issues, err := git.ListIssues(ctx, f)
 	if err != nil {
 		return nil, err
 	}
-	if resp.StatusCode != http.StatusOK {
-		fmt.Printf("Request ended with %d %s", resp.StatusCode, resp.Status)
-		return nil, errors.New("bad response")
-	}
-	fmt.Printf("Fetched %s.\n\n", util.Plural(len(issues), "issue", "issues"))
+	logging.Infof("Fetched %s", util.Plural(len(issues), "issue", "issues"))
 
-	return compactIssues(issues), nil
+	return issues, nil
 }
 
-func (git *Git) DetailedProjectIssue(pid int, issueID int) (*Issue, []*Comment, error) {
-	git.InitClient()
-	projectName, err := git.ProjectNameByID(pid)
+func (git *Git) DetailedProjectIssue(ctx context.Context, pid int, issueID int) (*Issue, []*Comment, error) {
+	if err := git.InitClient(); err != nil {
+		return nil, nil, err
+	}
+	projectName, err := git.ProjectNameByID(ctx, pid)
 	if err != nil {
-		util.Debug("project name fetch err: %s", err)
+		logging.Debugf("project name fetch err: %s", err)
 		projectName = "unknown"
 	}
 
-	fmt.Printf("Fetching GitLab issue #%d on project %s <%d>\n", issueID, projectName, pid)
+	logging.Infof("Fetching GitLab issue #%d on project %s <%d>", issueID, projectName, pid)
 	opt := new(gitlab.ListProjectIssuesOptions)
 	opt.IIDs = []int{issueID}
 
 	//todo use git.Issues.GetIssue()
-	issues, resp, err := git.client.Issues.ListProjectIssues(pid, opt)
-	if err != nil {
+	issues, resp, err := git.client.Issues.ListProjectIssues(pid, opt, gitlab.WithContext(ctx))
+	if err = check(resp, err, http.StatusOK); err != nil {
 		return nil, nil, err
 	}
-	if resp.StatusCode != http.StatusOK {
-		fmt.Printf("Request ended with %d %s", resp.StatusCode, resp.Status)
-		return nil, nil, errors.New("bad response")
-	}
 	issue := issues[0]
 
-	notes, resp, err := git.client.Notes.ListIssueNotes(pid, issueID, nil)
+	notes, err := git.ListIssueNotes(ctx, pid, issueID)
 	if err != nil {
 		return nil, nil, err
 	}
-	if resp.StatusCode != http.StatusOK {
-		fmt.Printf("Request ended with %d %s", resp.StatusCode, resp.Status)
-		return nil, nil, errors.New("bad response")
-	}
 
-	return newIssue(issue), compactComments(notes), nil
+	return newIssue(issue), notes, nil
 }
 
 func (git *Git) credentials(key []byte) (string, string, error) {
@@ -411,14 +457,13 @@ func (git *Git) credentials(key []byte) (string, string, error) {
 
 // If name is empty, provided pid will be returned.
 // Pid validation will be made on further stages.
-func (git *Git) GetPid(name string, pid int) (int, error) {
+func (git *Git) GetPid(ctx context.Context, name string, pid int) (int, error) {
 	if pid == 0 && name == "" {
-		fmt.Fprintln(os.Stderr, "You should provide project name via -p or --project flag or project ID via --pid flag.")
-		os.Exit(1)
+		return 0, util.Usage("You should provide project name via -p or --project flag or project ID via --pid flag.")
 	}
 
 	if name != "" {
-		p, err := git.ProjectByName(name, false, false)
+		p, err := git.FindProject(ctx, name)
 		if err != nil {
 			return 0, err
 		}
@@ -430,12 +475,20 @@ func (git *Git) GetPid(name string, pid int) (int, error) {
 func (git *Git) loadProjects() ([]*Project, error) {
 	p := make([]*Project, 0)
 	fn := func(k, v []byte) error {
-		util.Debug("[CACHE] decoding project '%s'", string(k))
+		if _, err := strconv.Atoi(string(k)); err == nil {
+			// <PID, ProjectName> pair
+			return nil
+		}
+		logging.Debugf("cache: decoding project '%s'", string(k))
 		gp := new(Project)
 		err := gp.Decode(v)
 		if err != nil {
 			return err
 		}
+		if string(k) != gp.Path {
+			// projects used to be stored by name, skip such leftovers
+			return nil
+		}
 		p = append(p, gp)
 		return nil
 	}
@@ -444,7 +497,7 @@ func (git *Git) loadProjects() ([]*Project, error) {
 		return nil, err
 	}
 	if len(p) != 0 {
-		util.Debug("[CACHE] Loaded %d projects\n", len(p))
+		logging.Debugf("cache: loaded %d projects", len(p))
 	}
 	return p, nil
 }
@@ -456,12 +509,13 @@ func (git *Git) storeProjects(projects []*Project) error {
 		git.storage.Set(storage.BucketGitProjectCache,
 			[]byte(strconv.Itoa(p.ID)), []byte(p.Name))
 
-		util.Debug("[CACHE] encoding project %q", p.Name)
+		logging.Debugf("cache: encoding project %q", p.Name)
 		err := p.Encode(buf)
 		if err != nil {
 			return errors.Wrapf(err, "can't encode '%s' project", p.Name)
 		}
-		err = git.storage.Set(storage.BucketGitProjectCache, []byte(p.Name), buf.Bytes())
+		// names aren't unique across groups, paths are
+		err = git.storage.Set(storage.BucketGitProjectCache, []byte(p.Path), buf.Bytes())
 		if err != nil {
 			return errors.Wrapf(err, "can't store '%s' project", p.Name)
 		}
@@ -471,64 +525,93 @@ func (git *Git) storeProjects(projects []*Project) error {
 }
 
 // Try to get name from storage. If data not found, try to fetch it from remote
-func (git *Git) ProjectNameByID(pid interface{}) (string, error) {
+func (git *Git) ProjectNameByID(ctx context.Context, pid interface{}) (string, error) {
 	var pidb []byte
 
 	switch pid.(type) {
 	case int:
-		pid = []byte(strconv.Itoa(pid.(int)))
+		pidb = []byte(strconv.Itoa(pid.(int)))
 	case string:
-		pid = []byte(pid.(string))
+		pidb = []byte(pid.(string))
 	}
 	name, err := git.storage.Get(storage.BucketGitProjectCache, pidb)
 	if err == nil { // found
 		return string(name), nil
 	}
 	// not found, check remote
-	git.InitClient()
-	p, resp, err := git.client.Projects.GetProject(pid, nil)
-	if err != nil {
+	if err := git.InitClient(); err != nil {
 		return "", err
 	}
-	if resp.StatusCode != http.StatusOK {
-		return "", errors.Errorf(resp.Status)
+	p, resp, err := git.client.Projects.GetProject(pid, gitlab.WithContext(ctx))
+	if err = check(resp, err, http.StatusOK); err != nil {
+		return "", err
 	}
 	git.storeProjects([]*Project{newProject(p)})
 	return p.Name, nil
 }
 
+// ProjectNames resolves names of projects by their IDs concurrently.
+// Projects which can't be resolved are missing in the result.
+func (git *Git) ProjectNames(ctx context.Context, pids []interface{}) map[interface{}]string {
+	names := make(map[interface{}]string, len(pids))
+	// client asks for credentials on first use, that can't be done concurrently
+	if err := git.InitClient(); err != nil {
+		return names
+	}
+
+	unique := make(map[interface{}]bool, len(pids))
+	for _, pid := range pids {
+		unique[pid] = true
+	}
+	var mu sync.Mutex
+	pool := parallel.New(0)
+	for pid := range unique {
+		pid := pid
+		pool.Go(func() error {
+			name, err := git.ProjectNameByID(ctx, pid)
+			if err != nil {
+				return err
+			}
+			mu.Lock()
+			names[pid] = name
+			mu.Unlock()
+			return nil
+		})
+	}
+	if err := pool.Wait(); err != nil {
+		logging.Debugf("can't resolve project names: %s", err)
+	}
+	return names
+}
+
 // Try to get project from storage. If no data found, try to fetch it from remote
-func (git *Git) ProjectByName(name string, noCache, alike bool) (*Project, error) {
-	p := new(Project)
+func (git *Git) ProjectByName(ctx context.Context, name string, noCache, alike bool) (*Project, error) {
 	if !noCache {
-		util.Debug("[CACHE] lookup git project by name '%s'", name)
-		b, err := git.storage.Get(storage.BucketGitProjectCache, []byte(name))
+		logging.Debugf("cache: lookup git project by name '%s'", name)
+		p, err := git.cachedProjectByName(name)
 		if err != nil {
-			goto fetchRemote
-		}
-		err = p.Decode(b)
-		if err != nil {
-			util.Debug("[CACHE] project '%s' not found", name)
-			goto fetchRemote
+			return nil, err
 		}
-		if p != nil && p.Name == name {
-			util.Debug("[CACHE] project '%s' found", name)
+		if p != nil {
+			logging.Debugf("cache: project '%s' found", name)
 			return p, nil
 		}
+		logging.Debugf("cache: project '%s' not found", name)
 	}
 
-fetchRemote:
-	git.InitClient()
-	opt := &gitlab.ListProjectsOptions{Search: gitlab.String(name)}
-	proj, resp, err := git.client.Projects.ListProjects(opt, nil)
-	if err != nil {
+	if err := git.InitClient(); err != nil {
 		return nil, err
 	}
-	if resp.StatusCode != http.StatusOK {
-		return nil, errors.New("bad response code")
+	opt := &gitlab.ListProjectsOptions{Search: gitlab.String(name)}
+	proj, resp, err := git.client.Projects.ListProjects(opt, gitlab.WithContext(ctx))
+	if err = check(resp, err, http.StatusOK); err != nil {
+		return nil, err
 	}
 
 	projects := compactProjects(proj)
+	if err := ambiguous(name, projects); err != nil {
+		return nil, err
+	}
 	err = git.storeProjects(projects)
 	if err != nil {
 		return nil, err
@@ -542,7 +625,50 @@ fetchRemote:
 			return projects[i], nil
 		}
 	}
-	return nil, errors.New("project not found")
+	return nil, apierr.Errorf("GitLab", apierr.NotFound, "project '%s' not found", name)
+}
+
+// cachedProjectByName returns cached project with the name, nil if there is
+// none. Name shared by several cached projects is an error.
+func (git *Git) cachedProjectByName(name string) (*Project, error) {
+	projects, err := git.loadProjects()
+	if err != nil {
+		logging.Debugf("cache: can't load projects: %s", err)
+		return nil, nil
+	}
+	if err := ambiguous(name, projects); err != nil {
+		return nil, err
+	}
+	for _, p := range projects {
+		if p.Name == name {
+			return p, nil
+		}
+	}
+	return nil, nil
+}
+
+// ambiguous returns usage error if several projects have the name.
+func ambiguous(name string, projects []*Project) error {
+	var same []string
+	for _, p := range projects {
+		if p.Name == name {
+			same = append(same, p.Path)
+		}
+	}
+	if len(same) > 1 {
+		return util.Usage("project name '%s' is ambiguous, use one of paths: %s",
+			name, strings.Join(same, ", "))
+	}
+	return nil
+}
+
+// FindProject returns project by its path with namespace, e.g.
+// "group/sub/project", or by its name, which must be unique then.
+func (git *Git) FindProject(ctx context.Context, name string) (*Project, error) {
+	if strings.Contains(name, "/") {
+		return git.Project(ctx, name)
+	}
+	return git.ProjectByName(ctx, name, false, false)
 }
 
 // Todo make it more granular
@@ -558,6 +684,7 @@ func (git *Git) Destruct() {
 // lightweight structure to store only valuable data
 type Project struct {
 	Name        string
+	Path        string // with namespace, e.g. group/project
 	Link        string
 	Description string
 	WebURL      string
@@ -570,6 +697,7 @@ func newProject(p *gitlab.Project) *Project {
 	}
 	return &Project{
 		Name:        p.Name,
+		Path:        p.PathWithNamespace,
 		Link:        p.WebURL,
 		Description: p.Description,
 		WebURL:      p.WebURL,
@@ -687,6 +815,7 @@ type User struct {
 	ID    int
 	Name  string
 	Login string
+	Email string
 }
 
 func stripUser(u *gitlab.User) *User {
@@ -694,5 +823,14 @@ func stripUser(u *gitlab.User) *User {
 		ID:    u.ID,
 		Name:  u.Name,
 		Login: u.Username,
+		Email: u.Email,
+	}
+}
+
+func compactUsers(l []*gitlab.User) []*User {
+	users := make([]*User, len(l))
+	for i := 0; i < len(l); i++ {
+		users[i] = stripUser(l[i])
 	}
+	return users
 }
@@ -0,0 +1,168 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/xanzy/go-gitlab"
+)
+
+// ErrStop is returned from iteration callbacks to stop fetching further pages.
+var ErrStop = errors.New("stop iteration")
+
+// listQuery is a query string of paginated listing.
+type listQuery struct {
+	Page    int `url:"page,omitempty"`
+	PerPage int `url:"per_page,omitempty"`
+}
+
+func pageSize(limit int) int {
+	if limit > 0 && limit < 100 {
+		return limit
+	}
+	return 100
+}
+
+// paginate requests listing at path page by page. Every page is decoded into
+// page, which is a pointer to slice, and handed to fn. Next page is found by
+// X-Next-Page header or, for keyset pagination, by next link.
+func (git *Git) paginate(ctx context.Context, path string, opt interface{}, page interface{}, fn func() error) error {
+	if err := git.InitClient(); err != nil {
+		return err
+	}
+
+	req, err := git.client.NewRequest("GET", path, opt, []gitlab.OptionFunc{gitlab.WithContext(ctx)})
+	if err != nil {
+		return err
+	}
+	for {
+		// decoding into previous page would overwrite items already passed to fn
+		v := reflect.ValueOf(page).Elem()
+		v.Set(reflect.Zero(v.Type()))
+
+		resp, err := git.client.Do(req, page)
+		if err = check(resp, err, http.StatusOK); err != nil {
+			return err
+		}
+		if err := fn(); err != nil {
+			if err == ErrStop {
+				return nil
+			}
+			return err
+		}
+
+		next := nextPage(req.URL, resp)
+		if next == nil {
+			return nil
+		}
+		if req, err = git.client.NewRequest("GET", path, nil, []gitlab.OptionFunc{gitlab.WithContext(ctx)}); err != nil {
+			return err
+		}
+		req.URL = next
+	}
+}
+
+// nextPage returns URL of the page following the one at u, nil for the last page.
+func nextPage(u *url.URL, resp *gitlab.Response) *url.URL {
+	if resp.NextPage != 0 {
+		next := *u
+		q := next.Query()
+		q.Set("page", strconv.Itoa(resp.NextPage))
+		next.RawQuery = q.Encode()
+		return &next
+	}
+	// keyset pagination has no page numbers, only links
+	for _, link := range strings.Split(resp.Header.Get("Link"), ",") {
+		parts := strings.Split(link, ";")
+		if len(parts) < 2 || strings.TrimSpace(parts[1]) != `rel="next"` {
+			continue
+		}
+		next, err := url.Parse(strings.Trim(strings.TrimSpace(parts[0]), "<>"))
+		if err == nil {
+			return next
+		}
+	}
+	return nil
+}
+
+// EachIssue hands issues matching the filter to fn page by page, so listing
+// can be consumed while it's still fetched. Fetching stops once limit of the
+// filter is reached or fn returns ErrStop.
+func (git *Git) EachIssue(ctx context.Context, f *IssueFilter, fn func([]*Issue) error) error {
+	q, err := git.newIssueQuery(ctx, f)
+	if err != nil {
+		return err
+	}
+	path := "issues"
+	switch {
+	case f.ProjectID != 0:
+		path = fmt.Sprintf("projects/%d/issues", f.ProjectID)
+	case f.Group != "":
+		path = fmt.Sprintf("groups/%s/issues", url.PathEscape(f.Group))
+	}
+
+	var (
+		page    []*gitlab.Issue
+		fetched int
+	)
+	return git.paginate(ctx, path, q, &page, func() error {
+		if f.Limit > 0 && fetched+len(page) > f.Limit {
+			page = page[:f.Limit-fetched]
+		}
+		fetched += len(page)
+		if err := fn(compactIssues(page)); err != nil {
+			return err
+		}
+		if f.Limit > 0 && fetched >= f.Limit {
+			return ErrStop
+		}
+		return nil
+	})
+}
+
+// EachProject hands projects current user is member of to fn page by page,
+// most active first.
+func (git *Git) EachProject(ctx context.Context, limit int, fn func([]*Project) error) error {
+	q := &struct {
+		listQuery
+		Membership bool   `url:"membership,omitempty"`
+		OrderBy    string `url:"order_by,omitempty"`
+	}{
+		listQuery:  listQuery{PerPage: pageSize(limit)},
+		Membership: true,
+		OrderBy:    "last_activity_at",
+	}
+
+	var (
+		page    []*gitlab.Project
+		fetched int
+	)
+	return git.paginate(ctx, "projects", q, &page, func() error {
+		if limit > 0 && fetched+len(page) > limit {
+			page = page[:limit-fetched]
+		}
+		fetched += len(page)
+		if err := fn(compactProjects(page)); err != nil {
+			return err
+		}
+		if limit > 0 && fetched >= limit {
+			return ErrStop
+		}
+		return nil
+	})
+}
+
+// EachNote hands comments on issue to fn page by page, system ones included.
+func (git *Git) EachNote(ctx context.Context, pid, issueID int, fn func([]*Comment) error) error {
+	var page []*gitlab.Note
+	path := fmt.Sprintf("projects/%d/issues/%d/notes", pid, issueID)
+	return git.paginate(ctx, path, &listQuery{PerPage: 100}, &page, func() error {
+		return fn(compactComments(page))
+	})
+}
@@ -0,0 +1,97 @@
+package git
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// GitLab has no issue attachments, files are uploaded to the project
+// and referenced from markdown as /uploads/<secret>/<filename>.
+var reUpload = regexp.MustCompile(`(!?)\[([^\]]*)\]\((/uploads/[0-9a-f]+/[^)\s]+)\)`)
+
+// Upload is a file uploaded to GitLab project.
+type Upload struct {
+	Alt      string
+	URL      string
+	Markdown string
+}
+
+// UploadRef is a reference to project upload found in markdown text.
+type UploadRef struct {
+	Match string // whole markdown reference
+	Image bool
+	Alt   string
+	URL   string
+}
+
+// Filename returns name of referenced file.
+func (r *UploadRef) Filename() string {
+	name := path.Base(r.URL)
+	if n, err := url.PathUnescape(name); err == nil {
+		name = n
+	}
+	return name
+}
+
+// FindUploads returns references to project uploads in markdown text.
+func FindUploads(text string) []UploadRef {
+	found := reUpload.FindAllStringSubmatch(text, -1)
+	refs := make([]UploadRef, len(found))
+	for i := 0; i < len(found); i++ {
+		refs[i] = UploadRef{
+			Match: found[i][0],
+			Image: found[i][1] == "!",
+			Alt:   found[i][2],
+			URL:   found[i][3],
+		}
+	}
+	return refs
+}
+
+// AbsoluteUploads rewrites upload references in markdown text to absolute links,
+// so they stay valid outside of the project.
+func AbsoluteUploads(p *Project, text string) string {
+	base := strings.TrimSuffix(p.WebURL, "/")
+	return reUpload.ReplaceAllString(text, "$1[$2]("+base+"$3)")
+}
+
+// UploadFile uploads local file to the project.
+func (git *Git) UploadFile(ctx context.Context, pid int, file string) (*Upload, error) {
+	if err := git.InitClient(); err != nil {
+		return nil, err
+	}
+
+	f, resp, err := git.client.Projects.UploadFile(pid, file, gitlab.WithContext(ctx))
+	if err = check(resp, err, http.StatusCreated); err != nil {
+		return nil, err
+	}
+	return &Upload{Alt: f.Alt, URL: f.URL, Markdown: f.Markdown}, nil
+}
+
+// Download writes content of the project upload into w.
+func (git *Git) Download(ctx context.Context, p *Project, ref *UploadRef, w io.Writer) error {
+	if err := git.InitClient(); err != nil {
+		return err
+	}
+
+	u, err := url.Parse(strings.TrimSuffix(p.WebURL, "/") + ref.URL)
+	if err != nil {
+		return err
+	}
+	// uploads are served outside of API, but with the same authorization
+	req, err := git.client.NewRequest("GET", "", nil, []gitlab.OptionFunc{gitlab.WithContext(ctx)})
+	if err != nil {
+		return err
+	}
+	req.URL = u
+
+	resp, err := git.client.Do(req, w)
+	return check(resp, err, http.StatusOK)
+}
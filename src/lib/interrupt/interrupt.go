@@ -0,0 +1,47 @@
+// Package interrupt cancels requests in flight on Ctrl-C.
+package interrupt
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// cleanupTimeout limits rollback of operations interrupted by user.
+const cleanupTimeout = 30 * time.Second
+
+var ctx, cancel = context.WithCancel(context.Background())
+
+// Notify cancels the context returned by Context on the first SIGINT or
+// SIGTERM, so commands roll back what is half-done. The second one exits
+// immediately.
+func Notify() {
+	sig := make(chan os.Signal, 2)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sig
+		fmt.Fprintln(os.Stderr, "\nInterrupted, cleaning up. Press Ctrl-C again to quit immediately.")
+		cancel()
+		<-sig
+		os.Exit(130)
+	}()
+}
+
+// Context returns context of the command, which is canceled on interrupt.
+func Context() context.Context {
+	return ctx
+}
+
+// Cleanup returns context for rolling back operations after the command
+// context is canceled. It isn't canceled on interrupt, only by timeout.
+func Cleanup() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), cleanupTimeout)
+}
+
+// Canceled reports if err is caused by interrupt rather than by failure.
+func Canceled(err error) bool {
+	return err != nil && ctx.Err() != nil
+}
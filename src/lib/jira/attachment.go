@@ -0,0 +1,116 @@
+package jira
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+
+	"lib/storage"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/pkg/errors"
+)
+
+type Attachment struct {
+	ID       string
+	Filename string
+	MimeType string
+	Size     int
+	Created  string
+	Content  string
+	Author   User
+}
+
+func stripAttachments(a []*jira.Attachment) []Attachment {
+	if a == nil {
+		return nil
+	}
+	attachments := make([]Attachment, len(a))
+	for i := 0; i < len(a); i++ {
+		attachments[i] = Attachment{
+			ID:       a[i].ID,
+			Filename: a[i].Filename,
+			MimeType: a[i].MimeType,
+			Size:     a[i].Size,
+			Created:  a[i].Created,
+			Content:  a[i].Content,
+			Author:   stripUser(a[i].Author),
+		}
+	}
+	return attachments
+}
+
+// Attach uploads content of r as attachment of the issue.
+func (j *Jira) Attach(ctx context.Context, issueID, name string, r io.Reader) (*Attachment, error) {
+	if err := j.InitClient(); err != nil {
+		return nil, err
+	}
+
+	attached, resp, err := j.api(ctx).Issue.PostAttachment(issueID, r, name)
+	if err = check(resp, err, http.StatusOK); err != nil {
+		return nil, err
+	}
+	if attached == nil || len(*attached) == 0 {
+		return nil, errors.New("no attachment returned")
+	}
+	j.storage.Delete(storage.BucketJiraIssueCache, []byte(issueID))
+	a := (*attached)[0]
+	return &stripAttachments([]*jira.Attachment{&a})[0], nil
+}
+
+// Download writes content of the attachment into w.
+func (j *Jira) Download(ctx context.Context, a *Attachment, w io.Writer) error {
+	if err := j.InitClient(); err != nil {
+		return err
+	}
+
+	resp, err := j.api(ctx).Issue.DownloadAttachment(a.ID)
+	if err = check(resp, err, http.StatusOK); err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// AttachmentRef returns link to the attachment, which can be used in markdown text
+// of the same issue. Jira Server resolves attachments by file name, while
+// Jira Cloud documents can only refer to them by URL.
+func (j *Jira) AttachmentRef(a *Attachment, image bool) string {
+	switch {
+	case j.cloud():
+		return a.Content
+	case image:
+		return a.Filename
+	}
+	return "^" + a.Filename
+}
+
+// SetDescription replaces description of the issue with markdown text.
+func (j *Jira) SetDescription(ctx context.Context, issueID, md string) error {
+	if err := j.InitClient(); err != nil {
+		return err
+	}
+
+	var description interface{} = j.toMarkup(md)
+	u := "rest/api/2/issue/" + url.PathEscape(issueID)
+	if j.cloud() {
+		description = j.toADF(md)
+		u = "rest/api/3/issue/" + url.PathEscape(issueID)
+	}
+	update := map[string]interface{}{
+		"fields": map[string]interface{}{"description": description},
+	}
+	api := j.api(ctx)
+	req, err := api.NewRequest("PUT", u, update)
+	if err != nil {
+		return err
+	}
+	resp, err := api.Do(req, nil)
+	if err = check(resp, err, http.StatusNoContent); err != nil {
+		return err
+	}
+	j.storage.Delete(storage.BucketJiraIssueCache, []byte(issueID))
+	return nil
+}
@@ -0,0 +1,82 @@
+package jira
+
+import (
+	"context"
+	"strings"
+
+	"subcmd/config"
+
+	"github.com/pkg/errors"
+)
+
+// Catalog is a set of issue types available in Jira project, along with
+// GitLab labels they are mapped to in config.
+type Catalog struct {
+	Project string
+	Types   []*IssueType
+
+	cfg *config.Config
+}
+
+// Catalog returns issue types of the project. Catalog is fetched once per client.
+func (j *Jira) Catalog(ctx context.Context, projectKey string) (*Catalog, error) {
+	if projectKey == "" {
+		return nil, errors.New("Jira project is not specified")
+	}
+	projectKey = strings.ToUpper(projectKey)
+	if c, ok := j.catalogs[projectKey]; ok {
+		return c, nil
+	}
+
+	types, err := j.IssueTypes(ctx, projectKey)
+	if err != nil {
+		return nil, err
+	}
+	c := &Catalog{
+		Project: projectKey,
+		Types:   types,
+		cfg:     j.cfg,
+	}
+	if j.catalogs == nil {
+		j.catalogs = make(map[string]*Catalog)
+	}
+	j.catalogs[projectKey] = c
+	return c, nil
+}
+
+// Type returns issue type by its name or ID, names are compared case insensitively.
+func (c *Catalog) Type(name string) *IssueType {
+	for _, t := range c.Types {
+		if strings.EqualFold(t.Name, name) || t.ID == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// Names returns names of issue types, except subtasks, which can't be
+// created on their own.
+func (c *Catalog) Names() []string {
+	names := make([]string, 0, len(c.Types))
+	for _, t := range c.Types {
+		if !t.Subtask {
+			names = append(names, t.Name)
+		}
+	}
+	return names
+}
+
+// TypeOf returns issue type mapped to GitLab labels or nil, if labels have
+// no mapping or mapped type is not available in the project.
+func (c *Catalog) TypeOf(labels []string) *IssueType {
+	name := c.cfg.LabelType(labels)
+	if name == "" {
+		return nil
+	}
+	return c.Type(name)
+}
+
+// Label returns GitLab label mapped to issue type, if any.
+func (c *Catalog) Label(issueType string) string {
+	return c.cfg.TypeLabel(issueType)
+}
@@ -0,0 +1,19 @@
+package jira
+
+import (
+	"net/http"
+
+	"lib/apierr"
+
+	"github.com/andygrunwald/go-jira"
+)
+
+// check turns failed request or response with status other than expected
+// into typed error.
+func check(resp *jira.Response, err error, expected int) error {
+	var r *http.Response
+	if resp != nil {
+		r = resp.Response
+	}
+	return apierr.Check("Jira", r, err, expected)
+}
@@ -0,0 +1,157 @@
+package jira
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"lib/storage"
+
+	"github.com/pkg/errors"
+	"github.com/trivago/tgo/tcontainer"
+)
+
+const customFieldPrefix = "customfield_"
+
+// ParseFields parses "Name=value" pairs typed by user into field values by name.
+func ParseFields(pairs []string) (map[string]string, error) {
+	fields := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			return nil, errors.Errorf("bad field '%s', expected \"Name=value\"", pair)
+		}
+		fields[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return fields, nil
+}
+
+// FieldNames returns names of all Jira fields by their IDs. Custom fields
+// are rarely renamed, so mapping is kept in storage until cache is invalidated.
+func (j *Jira) FieldNames(ctx context.Context) (map[string]string, error) {
+	names := make(map[string]string)
+	if !j.cfg.Storage.DisableCache {
+		err := j.storage.ForEach(storage.BucketJiraFieldCache, func(k, v []byte) error {
+			names[string(k)] = string(v)
+			return nil
+		})
+		if err == nil && len(names) > 0 {
+			return names, nil
+		}
+	}
+
+	if err := j.InitClient(); err != nil {
+		return nil, err
+	}
+	api := j.api(ctx)
+	req, err := api.NewRequest("GET", "rest/api/2/field", nil)
+	if err != nil {
+		return nil, err
+	}
+	fields := make([]struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}, 0)
+	resp, err := api.Do(req, &fields)
+	if err = check(resp, err, http.StatusOK); err != nil {
+		return nil, err
+	}
+	for _, f := range fields {
+		names[f.ID] = f.Name
+		if !j.cfg.Storage.DisableCache {
+			j.storage.Set(storage.BucketJiraFieldCache, []byte(f.ID), []byte(f.Name))
+		}
+	}
+	return names, nil
+}
+
+// EditableFields returns fields of the issue which can be changed by current user.
+func (j *Jira) EditableFields(ctx context.Context, issueID string) ([]Field, error) {
+	if err := j.InitClient(); err != nil {
+		return nil, err
+	}
+
+	api := j.api(ctx)
+	req, err := api.NewRequest("GET", "rest/api/2/issue/"+url.PathEscape(issueID)+"/editmeta", nil)
+	if err != nil {
+		return nil, err
+	}
+	meta := new(struct {
+		Fields tcontainer.MarshalMap `json:"fields"`
+	})
+	resp, err := api.Do(req, meta)
+	if err = check(resp, err, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return stripFields(meta.Fields)
+}
+
+// SetFields changes fields of the issue. Values are keyed by field name or ID
+// and typed as user would type them, see ParseFields.
+func (j *Jira) SetFields(ctx context.Context, issueID string, values map[string]string) error {
+	editable, err := j.EditableFields(ctx, issueID)
+	if err != nil {
+		return err
+	}
+	t := &IssueType{Fields: editable}
+	fields := make(map[string]interface{}, len(values))
+	for name, value := range values {
+		f := t.Field(name)
+		if f == nil {
+			return errors.Errorf("field %s can't be changed in %s", name, issueID)
+		}
+		v, err := j.fieldValue(f, value)
+		if err != nil {
+			return err
+		}
+		fields[f.ID] = v
+	}
+
+	u := "rest/api/2/issue/" + url.PathEscape(issueID)
+	if j.cloud() {
+		u = "rest/api/3/issue/" + url.PathEscape(issueID)
+	}
+	api := j.api(ctx)
+	req, err := api.NewRequest("PUT", u, map[string]interface{}{"fields": fields})
+	if err != nil {
+		return err
+	}
+	resp, err := api.Do(req, nil)
+	if err = check(resp, err, http.StatusNoContent); err != nil {
+		return err
+	}
+	j.storage.Delete(storage.BucketJiraIssueCache, []byte(issueID))
+	return nil
+}
+
+// fieldText returns human readable representation of field value.
+func fieldText(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case map[string]interface{}:
+		for _, k := range []string{"value", "displayName", "name", "key"} {
+			if s, ok := v[k].(string); ok {
+				if child, ok := v["child"]; ok {
+					return s + " / " + fieldText(child)
+				}
+				return s
+			}
+		}
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			if text := fieldText(item); text != "" {
+				items = append(items, text)
+			}
+		}
+		return strings.Join(items, ", ")
+	}
+	return ""
+}
@@ -2,19 +2,24 @@ package jira
 
 import (
 	"bytes"
+	"context"
 	"encoding/gob"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 
+	"lib/logging"
 	"lib/storage"
+	"lib/transport"
 	"lib/util"
 	"subcmd/config"
 
 	"github.com/andygrunwald/go-jira"
 	"github.com/pkg/errors"
+	"github.com/trivago/tgo/tcontainer"
 )
 
 var ErrBadEndpoint = errors.New("bad or empty endpoint")
@@ -23,7 +28,9 @@ type Jira struct {
 	endpoint string
 	cfg      *config.Config
 	storage  *storage.Storage
-	client   *jira.Client
+	http     *http.Client
+	ready    bool
+	catalogs map[string]*Catalog
 }
 
 func New() (*Jira, error) {
@@ -47,76 +54,51 @@ func NewWithStorage(store *storage.Storage) (*Jira, error) {
 	return &Jira{cfg: cfg, storage: store}, nil
 }
 
-type Kind uint8
-
-const (
-	kindInvalid Kind = iota
-	KindBug
-	KindFeature
-	KindEnhancement
-)
-
-type RemoteTag struct {
-	ID    string
-	Label string
-	Kind  Kind
-}
-
-func detectKind(k string) Kind {
-	switch strings.ToUpper(k) {
-	case "BUG":
-		return KindBug
-	case "FEATURE":
-		return KindFeature
-	case "ENHANCEMENT":
-		return KindEnhancement
+func (j *Jira) User(ctx context.Context) (*User, error) {
+	if err := j.InitClient(); err != nil {
+		return nil, err
+	}
+	u, resp, err := j.api(ctx).User.GetSelf()
+	if err = check(resp, err, http.StatusOK); err != nil {
+		return nil, err
 	}
-	return kindInvalid
+	user := stripUser(u)
+	return &user, nil
 }
 
-func (j *Jira) RemoteTags(projectKey string) ([3]*RemoteTag, error) {
-	meta, resp, err := j.client.Issue.GetCreateMeta(projectKey)
-	if err != nil {
-		return [3]*RemoteTag{}, err
-	}
-	if resp.StatusCode != http.StatusOK {
-		return [3]*RemoteTag{}, err
+// FindUsers searches Jira users by login, display name or email.
+func (j *Jira) FindUsers(ctx context.Context, query string) ([]User, error) {
+	if err := j.InitClient(); err != nil {
+		return nil, err
 	}
 
-	var rtags [3]*RemoteTag
-	var pos int
-
-	for _, pm := range meta.Projects {
-		for _, it := range pm.IssueTypes {
-			kind := detectKind(it.Name)
-			if kind == kindInvalid {
-				continue
-			}
-			rtags[pos] = &RemoteTag{
-				ID:    it.Id,
-				Label: it.Name,
-				Kind:  kind,
-			}
-			pos++
-		}
+	u := fmt.Sprintf("rest/api/2/user/search?username=%s", url.QueryEscape(query))
+	if j.cloud() {
+		// Jira Cloud dropped user names for privacy reasons
+		u = fmt.Sprintf("rest/api/3/user/search?query=%s", url.QueryEscape(query))
 	}
-	return rtags, nil
-}
-
-func (j *Jira) User() (*User, error) {
-	j.InitClient()
-	u, resp, err := j.client.User.GetSelf()
+	api := j.api(ctx)
+	req, err := api.NewRequest("GET", u, nil)
 	if err != nil {
 		return nil, err
 	}
-	if resp.StatusCode != http.StatusOK {
-		return nil, errors.New("got bad response code")
+	found := make([]jira.User, 0)
+	resp, err := api.Do(req, &found)
+	if err = check(resp, err, http.StatusOK); err != nil {
+		return nil, err
+	}
+
+	users := make([]User, len(found))
+	for i := 0; i < len(found); i++ {
+		users[i] = stripUser(&found[i])
 	}
-	user := stripUser(u)
-	return &user, nil
+	return users, nil
 }
 
 func (j *Jira) InitClient() error {
+	if j.ready {
+		return nil
+	}
 	ep := j.cfg.Jira.Address
 	if ep == "" {
 		return ErrBadEndpoint
@@ -127,10 +109,12 @@ func (j *Jira) InitClient() error {
 	// todo fix cred issuer
 	login, pass, err := j.credentials(key)
 	if err != nil {
-		// Todo sanitize error
-		fmt.Println(err)
+		logging.Debugf("no stored Jira credentials: %s", err)
 		// unauthorized
-		login, pass = util.AskCredentials(ep)
+		login, pass, err = util.AskCredentials(ep)
+		if err != nil {
+			return err
+		}
 		//encLogin, _ := persistent.Encrypt(key, login)
 		err := j.storage.Set(storage.BucketAuth, storage.KeyGitlabUser, []byte(login))
 		if err != nil {
@@ -145,22 +129,55 @@ func (j *Jira) InitClient() error {
 	tp := jira.BasicAuthTransport{
 		Username: login,
 		Password: pass,
+		// timeout is applied to every attempt of retried requests
+		Transport: transport.New("Jira", j.cfg.JiraTimeout()),
 	}
 
-	fmt.Printf("Connecting to '%s'\n", ep)
-	jrcli, err := jira.NewClient(tp.Client(), ep)
-	if err != nil {
+	logging.Infof("Connecting to '%s'", ep)
+	httpClient := tp.Client()
+	// endpoint is checked once, so clients bound to contexts can't fail
+	if _, err := jira.NewClient(httpClient, ep); err != nil {
 		return err
 	}
-	j.client = jrcli
+	j.endpoint = ep
+	j.http = httpClient
+	j.ready = true
 	return nil
 }
 
+// api returns client which requests are canceled along with ctx,
+// go-jira doesn't take contexts itself.
+func (j *Jira) api(ctx context.Context) *jira.Client {
+	httpClient := &http.Client{
+		Transport: &ctxTransport{ctx: ctx, base: j.http.Transport},
+	}
+	c, _ := jira.NewClient(httpClient, j.endpoint)
+	return c
+}
+
+type ctxTransport struct {
+	ctx  context.Context
+	base http.RoundTripper
+}
+
+func (t *ctxTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(req.WithContext(t.ctx))
+}
+
 func (j *Jira) Endpoint() string {
 	return j.endpoint
 }
 
-func (j *Jira) Issue(issueID string) (*Issue, error) {
+// BrowseURL returns link to the issue in Jira web interface.
+func (j *Jira) BrowseURL(key string) string {
+	return strings.TrimSuffix(j.cfg.Jira.Address, "/") + "/browse/" + key
+}
+
+func (j *Jira) Issue(ctx context.Context, issueID string) (*Issue, error) {
 	issue := new(Issue)
 
 	issueRaw, err := j.storage.Get(storage.BucketJiraIssueCache, []byte(issueID))
@@ -170,125 +187,208 @@ func (j *Jira) Issue(issueID string) (*Issue, error) {
 			return nil, err
 		}
 
-		is, resp, err := j.client.Issue.Get(issueID, nil)
-		if err != nil {
+		is, resp, err := j.getIssue(ctx, issueID)
+		if err = check(resp, err, http.StatusOK); err != nil {
 			return nil, err
 		}
-		if resp.StatusCode != http.StatusOK {
-			return nil, errors.New("bad status returned")
-		}
 		issue = stripIssue(is)
-		buf := new(bytes.Buffer)
-
-		if err = issue.Encode(buf); err != nil {
-			util.Debug("issue encode failed: %s", err)
-		} else {
-			err = j.storage.Set(storage.BucketJiraIssueCache, []byte(issue.Key), buf.Bytes())
-			if err != nil {
-				util.Debug("issue store failed: %s", err)
-			}
-		}
+		j.toMarkdown(issue)
+		j.cacheIssue(issue)
 	} else {
 		if err = issue.Decode(issueRaw); err != nil {
-			util.Debug("issue decode failed: %s", err)
+			logging.Debugf("issue decode failed: %s", err)
 		}
 
 		if issue == nil {
-			util.Debug("issue is nil :(")
+			logging.Debugf("issue is nil :(")
 		}
 	}
 
 	return issue, nil
 }
 
-func (j *Jira) CreateIssue(issue *Issue) (*Issue, error) {
-	meta, resp, err := j.client.Issue.GetCreateMeta("STORAGE")
+func (j *Jira) CreateIssue(ctx context.Context, issue *Issue) (*Issue, error) {
+	if err := j.InitClient(); err != nil {
+		return nil, err
+	}
+	t, err := j.FindIssueType(ctx, issue.ProjectKey, issue.Type)
 	if err != nil {
 		return nil, err
 	}
+	if missing := t.Missing(issue); len(missing) > 0 {
+		names := make([]string, len(missing))
+		for i, f := range missing {
+			names[i] = f.Name
+		}
+		return nil, errors.Errorf("required fields are not set: %s", strings.Join(names, ", "))
+	}
 
-	m := meta.Projects[0]
 	extendedIssue := extendIssue(issue)
-	extendedIssue.Fields.Type = jira.IssueType{ID: "3"}
-	extendedIssue.Fields.Project = jira.Project{Key: m.Key}
+	extendedIssue.Fields.Type = jira.IssueType{ID: t.ID}
+	extendedIssue.Fields.Project = jira.Project{Key: issue.ProjectKey}
+	extendedIssue.Fields.Unknowns = tcontainer.NewMarshalMap()
+	for id, value := range issue.Fields {
+		f := t.Field(id)
+		if f == nil {
+			return nil, errors.Errorf("field %s is not available for %s", id, t.Name)
+		}
+		v, err := j.fieldValue(f, value)
+		if err != nil {
+			return nil, err
+		}
+		extendedIssue.Fields.Unknowns[f.ID] = v
+	}
 
-	is, resp, err := j.client.Issue.Create(extendedIssue)
-	if err != nil {
+	is, resp, err := j.createIssue(ctx, extendedIssue, issue.Description)
+	if err = check(resp, err, http.StatusCreated); err != nil {
 		return nil, err
 	}
-	if resp.StatusCode != http.StatusCreated {
-		return nil, errors.New("bad status returned")
-	}
 	issue.Key = is.Key
+	issue.Type = t.Name
+	j.cacheIssue(issue)
+	return issue, nil
+}
+
+func (j *Jira) cacheIssue(issue *Issue) {
 	buf := new(bytes.Buffer)
+	if err := issue.Encode(buf); err != nil {
+		logging.Debugf("issue encode failed: %s", err)
+		return
+	}
+	if err := j.storage.Set(storage.BucketJiraIssueCache, []byte(issue.Key), buf.Bytes()); err != nil {
+		logging.Debugf("issue store failed: %s", err)
+	}
+}
 
-	if err = issue.Encode(buf); err != nil {
-		util.Debug("issue encode failed: %s", err)
-	} else {
-		err = j.storage.Set(storage.BucketJiraIssueCache, []byte(issue.Key), buf.Bytes())
-		if err != nil {
-			util.Debug("issue store failed: %s", err)
-		}
+// Comment adds comment to issue and returns its ID.
+func (j *Jira) Comment(ctx context.Context, issueID, message string) (string, error) {
+	if err := j.InitClient(); err != nil {
+		return "", err
 	}
-	return issue, nil
+
+	id, resp, err := j.addComment(ctx, issueID, message)
+	if err = check(resp, err, http.StatusCreated); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// DeleteComment removes comment from issue.
+func (j *Jira) DeleteComment(ctx context.Context, issueID, commentID string) error {
+	if err := j.InitClient(); err != nil {
+		return err
+	}
+
+	u := fmt.Sprintf("rest/api/2/issue/%s/comment/%s", url.PathEscape(issueID), url.PathEscape(commentID))
+	api := j.api(ctx)
+	req, err := api.NewRequest("DELETE", u, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := api.Do(req, nil)
+	return check(resp, err, http.StatusNoContent)
 }
 
-func (j *Jira) Comment(issueID, message string) error {
-	j.InitClient()
+// DeleteIssue removes issue along with its subtasks.
+func (j *Jira) DeleteIssue(ctx context.Context, issueID string) error {
+	if err := j.InitClient(); err != nil {
+		return err
+	}
 
-	opt := &jira.Comment{Body: message}
-	_, resp, err := j.client.Issue.AddComment(issueID, opt)
+	u := fmt.Sprintf("rest/api/2/issue/%s?deleteSubtasks=true", url.PathEscape(issueID))
+	api := j.api(ctx)
+	req, err := api.NewRequest("DELETE", u, nil)
 	if err != nil {
 		return err
 	}
-	if resp.StatusCode != http.StatusCreated {
-		return errors.Errorf("unexpected status code returned: %d", resp.StatusCode)
+	resp, err := api.Do(req, nil)
+	if err = check(resp, err, http.StatusNoContent); err != nil {
+		return err
+	}
+	if err := j.storage.Delete(storage.BucketJiraIssueCache, []byte(issueID)); err != nil {
+		logging.Debugf("cache: can't remove issue %s: %s", issueID, err)
 	}
 	return nil
 }
 
+// Transition moves issue to the status with provided name.
+// Name of transition itself is accepted as well.
+func (j *Jira) Transition(ctx context.Context, issueID, status string) error {
+	if err := j.InitClient(); err != nil {
+		return err
+	}
+
+	transitions, resp, err := j.api(ctx).Issue.GetTransitions(issueID)
+	if err = check(resp, err, http.StatusOK); err != nil {
+		return err
+	}
+	for _, t := range transitions {
+		if !strings.EqualFold(t.To.Name, status) && !strings.EqualFold(t.Name, status) {
+			continue
+		}
+		resp, err := j.api(ctx).Issue.DoTransition(issueID, t.ID)
+		if err = check(resp, err, http.StatusNoContent); err != nil {
+			return err
+		}
+		return nil
+	}
+	return errors.Errorf("status '%s' is not reachable from current state of %s", status, issueID)
+}
+
 func (j *Jira) InvalidateCache() {
 	j.storage.Invalidate(storage.BucketJiraIssueCache)
+	j.storage.Invalidate(storage.BucketJiraFieldCache)
 }
 
-func (j *Jira) ListProjects() ([]*Project, error) {
+func (j *Jira) ListProjects(ctx context.Context) ([]*Project, error) {
 	err := j.InitClient()
 	if err != nil {
 		return nil, err
 	}
 
-	list, resp, err := j.client.Project.GetList()
-	if err != nil {
+	list, resp, err := j.api(ctx).Project.GetList()
+	if err = check(resp, err, http.StatusOK); err != nil {
 		return nil, err
 	}
-	if resp.StatusCode != http.StatusOK {
-		return nil, errors.New("jira did not return 200")
-	}
 	return compactProjects(list), nil
 }
 
-func (j *Jira) ListAssignedIssues(projectName string) ([]*Issue, error) {
-	err := j.InitClient()
-	if err != nil {
-		return nil, err
-	}
+// ListAssignedIssues returns unresolved issues assigned to current user.
+// Project and issue type are optional filters.
+func (j *Jira) ListAssignedIssues(ctx context.Context, projectName, issueType string) ([]*Issue, error) {
+	issues, _, err := j.Search(ctx, AssignedJQL(projectName, issueType), 0)
+	return issues, err
+}
 
-	user, resp, err := j.client.User.GetSelf()
-	if err != nil {
-		return nil, err
-	}
-	q := fmt.Sprintf("assignee = %s AND status not in (Closed, Resolved)", user.Key)
+// AssignedJQL returns query of issues listed by ListAssignedIssues.
+func AssignedJQL(projectName, issueType string) string {
+	// user keys are gone on Jira Cloud, currentUser() works on both
+	q := "assignee = currentUser() AND status not in (Closed, Resolved)"
 	if projectName != "" {
 		q += fmt.Sprintf(" AND project = %s", projectName)
 	}
-	issues, resp, err := j.client.Issue.Search(q, nil)
-	if err != nil {
-		return nil, err
+	if issueType != "" {
+		q += fmt.Sprintf(" AND issuetype = %q", issueType)
 	}
-	if resp.StatusCode != http.StatusOK {
-		return nil, errors.New("bad status returned")
+	return q
+}
+
+// Search returns issues matching provided JQL query along with total count
+// of matching issues. Limit of 0 means all of them.
+func (j *Jira) Search(ctx context.Context, jql string, limit int) ([]*Issue, int, error) {
+	var (
+		issues = make([]*Issue, 0)
+		total  int
+	)
+	err := j.EachIssue(ctx, jql, limit, func(page []*Issue, n int) error {
+		issues = append(issues, page...)
+		total = n
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
 	}
-	return compactIssues(issues), nil
+	return issues, total, nil
 }
 
 func (j *Jira) Destruct() {
@@ -347,6 +447,8 @@ func compactProjects(p *jira.ProjectList) []*Project {
 
 type Issue struct {
 	Key          string
+	ProjectKey   string
+	Type         string
 	Summary      string
 	Description  string
 	Created      time.Time
@@ -358,26 +460,25 @@ type Issue struct {
 	StatusName   string
 	ParentKey    string
 	PriorityName string
+	Labels       []string
+	Attachments  []Attachment
+	Fields       map[string]string // values of other fields by ID or name, as typed by user
 }
 
 func extendIssue(i *Issue) *jira.Issue {
-	return &jira.Issue{
+	ji := &jira.Issue{
 		Fields: &jira.IssueFields{
 			Summary:     i.Summary,
 			Description: i.Description,
-			Assignee:    extendUser(&i.Assignee),
+			Labels:      i.Labels,
 			//Status: i.StatusName,
 			//Type: i.Type,
 		},
 	}
-}
-
-func compactIssues(li []jira.Issue) []*Issue {
-	res := make([]*Issue, len(li))
-	for i := 0; i < len(res); i++ {
-		res[i] = stripIssue(&li[i])
+	if i.Assignee.Name != "" || i.Assignee.AccountID != "" {
+		ji.Fields.Assignee = extendUser(&i.Assignee)
 	}
-	return res
+	return ji
 }
 
 func stripIssue(i *jira.Issue) (ji *Issue) {
@@ -387,6 +488,8 @@ func stripIssue(i *jira.Issue) (ji *Issue) {
 
 	ji = &Issue{
 		Key:          i.Key,
+		ProjectKey:   i.Fields.Project.Key,
+		Type:         i.Fields.Type.Name,
 		Assignee:     stripUser(i.Fields.Assignee),
 		Creator:      stripUser(i.Fields.Creator),
 		Summary:      i.Fields.Summary,
@@ -395,8 +498,21 @@ func stripIssue(i *jira.Issue) (ji *Issue) {
 		StatusName:   i.Fields.Status.Name,
 		PriorityName: i.Fields.Priority.Name,
 		IssueLinks:   stripIssueLinks(i.Fields.IssueLinks),
+		Labels:       i.Fields.Labels,
+		Attachments:  stripAttachments(i.Fields.Attachments),
 	}
 
+	for id, v := range i.Fields.Unknowns {
+		if !strings.HasPrefix(id, customFieldPrefix) {
+			continue
+		}
+		if text := fieldText(v); text != "" {
+			if ji.Fields == nil {
+				ji.Fields = make(map[string]string)
+			}
+			ji.Fields[id] = text
+		}
+	}
 	if i.Fields.Parent != nil {
 		ji.ParentKey = i.Fields.Parent.Key
 	}
@@ -446,8 +562,11 @@ func stripIssueLinks(l []*jira.IssueLink) []IssueLink {
 }
 
 type User struct {
-	DisplayName string
-	Name        string
+	DisplayName  string
+	Name         string
+	EmailAddress string
+	// AccountID identifies user on Jira Cloud, where Name is empty.
+	AccountID string
 }
 
 func stripUser(u *jira.User) User {
@@ -455,8 +574,10 @@ func stripUser(u *jira.User) User {
 		return User{}
 	}
 	return User{
-		DisplayName: u.DisplayName,
-		Name:        u.Name,
+		DisplayName:  u.DisplayName,
+		Name:         u.Name,
+		EmailAddress: u.EmailAddress,
+		AccountID:    u.AccountID,
 	}
 }
 
@@ -464,7 +585,25 @@ func extendUser(u *User) *jira.User {
 	return &jira.User{
 		DisplayName: u.DisplayName,
 		Name:        u.Name,
+		AccountID:   u.AccountID,
+	}
+}
+
+// UserKey returns identifier users are mapped and mentioned by:
+// account ID on Jira Cloud and user name on Jira Server.
+func (j *Jira) UserKey(u *User) string {
+	if j.cloud() {
+		return u.AccountID
+	}
+	return u.Name
+}
+
+// UserByKey returns user identified by key, see UserKey.
+func (j *Jira) UserByKey(key string) User {
+	if j.cloud() {
+		return User{AccountID: key}
 	}
+	return User{Name: key}
 }
 
 type Subtask struct {
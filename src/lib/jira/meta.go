@@ -0,0 +1,256 @@
+package jira
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/trivago/tgo/tcontainer"
+)
+
+// IssueType is a type of tickets which can be created in the project.
+type IssueType struct {
+	ID      string
+	Name    string
+	Subtask bool
+	Fields  []Field
+}
+
+// Field is a field of ticket creation screen.
+type Field struct {
+	ID         string
+	Name       string
+	Required   bool
+	HasDefault bool
+	Type       string // schema type: string, number, option, array, user...
+	Items      string // type of array items
+	Custom     string // type of custom field, empty for system ones
+	Allowed    []string
+}
+
+// Fields filled by jigit itself, they are never asked for.
+var managedFields = map[string]bool{
+	"summary":     true,
+	"description": true,
+	"project":     true,
+	"issuetype":   true,
+	"assignee":    true,
+	"reporter":    true,
+	"labels":      true,
+}
+
+// IssueTypes returns issue types available for creation in the project
+// along with fields of their creation screens.
+func (j *Jira) IssueTypes(ctx context.Context, projectKey string) ([]*IssueType, error) {
+	if err := j.InitClient(); err != nil {
+		return nil, err
+	}
+
+	meta, resp, err := j.api(ctx).Issue.GetCreateMeta(projectKey)
+	if err = check(resp, err, http.StatusOK); err != nil {
+		return nil, err
+	}
+	p := meta.GetProjectWithKey(projectKey)
+	if p == nil {
+		return nil, errors.Errorf("Jira project %s doesn't exist or you can't create tickets there", projectKey)
+	}
+
+	types := make([]*IssueType, 0, len(p.IssueTypes))
+	for _, it := range p.IssueTypes {
+		fields, err := stripFields(it.Fields)
+		if err != nil {
+			return nil, errors.Wrapf(err, "bad fields of %s", it.Name)
+		}
+		types = append(types, &IssueType{
+			ID:      it.Id,
+			Name:    it.Name,
+			Subtask: it.Subtasks,
+			Fields:  fields,
+		})
+	}
+	return types, nil
+}
+
+// FindIssueType returns issue type of the project by its name or ID.
+// Names are compared case insensitively.
+func (j *Jira) FindIssueType(ctx context.Context, projectKey, name string) (*IssueType, error) {
+	if name == "" {
+		return nil, errors.New("Jira issue type is not specified")
+	}
+	c, err := j.Catalog(ctx, projectKey)
+	if err != nil {
+		return nil, err
+	}
+	if t := c.Type(name); t != nil {
+		return t, nil
+	}
+	return nil, errors.Errorf("issue type '%s' is not available in %s, choose one of: %s",
+		name, projectKey, strings.Join(c.Names(), ", "))
+}
+
+// Field returns field of the creation screen by its ID or name.
+func (t *IssueType) Field(idOrName string) *Field {
+	for i := range t.Fields {
+		if t.Fields[i].ID == idOrName || strings.EqualFold(t.Fields[i].Name, idOrName) {
+			return &t.Fields[i]
+		}
+	}
+	return nil
+}
+
+// Missing returns required fields which Jira can't fill for the issue by itself.
+func (t *IssueType) Missing(issue *Issue) []Field {
+	set := make(map[string]bool, len(issue.Fields))
+	for name, value := range issue.Fields {
+		if f := t.Field(name); f != nil && value != "" {
+			set[f.ID] = true
+		}
+	}
+	missing := make([]Field, 0)
+	for _, f := range t.Fields {
+		if !f.Required || f.HasDefault || managedFields[f.ID] || set[f.ID] {
+			continue
+		}
+		missing = append(missing, f)
+	}
+	return missing
+}
+
+func stripFields(m tcontainer.MarshalMap) ([]Field, error) {
+	raw := make(map[string]struct {
+		Name            string `json:"name"`
+		Required        bool   `json:"required"`
+		HasDefaultValue bool   `json:"hasDefaultValue"`
+		Schema          struct {
+			Type   string `json:"type"`
+			Items  string `json:"items"`
+			Custom string `json:"custom"`
+		} `json:"schema"`
+		AllowedValues []struct {
+			Name  string `json:"name"`
+			Value string `json:"value"`
+		} `json:"allowedValues"`
+	})
+	if err := recode(m, &raw); err != nil {
+		return nil, err
+	}
+
+	fields := make([]Field, 0, len(raw))
+	for id, f := range raw {
+		field := Field{
+			ID:         id,
+			Name:       f.Name,
+			Required:   f.Required,
+			HasDefault: f.HasDefaultValue,
+			Type:       f.Schema.Type,
+			Items:      f.Schema.Items,
+			Custom:     f.Schema.Custom,
+		}
+		for _, v := range f.AllowedValues {
+			if v.Value != "" {
+				field.Allowed = append(field.Allowed, v.Value)
+			} else if v.Name != "" {
+				field.Allowed = append(field.Allowed, v.Name)
+			}
+		}
+		fields = append(fields, field)
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Name < fields[j].Name })
+	return fields, nil
+}
+
+// Validate checks if value typed by user is acceptable for the field.
+func (f *Field) Validate(value string) error {
+	for _, item := range f.items(value) {
+		if _, err := f.normalize(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// kind returns type of single field value.
+func (f *Field) kind() string {
+	if f.Type == "array" {
+		return f.Items
+	}
+	return f.Type
+}
+
+// items splits comma-separated value of array field.
+func (f *Field) items(value string) []string {
+	if f.Type != "array" {
+		return []string{strings.TrimSpace(value)}
+	}
+	items := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// normalize checks single value and fixes its case to match allowed one.
+func (f *Field) normalize(value string) (string, error) {
+	if len(f.Allowed) > 0 {
+		for _, a := range f.Allowed {
+			if strings.EqualFold(a, value) {
+				return a, nil
+			}
+		}
+		return "", errors.Errorf("%s should be one of: %s", f.Name, strings.Join(f.Allowed, ", "))
+	}
+	if f.kind() == "number" {
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return "", errors.Errorf("%s should be a number", f.Name)
+		}
+	}
+	return value, nil
+}
+
+// fieldValue converts value typed by user into representation expected
+// by Jira for the field.
+func (j *Jira) fieldValue(f *Field, value string) (interface{}, error) {
+	items := f.items(value)
+	values := make([]interface{}, 0, len(items))
+	for _, item := range items {
+		item, err := f.normalize(item)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, j.scalarValue(f, item))
+	}
+	if f.Type == "array" {
+		return values, nil
+	}
+	return values[0], nil
+}
+
+func (j *Jira) scalarValue(f *Field, value string) interface{} {
+	switch f.kind() {
+	case "number":
+		n, _ := strconv.ParseFloat(value, 64)
+		return n
+	case "option":
+		return map[string]string{"value": value}
+	case "user":
+		if j.cloud() {
+			return map[string]string{"accountId": value}
+		}
+		return map[string]string{"name": value}
+	case "project":
+		return map[string]string{"key": strings.ToUpper(value)}
+	case "priority", "version", "component", "resolution", "securitylevel", "group":
+		return map[string]string{"name": value}
+	case "string":
+		// Jira Cloud expects rich text for multiline custom fields
+		if j.cloud() && strings.HasSuffix(f.Custom, ":textarea") {
+			return j.toADF(value)
+		}
+	}
+	return value
+}
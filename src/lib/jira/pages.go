@@ -0,0 +1,104 @@
+package jira
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/pkg/errors"
+)
+
+// ErrStop is returned from iteration callbacks to stop fetching further pages.
+var ErrStop = errors.New("stop iteration")
+
+const maxPageSize = 100
+
+// paginate requests listing at path page by page with startAt and maxResults
+// parameters, until limit is reached. Items of every page are found under
+// key of the response and handed to fn along with total count of items.
+func (j *Jira) paginate(ctx context.Context, path string, q url.Values, key string, limit int,
+	fn func(items []json.RawMessage, total int) error) error {
+
+	if err := j.InitClient(); err != nil {
+		return err
+	}
+	startAt := 0
+	for {
+		size := maxPageSize
+		if limit > 0 && limit-startAt < size {
+			size = limit - startAt
+		}
+		q.Set("startAt", strconv.Itoa(startAt))
+		q.Set("maxResults", strconv.Itoa(size))
+		api := j.api(ctx)
+		req, err := api.NewRequest("GET", path+"?"+q.Encode(), nil)
+		if err != nil {
+			return err
+		}
+		page := make(map[string]json.RawMessage)
+		resp, err := api.Do(req, &page)
+		if err = check(resp, err, http.StatusOK); err != nil {
+			return err
+		}
+
+		var (
+			items []json.RawMessage
+			total int
+		)
+		if err := json.Unmarshal(page[key], &items); err != nil {
+			return errors.Wrapf(err, "bad %s page", key)
+		}
+		// some listings don't know total count
+		json.Unmarshal(page["total"], &total)
+
+		if err := fn(items, total); err != nil {
+			if err == ErrStop {
+				return nil
+			}
+			return err
+		}
+		// Jira may return less than asked for, but never an empty page in the middle
+		startAt += len(items)
+		if len(items) == 0 || total > 0 && startAt >= total || limit > 0 && startAt >= limit {
+			return nil
+		}
+	}
+}
+
+// EachIssue hands issues matching JQL query to fn page by page along with
+// total count of matching issues, so listing can be consumed while it's still
+// fetched. Fetching stops once limit is reached or fn returns ErrStop, limit
+// of 0 means all of them. Issues are fetched with all their fields, so they
+// are put into the issue cache.
+func (j *Jira) EachIssue(ctx context.Context, jql string, limit int, fn func(issues []*Issue, total int) error) error {
+	path := "rest/api/2/search"
+	if j.cloud() {
+		path = "rest/api/3/search"
+	}
+	q := url.Values{"jql": []string{jql}, "fields": []string{"*all"}}
+	return j.paginate(ctx, path, q, "issues", limit, func(items []json.RawMessage, total int) error {
+		issues := make([]*Issue, len(items))
+		for i, item := range items {
+			raw := make(map[string]interface{})
+			if err := json.Unmarshal(item, &raw); err != nil {
+				return err
+			}
+			if j.cloud() {
+				raw = j.fromADF(raw)
+			}
+			issue := new(jira.Issue)
+			if err := recode(raw, issue); err != nil {
+				return err
+			}
+			issues[i] = stripIssue(issue)
+		}
+		j.toMarkdown(issues...)
+		for _, i := range issues {
+			j.cacheIssue(i)
+		}
+		return fn(issues, total)
+	})
+}
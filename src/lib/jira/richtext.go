@@ -0,0 +1,177 @@
+package jira
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	"lib/markup"
+	"lib/users"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/pkg/errors"
+)
+
+// Jira Server speaks REST API v2 and keeps rich text fields (descriptions and comments)
+// in wiki markup. Jira Cloud REST API v3 uses ADF documents instead.
+// Outside of this package rich text is always markdown.
+const apiCloud = 3
+
+func (j *Jira) cloud() bool {
+	return j.cfg.Jira.APIVersion == apiCloud
+}
+
+func (j *Jira) userMap() *users.Map {
+	return users.New(j.storage)
+}
+
+// toMarkup converts markdown into wiki markup, v2 API only.
+func (j *Jira) toMarkup(md string) string {
+	return markup.MarkdownToJira(md, j.userMap().JiraName)
+}
+
+// toADF converts markdown into ADF document, v3 API only.
+func (j *Jira) toADF(md string) *markup.Node {
+	return markup.MarkdownToADF(md, j.userMap().JiraName)
+}
+
+// toMarkdown converts rich text fields of issue received via v2 API into markdown.
+// Documents received via v3 API are converted before decoding, see fromADF.
+func (j *Jira) toMarkdown(issues ...*Issue) {
+	if j.cloud() {
+		return
+	}
+	mapping := j.userMap()
+	for _, i := range issues {
+		i.Description = markup.JiraToMarkdown(i.Description, mapping.GitLogin)
+		for c := range i.Comments {
+			i.Comments[c].Body = markup.JiraToMarkdown(i.Comments[c].Body, mapping.GitLogin)
+		}
+	}
+}
+
+func (j *Jira) getIssue(ctx context.Context, issueID string) (*jira.Issue, *jira.Response, error) {
+	if !j.cloud() {
+		return j.api(ctx).Issue.Get(issueID, nil)
+	}
+
+	api := j.api(ctx)
+	req, err := api.NewRequest("GET", "rest/api/3/issue/"+url.PathEscape(issueID), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	raw := make(map[string]interface{})
+	resp, err := api.Do(req, &raw)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	issue := new(jira.Issue)
+	if err := recode(j.fromADF(raw), issue); err != nil {
+		return nil, resp, err
+	}
+	return issue, resp, nil
+}
+
+// createIssue creates issue with markdown description.
+func (j *Jira) createIssue(ctx context.Context, issue *jira.Issue, description string) (*jira.Issue, *jira.Response, error) {
+	if !j.cloud() {
+		issue.Fields.Description = j.toMarkup(description)
+		return j.api(ctx).Issue.Create(issue)
+	}
+
+	raw := make(map[string]interface{})
+	if err := recode(issue, &raw); err != nil {
+		return nil, nil, err
+	}
+	fields, ok := raw["fields"].(map[string]interface{})
+	if !ok {
+		return nil, nil, errors.New("issue has no fields")
+	}
+	fields["description"] = j.toADF(description)
+
+	api := j.api(ctx)
+	req, err := api.NewRequest("POST", "rest/api/3/issue", raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	created := new(jira.Issue)
+	resp, err := api.Do(req, created)
+	if err != nil {
+		return nil, resp, err
+	}
+	return created, resp, nil
+}
+
+// addComment adds comment with markdown body and returns its ID.
+func (j *Jira) addComment(ctx context.Context, issueID, body string) (string, *jira.Response, error) {
+	if !j.cloud() {
+		c, resp, err := j.api(ctx).Issue.AddComment(issueID, &jira.Comment{Body: j.toMarkup(body)})
+		if err != nil {
+			return "", resp, err
+		}
+		return c.ID, resp, nil
+	}
+
+	comment := map[string]interface{}{"body": j.toADF(body)}
+	api := j.api(ctx)
+	req, err := api.NewRequest("POST", "rest/api/3/issue/"+url.PathEscape(issueID)+"/comment", comment)
+	if err != nil {
+		return "", nil, err
+	}
+	created := new(jira.Comment)
+	resp, err := api.Do(req, created)
+	return created.ID, resp, err
+}
+
+// fromADF replaces ADF documents in raw v3 issue with markdown,
+// so issue can be decoded into v2 structures.
+func (j *Jira) fromADF(issue map[string]interface{}) map[string]interface{} {
+	fields, ok := issue["fields"].(map[string]interface{})
+	if !ok {
+		return issue
+	}
+
+	mapping := j.userMap()
+	convert := func(v interface{}) interface{} {
+		if v == nil {
+			return v
+		}
+		doc := new(markup.Node)
+		if err := recode(v, doc); err != nil {
+			return v
+		}
+		return markup.ADFToMarkdown(doc, mapping.GitLogin)
+	}
+
+	for _, f := range []string{"description", "environment"} {
+		if v, ok := fields[f]; ok {
+			fields[f] = convert(v)
+		}
+	}
+	// multiline custom fields are documents as well
+	for f, v := range fields {
+		if doc, ok := v.(map[string]interface{}); ok && doc["type"] == "doc" && strings.HasPrefix(f, customFieldPrefix) {
+			fields[f] = convert(v)
+		}
+	}
+	if c, ok := fields["comment"].(map[string]interface{}); ok {
+		comments, _ := c["comments"].([]interface{})
+		for _, raw := range comments {
+			if comment, ok := raw.(map[string]interface{}); ok {
+				comment["body"] = convert(comment["body"])
+			}
+		}
+	}
+	return issue
+}
+
+// recode decodes v into dst through JSON.
+func recode(v, dst interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, dst)
+}
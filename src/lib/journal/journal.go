@@ -0,0 +1,172 @@
+package journal
+
+import (
+	"bytes"
+	"encoding/gob"
+	"io"
+	"time"
+
+	"lib/storage"
+	"lib/util"
+
+	"github.com/pkg/errors"
+)
+
+// Kinds of operations which touch both GitLab and Jira.
+const (
+	KindAdd    = "add"
+	KindCommit = "commit"
+	KindImport = "import"
+	// import from Jira to GitLab
+	KindImportJira = "import-jira"
+)
+
+// Steps of multi-system operations. Each step is marked as done right after
+// remote system confirmed it, so recovery knows what should be resumed
+// or compensated.
+const (
+	StepGitIssue    = "git-issue"
+	StepJiraIssue   = "jira-issue"
+	StepLink        = "link"
+	StepGitComment  = "git-comment"
+	StepJiraComment = "jira-comment"
+	// holds Jira text with references to copied attachments
+	StepJiraAttachments = "jira-attachments"
+	// holds ID of the last copied comment
+	StepComments = "comments"
+	StepState    = "state"
+)
+
+var ErrNotFound = errors.New("operation not found")
+
+// Journal keeps operations spanning both GitLab and Jira.
+// Operation is written before execution and removed after it's finished,
+// so anything left in journal was interrupted.
+type Journal struct {
+	storage *storage.Storage
+}
+
+func New(store *storage.Storage) *Journal {
+	return &Journal{storage: store}
+}
+
+// Begin writes new operation with provided arguments to the journal.
+func (j *Journal) Begin(kind string, args map[string]string) (*Operation, error) {
+	id, err := j.storage.NextID(storage.BucketJournal)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't allocate operation id")
+	}
+	op := &Operation{
+		ID:      id,
+		Kind:    kind,
+		Args:    args,
+		Created: time.Now(),
+		Steps:   make(map[string]string),
+		j:       j,
+	}
+	return op, j.save(op)
+}
+
+// Operation returns stored operation by its ID.
+func (j *Journal) Operation(id int) (*Operation, error) {
+	b, err := j.storage.Get(storage.BucketJournal, util.Itob(id))
+	if err != nil {
+		if err == storage.ErrNoData {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	op := new(Operation)
+	if err := op.Decode(b); err != nil {
+		return nil, err
+	}
+	op.j = j
+	return op, nil
+}
+
+// Pending returns all unfinished operations in order they were started.
+func (j *Journal) Pending() ([]*Operation, error) {
+	ops := make([]*Operation, 0)
+	fn := func(k, v []byte) error {
+		op := new(Operation)
+		if err := op.Decode(v); err != nil {
+			return errors.Wrapf(err, "can't decode operation %d", util.Btoi(k))
+		}
+		op.j = j
+		ops = append(ops, op)
+		return nil
+	}
+	if err := j.storage.ForEach(storage.BucketJournal, fn); err != nil {
+		return nil, err
+	}
+	return ops, nil
+}
+
+func (j *Journal) save(op *Operation) error {
+	buf := new(bytes.Buffer)
+	if err := op.Encode(buf); err != nil {
+		return errors.Wrapf(err, "can't encode operation %d", op.ID)
+	}
+	return j.storage.Set(storage.BucketJournal, util.Itob(op.ID), buf.Bytes())
+}
+
+type Operation struct {
+	ID      int
+	Kind    string
+	Args    map[string]string
+	Created time.Time
+	// Steps holds finished steps with value, returned by remote
+	// (issue ID, comment ID, etc).
+	Steps map[string]string
+	// Compensating is set when operation should be rolled back
+	// instead of resumed.
+	Compensating bool
+
+	j *Journal
+}
+
+// Done marks provided step as finished and persists it immediately.
+func (op *Operation) Done(step, value string) error {
+	op.Steps[step] = value
+	return op.j.save(op)
+}
+
+// Undone marks provided step as compensated.
+func (op *Operation) Undone(step string) error {
+	delete(op.Steps, step)
+	return op.j.save(op)
+}
+
+func (op *Operation) IsDone(step string) bool {
+	_, ok := op.Steps[step]
+	return ok
+}
+
+func (op *Operation) Value(step string) string {
+	return op.Steps[step]
+}
+
+// SetArg sets argument of operation and persists it immediately.
+func (op *Operation) SetArg(name, value string) error {
+	op.Args[name] = value
+	return op.j.save(op)
+}
+
+// Compensate marks operation as one which should be rolled back.
+func (op *Operation) Compensate() error {
+	op.Compensating = true
+	return op.j.save(op)
+}
+
+// Finish removes operation from journal.
+func (op *Operation) Finish() error {
+	return op.j.storage.Delete(storage.BucketJournal, util.Itob(op.ID))
+}
+
+func (op *Operation) Encode(into io.Writer) error {
+	return gob.NewEncoder(into).Encode(op)
+}
+
+func (op *Operation) Decode(v []byte) error {
+	return gob.NewDecoder(bytes.NewBuffer(v)).Decode(op)
+}
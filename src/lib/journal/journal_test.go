@@ -0,0 +1,191 @@
+package journal
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"lib/storage"
+)
+
+func open(t *testing.T, dir string) *storage.Storage {
+	disk, err := storage.NewStorage(filepath.Join(dir, "jigit.db"))
+	if err != nil {
+		t.Fatalf("can't open storage: %s", err)
+	}
+	return disk
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "jigit-journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+func TestPending(t *testing.T) {
+	tests := []struct {
+		name     string
+		begin    int
+		finish   []int // indexes of finished operations
+		expected []int // indexes of pending ones, in order
+	}{
+		{"empty", 0, nil, []int{}},
+		{"all pending", 3, nil, []int{0, 1, 2}},
+		{"middle finished", 3, []int{1}, []int{0, 2}},
+		{"all finished", 2, []int{0, 1}, []int{}},
+		// IDs are ordered numerically, not as strings
+		{"many", 300, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			disk := open(t, tempDir(t))
+			defer disk.Close()
+			j := New(disk)
+
+			ops := make([]*Operation, tt.begin)
+			for i := range ops {
+				op, err := j.Begin(KindCommit, map[string]string{"body": "text"})
+				if err != nil {
+					t.Fatal(err)
+				}
+				ops[i] = op
+			}
+			for _, i := range tt.finish {
+				if err := ops[i].Finish(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			expected := tt.expected
+			if expected == nil {
+				for i := range ops {
+					expected = append(expected, i)
+				}
+			}
+
+			pending, err := j.Pending()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(pending) != len(expected) {
+				t.Fatalf("expected %d pending operations, got %d", len(expected), len(pending))
+			}
+			for n, i := range expected {
+				if pending[n].ID != ops[i].ID {
+					t.Errorf("expected operation %d at %d, got %d", ops[i].ID, n, pending[n].ID)
+				}
+			}
+		})
+	}
+}
+
+// Steps of interrupted operation must survive restart, so it can be resumed
+// or rolled back in reverse.
+func TestResumeAfterRestart(t *testing.T) {
+	dir := tempDir(t)
+	disk := open(t, dir)
+	op, err := New(disk).Begin(KindAdd, map[string]string{"project": "backend"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := op.Done(StepGitIssue, "12"); err != nil {
+		t.Fatal(err)
+	}
+	if err := op.Done(StepJiraIssue, "ABC-1"); err != nil {
+		t.Fatal(err)
+	}
+	disk.Close()
+
+	disk = open(t, dir)
+	defer disk.Close()
+	j := New(disk)
+	op, err = j.Operation(op.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if op.Kind != KindAdd || op.Args["project"] != "backend" {
+		t.Errorf("unexpected operation %+v", op)
+	}
+	if !op.IsDone(StepGitIssue) || op.Value(StepGitIssue) != "12" || op.Value(StepJiraIssue) != "ABC-1" {
+		t.Errorf("unexpected steps %v", op.Steps)
+	}
+	if op.IsDone(StepLink) {
+		t.Error("link is not done yet")
+	}
+
+	// rollback undoes steps in reverse, each one is persisted right away
+	steps := []string{StepJiraIssue, StepGitIssue}
+	if err := op.Compensate(); err != nil {
+		t.Fatal(err)
+	}
+	for n, step := range steps {
+		if err := op.Undone(step); err != nil {
+			t.Fatal(err)
+		}
+		stored, err := j.Operation(op.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !stored.Compensating {
+			t.Error("expected operation to stay compensating")
+		}
+		for i, s := range steps {
+			if done := stored.IsDone(s); done != (i > n) {
+				t.Errorf("after undoing %s: step %s done = %v", step, s, done)
+			}
+		}
+	}
+
+	if err := op.Finish(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := j.Operation(op.ID); err != ErrNotFound {
+		t.Errorf("expected finished operation to be gone, got %v", err)
+	}
+}
+
+func TestSetArg(t *testing.T) {
+	disk := open(t, tempDir(t))
+	defer disk.Close()
+	j := New(disk)
+	op, err := j.Begin(KindCommit, map[string]string{"body": "text"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := op.SetArg("queue_entry", "7"); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := j.Operation(op.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Args["queue_entry"] != "7" || stored.Args["body"] != "text" {
+		t.Errorf("unexpected arguments %v", stored.Args)
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	op := &Operation{
+		ID:           3,
+		Kind:         KindImportJira,
+		Args:         map[string]string{"ticket": "ABC-1", "project": "backend"},
+		Steps:        map[string]string{StepGitIssue: "4", StepComments: "10001"},
+		Compensating: true,
+	}
+	buf := new(bytes.Buffer)
+	if err := op.Encode(buf); err != nil {
+		t.Fatal(err)
+	}
+	decoded := new(Operation)
+	if err := decoded.Decode(buf.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.ID != op.ID || decoded.Kind != op.Kind || !decoded.Compensating ||
+		decoded.Args["ticket"] != "ABC-1" || decoded.Value(StepComments) != "10001" {
+		t.Errorf("decoded %+v, expected %+v", decoded, op)
+	}
+}
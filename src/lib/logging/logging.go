@@ -0,0 +1,160 @@
+// Package logging writes leveled messages to stderr, so they never mix with
+// output of commands, and optionally records everything into JSON log file.
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Level int
+
+const (
+	Error Level = iota
+	Warn
+	Info
+	Debug
+)
+
+var levelNames = map[Level]string{
+	Error: "error",
+	Warn:  "warn",
+	Info:  "info",
+	Debug: "debug",
+}
+
+func (l Level) String() string {
+	return levelNames[l]
+}
+
+// Verbosity returns level of stderr output for number of -v flags,
+// quiet leaves errors only.
+func Verbosity(verbose int, quiet bool) Level {
+	if quiet {
+		return Error
+	}
+	l := Warn + Level(verbose)
+	if l > Debug {
+		l = Debug
+	}
+	return l
+}
+
+// Fields are structured details of a record.
+type Fields map[string]interface{}
+
+var (
+	mu    sync.Mutex
+	level           = Warn
+	out   io.Writer = os.Stderr
+	file  *os.File
+)
+
+// Setup sets level of stderr output. If path isn't empty, records of all
+// levels are appended to the file as JSON lines.
+func Setup(l Level, path string) error {
+	mu.Lock()
+	defer mu.Unlock()
+	level = l
+	if path == "" {
+		return nil
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("can't open log file: %v", err)
+	}
+	file = f
+	return nil
+}
+
+// Redirect sends stderr output to w until restore is called, e.g. while
+// terminal UI owns the screen.
+func Redirect(w io.Writer) (restore func()) {
+	mu.Lock()
+	defer mu.Unlock()
+	prev := out
+	out = w
+	return func() {
+		mu.Lock()
+		defer mu.Unlock()
+		out = prev
+	}
+}
+
+// Close closes log file, if any.
+func Close() error {
+	mu.Lock()
+	defer mu.Unlock()
+	if file == nil {
+		return nil
+	}
+	err := file.Close()
+	file = nil
+	return err
+}
+
+// Enabled reports if records of level are written anywhere, so costly
+// details could be skipped otherwise.
+func Enabled(l Level) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return l <= level || file != nil
+}
+
+// Log writes record with message and fields.
+func Log(l Level, msg string, fields Fields) {
+	mu.Lock()
+	defer mu.Unlock()
+	if l <= level {
+		fmt.Fprintln(out, text(l, msg, fields))
+	}
+	if file != nil {
+		record := Fields{"time": time.Now().Format(time.RFC3339Nano), "level": l.String(), "msg": msg}
+		for k, v := range fields {
+			record[k] = v
+		}
+		if b, err := json.Marshal(record); err == nil {
+			file.Write(append(b, '\n'))
+		}
+	}
+}
+
+func text(l Level, msg string, fields Fields) string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := []string{msg}
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%v", k, fields[k]))
+	}
+	if l == Info {
+		return strings.Join(parts, " ")
+	}
+	return l.String() + ": " + strings.Join(parts, " ")
+}
+
+func Errorf(format string, args ...interface{}) {
+	Log(Error, fmt.Sprintf(format, args...), nil)
+}
+
+func Warnf(format string, args ...interface{}) {
+	Log(Warn, fmt.Sprintf(format, args...), nil)
+}
+
+// Infof reports progress, it's shown with -v flag.
+func Infof(format string, args ...interface{}) {
+	Log(Info, fmt.Sprintf(format, args...), nil)
+}
+
+// Debugf reports details useful for troubleshooting, shown with -vv flag.
+func Debugf(format string, args ...interface{}) {
+	Log(Debug, fmt.Sprintf(format, args...), nil)
+}
@@ -0,0 +1,59 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLevels(t *testing.T) {
+	buf := new(bytes.Buffer)
+	out = buf
+	defer func() { out = os.Stderr }()
+
+	dir, err := ioutil.TempDir("", "logging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "jigit.log")
+	if err := Setup(Verbosity(0, false), path); err != nil {
+		t.Fatal(err)
+	}
+	Infof("Fetching %d issues", 3)
+	Warnf("can't copy attachments")
+	if err := Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if s := buf.String(); s != "warn: can't copy attachments\n" {
+		t.Errorf("unexpected stderr output %q", s)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := bytes.Split(bytes.TrimSpace(data), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("expected records of all levels in file, got %s", data)
+	}
+	var record map[string]interface{}
+	if err := json.Unmarshal(lines[0], &record); err != nil {
+		t.Fatal(err)
+	}
+	if record["level"] != "info" || record["msg"] != "Fetching 3 issues" {
+		t.Errorf("unexpected record %v", record)
+	}
+}
+
+func TestVerbosity(t *testing.T) {
+	if l := Verbosity(5, false); l != Debug {
+		t.Errorf("expected debug level, got %s", l)
+	}
+	if l := Verbosity(2, true); l != Error {
+		t.Errorf("expected quiet to win, got %s", l)
+	}
+}
@@ -0,0 +1,555 @@
+package markup
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Node is a node of Atlassian Document Format (ADF) document,
+// which is used for rich text fields by Jira Cloud REST API v3.
+type Node struct {
+	Type    string                 `json:"type"`
+	Version int                    `json:"version,omitempty"`
+	Text    string                 `json:"text,omitempty"`
+	Attrs   map[string]interface{} `json:"attrs,omitempty"`
+	Marks   []Mark                 `json:"marks,omitempty"`
+	Content []*Node                `json:"content,omitempty"`
+}
+
+// Mark is a formatting of ADF text node.
+type Mark struct {
+	Type  string                 `json:"type"`
+	Attrs map[string]interface{} `json:"attrs,omitempty"`
+}
+
+func (n *Node) add(c ...*Node) {
+	n.Content = append(n.Content, c...)
+}
+
+func (n *Node) attr(key string) string {
+	switch v := n.Attrs[key].(type) {
+	case string:
+		return v
+	case float64: // numbers decoded from JSON
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int:
+		return strconv.Itoa(v)
+	}
+	return ""
+}
+
+var (
+	reMdImageLine = regexp.MustCompile(`^\s*!\[([^\]]*)\]\(([^)\s]+)(?:\s+"[^"]*")?\)\s*$`)
+	reMdToken     = regexp.MustCompile("`([^`]+)`" +
+		`|!\[([^\]]*)\]\(([^)\s]+)(?:\s+"[^"]*")?\)` +
+		`|\[([^\]]+)\]\(([^)\s]+)(?:\s+"[^"]*")?\)` +
+		`|<((?:https?|mailto):[^>\s]+)>` +
+		`|\*\*([^*\s](?:[^*]*[^*\s])?)\*\*|__([^_\s](?:[^_]*[^_\s])?)__` +
+		`|~~([^~\s](?:[^~]*[^~\s])?)~~` +
+		`|\*([^*\s](?:[^*]*[^*\s])?)\*|_([^_\s](?:[^_]*[^_\s])?)_` +
+		`|@(\w[\w.-]*\w|\w)`)
+)
+
+// submatch groups of reMdToken
+const (
+	tokCode = iota + 1
+	tokImageAlt
+	tokImageURL
+	tokLinkText
+	tokLinkURL
+	tokAutoLink
+	tokBold
+	tokBoldUnderscore
+	tokStrike
+	tokItalic
+	tokItalicUnderscore
+	tokMention
+)
+
+// MarkdownToADF converts GitLab flavoured markdown into ADF document.
+// GitLab @mentions are translated with mentions into Jira account IDs.
+func MarkdownToADF(md string, mentions Mentions) *Node {
+	var (
+		doc   = &Node{Type: "doc", Version: 1}
+		lines = scanLines(md)
+		lists adfLists
+		para  *Node
+	)
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if strings.TrimSpace(line) == "" {
+			para = nil
+			continue
+		}
+
+		if m := reMdFence.FindStringSubmatch(line); m != nil {
+			code := make([]string, 0)
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), m[1]); i++ {
+				code = append(code, lines[i])
+			}
+			block := &Node{Type: "codeBlock"}
+			if m[2] != "" {
+				block.Attrs = map[string]interface{}{"language": m[2]}
+			}
+			if len(code) > 0 {
+				block.add(&Node{Type: "text", Text: strings.Join(code, "\n")})
+			}
+			doc.add(block)
+			para = nil
+			lists.reset()
+			continue
+		}
+
+		if reMdRule.MatchString(line) {
+			doc.add(&Node{Type: "rule"})
+			para = nil
+			lists.reset()
+			continue
+		}
+
+		if m := reMdList.FindStringSubmatch(line); m != nil {
+			ordered := m[2][0] >= '0' && m[2][0] <= '9'
+			item := &Node{Type: "listItem"}
+			item.add(&Node{Type: "paragraph", Content: adfInline(m[3], nil, mentions)})
+			lists.push(doc, len(strings.Replace(m[1], "\t", "    ", -1)), ordered, item)
+			para = nil
+			continue
+		}
+		lists.reset()
+
+		switch {
+		case reMdHeading.MatchString(line):
+			m := reMdHeading.FindStringSubmatch(line)
+			doc.add(&Node{
+				Type:    "heading",
+				Attrs:   map[string]interface{}{"level": len(m[1])},
+				Content: adfInline(m[2], nil, mentions),
+			})
+			para = nil
+		case reMdQuote.MatchString(line):
+			quote := make([]string, 0)
+			for ; i < len(lines) && reMdQuote.MatchString(lines[i]); i++ {
+				quote = append(quote, reMdQuote.FindStringSubmatch(lines[i])[1])
+			}
+			i--
+			doc.add(&Node{Type: "blockquote", Content: MarkdownToADF(strings.Join(quote, "\n"), mentions).Content})
+			para = nil
+		case strings.Contains(line, "|") && i+1 < len(lines) && reMdTableSep.MatchString(lines[i+1]):
+			table := &Node{Type: "table"}
+			table.add(adfRow(line, "tableHeader", mentions))
+			for i += 2; i < len(lines) && strings.Contains(lines[i], "|"); i++ {
+				table.add(adfRow(lines[i], "tableCell", mentions))
+			}
+			i--
+			doc.add(table)
+			para = nil
+		case reMdImageLine.MatchString(line):
+			m := reMdImageLine.FindStringSubmatch(line)
+			media := &Node{Type: "media", Attrs: map[string]interface{}{"type": "external", "url": m[2]}}
+			if m[1] != "" {
+				media.Attrs["alt"] = m[1]
+			}
+			doc.add(&Node{Type: "mediaSingle", Content: []*Node{media}})
+			para = nil
+		default:
+			if para == nil {
+				para = &Node{Type: "paragraph"}
+				doc.add(para)
+			} else {
+				// GitLab renders line breaks inside of paragraph
+				para.add(&Node{Type: "hardBreak"})
+			}
+			para.add(adfInline(line, nil, mentions)...)
+		}
+	}
+	return doc
+}
+
+func adfRow(row, cell string, mentions Mentions) *Node {
+	tr := &Node{Type: "tableRow"}
+	for _, c := range splitRow(row, "|") {
+		tr.add(&Node{Type: cell, Content: []*Node{{Type: "paragraph", Content: adfInline(c, nil, mentions)}}})
+	}
+	return tr
+}
+
+// adfInline converts markdown inline markup into ADF text nodes.
+// Every node gets provided marks in addition to its own ones.
+func adfInline(s string, marks []Mark, mentions Mentions) []*Node {
+	nodes := make([]*Node, 0)
+	text := func(t string, marks []Mark) {
+		if t != "" {
+			nodes = append(nodes, &Node{Type: "text", Text: t, Marks: marks})
+		}
+	}
+
+	var last int
+	for from := 0; from < len(s); {
+		loc := reMdToken.FindStringSubmatchIndex(s[from:])
+		if loc == nil {
+			break
+		}
+		for i := range loc {
+			if loc[i] >= 0 {
+				loc[i] += from
+			}
+		}
+		start, end := loc[0], loc[1]
+		if !onWordBoundary(s, loc) {
+			from = start + 1
+			continue
+		}
+		group := func(n int) string {
+			if loc[2*n] < 0 {
+				return ""
+			}
+			return s[loc[2*n]:loc[2*n+1]]
+		}
+		matched := func(n int) bool {
+			return loc[2*n] >= 0
+		}
+
+		text(s[last:start], marks)
+		switch {
+		case matched(tokCode):
+			// code mark can be combined with link only
+			code := []Mark{{Type: "code"}}
+			for _, m := range marks {
+				if m.Type == "link" {
+					code = append(code, m)
+				}
+			}
+			text(group(tokCode), code)
+		case matched(tokImageURL):
+			// images can't be inline in ADF
+			alt := group(tokImageAlt)
+			if alt == "" {
+				alt = group(tokImageURL)
+			}
+			text(alt, withMark(marks, Mark{Type: "link", Attrs: map[string]interface{}{"href": group(tokImageURL)}}))
+		case matched(tokLinkURL):
+			link := Mark{Type: "link", Attrs: map[string]interface{}{"href": group(tokLinkURL)}}
+			nodes = append(nodes, adfInline(group(tokLinkText), withMark(marks, link), mentions)...)
+		case matched(tokAutoLink):
+			url := group(tokAutoLink)
+			text(url, withMark(marks, Mark{Type: "link", Attrs: map[string]interface{}{"href": url}}))
+		case matched(tokBold), matched(tokBoldUnderscore):
+			nodes = append(nodes, adfInline(group(tokBold)+group(tokBoldUnderscore), withMark(marks, Mark{Type: "strong"}), mentions)...)
+		case matched(tokStrike):
+			nodes = append(nodes, adfInline(group(tokStrike), withMark(marks, Mark{Type: "strike"}), mentions)...)
+		case matched(tokItalic), matched(tokItalicUnderscore):
+			nodes = append(nodes, adfInline(group(tokItalic)+group(tokItalicUnderscore), withMark(marks, Mark{Type: "em"}), mentions)...)
+		case matched(tokMention):
+			login := group(tokMention)
+			id, ok := mentions.lookup(login)
+			if !ok {
+				text(s[start:end], marks)
+				break
+			}
+			nodes = append(nodes, &Node{Type: "mention", Attrs: map[string]interface{}{"id": id, "text": "@" + login}})
+		}
+		last, from = end, end
+	}
+	text(s[last:], marks)
+	return nodes
+}
+
+func withMark(marks []Mark, m Mark) []Mark {
+	res := make([]Mark, len(marks), len(marks)+1)
+	copy(res, marks)
+	return append(res, m)
+}
+
+// onWordBoundary reports whether emphasis or mention token matched at loc
+// isn't a part of a word, like in snake_case_name or user@example.com.
+func onWordBoundary(s string, loc []int) bool {
+	var (
+		start, end = loc[0], loc[1]
+		before     = start > 0 && isWordByte(s[start-1])
+		after      = end < len(s) && isWordByte(s[end])
+	)
+	switch {
+	case loc[2*tokMention] >= 0:
+		return start == 0 || !before && strings.IndexByte(".@[", s[start-1]) < 0
+	case loc[2*tokItalic] >= 0:
+		return !before
+	case loc[2*tokItalicUnderscore] >= 0, loc[2*tokBoldUnderscore] >= 0:
+		return !before && !after
+	}
+	return true
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
+
+// adfLists tracks nesting of markdown lists by their indentation.
+type adfLists struct {
+	indents []int
+	nodes   []*Node
+}
+
+func (l *adfLists) push(doc *Node, indent int, ordered bool, item *Node) {
+	kind := "bulletList"
+	if ordered {
+		kind = "orderedList"
+	}
+	for len(l.indents) > 0 && indent < l.indents[len(l.indents)-1] {
+		l.pop()
+	}
+	if n := len(l.indents); n > 0 && indent == l.indents[n-1] && l.nodes[n-1].Type != kind {
+		l.pop()
+	}
+
+	if n := len(l.indents); n == 0 || indent > l.indents[n-1] {
+		list := &Node{Type: kind}
+		if n == 0 {
+			doc.add(list)
+		} else {
+			items := l.nodes[n-1].Content
+			items[len(items)-1].add(list)
+		}
+		l.indents = append(l.indents, indent)
+		l.nodes = append(l.nodes, list)
+	}
+	l.nodes[len(l.nodes)-1].add(item)
+}
+
+func (l *adfLists) pop() {
+	l.indents, l.nodes = l.indents[:len(l.indents)-1], l.nodes[:len(l.nodes)-1]
+}
+
+func (l *adfLists) reset() {
+	l.indents, l.nodes = l.indents[:0], l.nodes[:0]
+}
+
+// ADFToMarkdown converts ADF document into GitLab flavoured markdown.
+// Jira account IDs of mentioned users are translated with mentions.
+func ADFToMarkdown(doc *Node, mentions Mentions) string {
+	if doc == nil {
+		return ""
+	}
+	return strings.TrimRight(adfBlocks(doc.Content, mentions), "\n")
+}
+
+func adfBlocks(nodes []*Node, mentions Mentions) string {
+	blocks := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		if b := adfBlock(n, mentions); b != "" {
+			blocks = append(blocks, b)
+		}
+	}
+	return strings.Join(blocks, "\n\n")
+}
+
+func adfBlock(n *Node, mentions Mentions) string {
+	switch n.Type {
+	case "paragraph":
+		return adfText(n.Content, mentions)
+	case "heading":
+		level, _ := strconv.Atoi(n.attr("level"))
+		if level < 1 || level > 6 {
+			level = 1
+		}
+		return strings.Repeat("#", level) + " " + adfText(n.Content, mentions)
+	case "bulletList", "orderedList":
+		return adfList(n, "", mentions)
+	case "codeBlock":
+		return "```" + n.attr("language") + "\n" + adfPlain(n.Content) + "\n```"
+	case "blockquote":
+		return adfQuote(adfBlocks(n.Content, mentions))
+	case "panel":
+		title := n.attr("panelType")
+		if title == "" {
+			title = "info"
+		}
+		return adfQuote("**" + strings.ToUpper(title[:1]) + title[1:] + "**\n\n" + adfBlocks(n.Content, mentions))
+	case "expand", "nestedExpand":
+		if title := n.attr("title"); title != "" {
+			return "**" + title + "**\n\n" + adfBlocks(n.Content, mentions)
+		}
+		return adfBlocks(n.Content, mentions)
+	case "rule":
+		return "---"
+	case "table":
+		return adfTable(n, mentions)
+	case "mediaSingle", "mediaGroup":
+		media := make([]string, 0, len(n.Content))
+		for _, m := range n.Content {
+			media = append(media, adfMedia(m))
+		}
+		return strings.Join(media, "\n")
+	case "media":
+		return adfMedia(n)
+	}
+	if len(n.Content) > 0 && n.Text == "" {
+		return adfBlocks(n.Content, mentions)
+	}
+	// inline node outside of paragraph
+	return adfText([]*Node{n}, mentions)
+}
+
+func adfList(list *Node, indent string, mentions Mentions) string {
+	order, err := strconv.Atoi(list.attr("order"))
+	if err != nil {
+		order = 1
+	}
+
+	lines := make([]string, 0, len(list.Content))
+	for i, item := range list.Content {
+		marker := "- "
+		if list.Type == "orderedList" {
+			marker = strconv.Itoa(order+i) + ". "
+		}
+		pad := indent + strings.Repeat(" ", len(marker))
+
+		first := true
+		for _, c := range item.Content {
+			if c.Type == "bulletList" || c.Type == "orderedList" {
+				lines = append(lines, adfList(c, pad, mentions))
+				continue
+			}
+			for _, l := range strings.Split(adfBlock(c, mentions), "\n") {
+				if first {
+					lines = append(lines, indent+marker+l)
+					first = false
+					continue
+				}
+				lines = append(lines, strings.TrimRight(pad+l, " "))
+			}
+		}
+		if first {
+			lines = append(lines, indent+strings.TrimSpace(marker))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func adfTable(table *Node, mentions Mentions) string {
+	lines := make([]string, 0, len(table.Content)+1)
+	for i, row := range table.Content {
+		cells := make([]string, 0, len(row.Content))
+		for _, cell := range row.Content {
+			text := adfBlocks(cell.Content, mentions)
+			text = strings.Replace(text, "|", "\\|", -1)
+			cells = append(cells, strings.Replace(text, "\n", "<br>", -1))
+		}
+		lines = append(lines, "| "+strings.Join(cells, " | ")+" |")
+		if i == 0 {
+			// markdown table always has a header
+			lines = append(lines, "|"+strings.Repeat(" --- |", len(cells)))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func adfMedia(n *Node) string {
+	if n.attr("type") == "external" {
+		return "![" + n.attr("alt") + "](" + n.attr("url") + ")"
+	}
+	// Jira Cloud names attached files by their file names,
+	// so they can be matched against issue attachments
+	if name := n.attr("alt"); name != "" {
+		return "![" + name + "](" + name + ")"
+	}
+	return "[attachment]"
+}
+
+func adfQuote(text string) string {
+	lines := strings.Split(text, "\n")
+	for i := range lines {
+		lines[i] = strings.TrimRight("> "+lines[i], " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// adfPlain returns text of nodes without any formatting.
+func adfPlain(nodes []*Node) string {
+	buf := new(bytes.Buffer)
+	for _, n := range nodes {
+		if n.Type == "hardBreak" {
+			buf.WriteString("\n")
+		}
+		buf.WriteString(n.Text)
+		buf.WriteString(adfPlain(n.Content))
+	}
+	return buf.String()
+}
+
+func adfText(nodes []*Node, mentions Mentions) string {
+	buf := new(bytes.Buffer)
+	for _, n := range nodes {
+		switch n.Type {
+		case "text":
+			buf.WriteString(adfMarks(n.Text, n.Marks))
+		case "hardBreak":
+			buf.WriteString("\n")
+		case "mention":
+			if login, ok := mentions.lookup(n.attr("id")); ok && mentions != nil {
+				buf.WriteString("@" + login)
+				break
+			}
+			name := strings.TrimPrefix(n.attr("text"), "@")
+			if name == "" {
+				name = n.attr("id")
+			}
+			buf.WriteString("@" + name)
+		case "emoji":
+			if text := n.attr("text"); text != "" {
+				buf.WriteString(text)
+				break
+			}
+			buf.WriteString(n.attr("shortName"))
+		case "inlineCard", "blockCard":
+			buf.WriteString("<" + n.attr("url") + ">")
+		case "status":
+			buf.WriteString("`" + n.attr("text") + "`")
+		case "date":
+			ms, err := strconv.ParseInt(n.attr("timestamp"), 10, 64)
+			if err != nil {
+				break
+			}
+			buf.WriteString(time.Unix(ms/1000, 0).UTC().Format("2006-01-02"))
+		default:
+			buf.WriteString(adfText(n.Content, mentions))
+		}
+	}
+	return buf.String()
+}
+
+// adfMarks wraps text into markdown formatting.
+// Surrounding spaces are kept outside, otherwise markdown won't recognize it.
+func adfMarks(text string, marks []Mark) string {
+	has := make(map[string]Mark, len(marks))
+	for _, m := range marks {
+		has[m.Type] = m
+	}
+
+	if _, ok := has["code"]; ok {
+		text = "`" + text + "`"
+	}
+	for _, f := range []struct{ mark, wrap string }{{"em", "_"}, {"strong", "**"}, {"strike", "~~"}} {
+		if _, ok := has[f.mark]; !ok {
+			continue
+		}
+		trimmed := strings.TrimSpace(text)
+		if trimmed == "" {
+			continue
+		}
+		lead := text[:strings.Index(text, trimmed)]
+		text = lead + f.wrap + trimmed + f.wrap + text[len(lead)+len(trimmed):]
+	}
+	if link, ok := has["link"]; ok {
+		href, _ := link.Attrs["href"].(string)
+		if href != "" && href != text {
+			text = "[" + text + "](" + href + ")"
+		} else if href != "" {
+			text = "<" + href + ">"
+		}
+	}
+	return text
+}
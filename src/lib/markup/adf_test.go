@@ -0,0 +1,149 @@
+package markup
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMarkdownToADF(t *testing.T) {
+	cases := []struct {
+		name, md, adf string
+	}{
+		{
+			"paragraph",
+			lines("first **bold** line", "second"),
+			`{"type":"doc","version":1,"content":[{"type":"paragraph","content":[` +
+				`{"type":"text","text":"first "},{"type":"text","text":"bold","marks":[{"type":"strong"}]},` +
+				`{"type":"text","text":" line"},{"type":"hardBreak"},{"type":"text","text":"second"}]}]}`,
+		},
+		{
+			"link with code",
+			"[run `make`](http://x.y)",
+			`{"type":"doc","version":1,"content":[{"type":"paragraph","content":[` +
+				`{"type":"text","text":"run ","marks":[{"type":"link","attrs":{"href":"http://x.y"}}]},` +
+				`{"type":"text","text":"make","marks":[{"type":"code"},{"type":"link","attrs":{"href":"http://x.y"}}]}]}]}`,
+		},
+		{
+			"mention",
+			"ping @john, not @jane or snake_case_name",
+			`{"type":"doc","version":1,"content":[{"type":"paragraph","content":[` +
+				`{"type":"text","text":"ping "},{"type":"mention","attrs":{"id":"john.doe","text":"@john"}},` +
+				`{"type":"text","text":", not "},{"type":"text","text":"@jane"},{"type":"text","text":" or snake_case_name"}]}]}`,
+		},
+		{
+			"nested list",
+			lines("- one", "  1. two"),
+			`{"type":"doc","version":1,"content":[{"type":"bulletList","content":[{"type":"listItem","content":[` +
+				`{"type":"paragraph","content":[{"type":"text","text":"one"}]},` +
+				`{"type":"orderedList","content":[{"type":"listItem","content":[` +
+				`{"type":"paragraph","content":[{"type":"text","text":"two"}]}]}]}]}]}]}`,
+		},
+		{
+			"code block",
+			lines("```go", "x := 1", "```"),
+			`{"type":"doc","version":1,"content":[{"type":"codeBlock","attrs":{"language":"go"},` +
+				`"content":[{"type":"text","text":"x := 1"}]}]}`,
+		},
+		{
+			"image",
+			"![shot](http://x.y/shot.png)",
+			`{"type":"doc","version":1,"content":[{"type":"mediaSingle","content":[` +
+				`{"type":"media","attrs":{"alt":"shot","type":"external","url":"http://x.y/shot.png"}}]}]}`,
+		},
+	}
+
+	for _, c := range cases {
+		res, err := json.Marshal(MarkdownToADF(c.md, gitToJira))
+		if err != nil {
+			t.Fatalf("%s: can't marshal document: %s", c.name, err)
+		}
+		if string(res) != c.adf {
+			t.Errorf("%s: unexpected conversion result:\n%s\nexpected:\n%s", c.name, res, c.adf)
+		}
+	}
+}
+
+func TestADFToMarkdown(t *testing.T) {
+	doc := `{"version":1,"type":"doc","content":[
+		{"type":"heading","attrs":{"level":2},"content":[{"type":"text","text":"Summary"}]},
+		{"type":"paragraph","content":[
+			{"type":"text","text":"bold ","marks":[{"type":"strong"}]},
+			{"type":"text","text":"and "},
+			{"type":"mention","attrs":{"id":"john.doe","text":"@John Doe"}},
+			{"type":"text","text":" with "},
+			{"type":"mention","attrs":{"id":"5b10ac8d82e05b22cc7d4ef5","text":"@Jane"}}
+		]},
+		{"type":"panel","attrs":{"panelType":"warning"},"content":[
+			{"type":"paragraph","content":[{"type":"text","text":"careful"}]}
+		]},
+		{"type":"orderedList","attrs":{"order":3},"content":[
+			{"type":"listItem","content":[{"type":"paragraph","content":[{"type":"text","text":"three"}]}]},
+			{"type":"listItem","content":[{"type":"paragraph","content":[{"type":"text","text":"four"}]}]}
+		]},
+		{"type":"table","content":[
+			{"type":"tableRow","content":[
+				{"type":"tableHeader","content":[{"type":"paragraph","content":[{"type":"text","text":"Key"}]}]},
+				{"type":"tableHeader","content":[{"type":"paragraph","content":[{"type":"text","text":"Value"}]}]}
+			]},
+			{"type":"tableRow","content":[
+				{"type":"tableCell","content":[{"type":"paragraph","content":[{"type":"text","text":"a|b"}]}]},
+				{"type":"tableCell","content":[{"type":"paragraph","content":[{"type":"inlineCard","attrs":{"url":"http://x.y"}}]}]}
+			]}
+		]},
+		{"type":"mediaSingle","content":[{"type":"media","attrs":{"type":"file","id":"abc","collection":"x"}}]}
+	]}`
+	expected := lines(
+		"## Summary",
+		"",
+		"**bold** and @john with @Jane",
+		"",
+		"> **Warning**",
+		">",
+		"> careful",
+		"",
+		"3. three",
+		"4. four",
+		"",
+		"| Key | Value |",
+		"| --- | --- |",
+		`| a\|b | <http://x.y> |`,
+		"",
+		"[attachment]",
+	)
+
+	n := new(Node)
+	if err := json.Unmarshal([]byte(doc), n); err != nil {
+		t.Fatalf("can't unmarshal document: %s", err)
+	}
+	if res := ADFToMarkdown(n, jiraToGit); res != expected {
+		t.Fatalf("unexpected conversion result:\n%s\nexpected:\n%s", res, expected)
+	}
+}
+
+func TestADFRoundTrip(t *testing.T) {
+	md := lines(
+		"## Summary",
+		"",
+		"Crash on **start**, see [log](http://example.com/log) and ask @john.",
+		"Second _line_ with `code` and ~~strike~~.",
+		"",
+		"- first",
+		"  - second",
+		"    1. third",
+		"",
+		"> quoted",
+		"",
+		"```sh",
+		"make run",
+		"```",
+		"",
+		"---",
+		"",
+		"| Key | Value |",
+		"| --- | --- |",
+		"| a | b |",
+	)
+	if res := ADFToMarkdown(MarkdownToADF(md, gitToJira), jiraToGit); res != md {
+		t.Fatalf("round trip changed text:\n%s\nexpected:\n%s", res, md)
+	}
+}
@@ -0,0 +1,174 @@
+package markup
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	reJiraHeading  = regexp.MustCompile(`^\s*h([1-6])\.\s+(.*)$`)
+	reJiraList     = regexp.MustCompile(`^\s*([*#-]+)\s+(.*)$`)
+	reJiraRule     = regexp.MustCompile(`^\s*-{4,}\s*$`)
+	reJiraQuote    = regexp.MustCompile(`^\s*bq\.\s+(.*)$`)
+	reJiraBlock    = regexp.MustCompile(`^\s*\{(code|noformat|quote|panel|info|note|tip|warning)(:[^}]*)?\}(.*)$`)
+	reJiraCodeSpan = regexp.MustCompile(`\{\{.+?\}\}`)
+	reJiraImage    = regexp.MustCompile(`!([^!\s|]+)(\|[^!]*)?!`)
+	reJiraLink     = regexp.MustCompile(`\[([^|\]]+)\|([^\]]+)\]`)
+	reJiraURL      = regexp.MustCompile(`\[((?:https?|mailto|ftp):[^\]]+)\]`)
+	reJiraMention  = regexp.MustCompile(`\[~([^\]]+)\]`)
+	reJiraBold     = regexp.MustCompile(`(^|[^\w*])\*([^*\s](?:[^*]*[^*\s])?)\*([^\w*]|$)`)
+	reJiraStrike   = regexp.MustCompile(`(^|\s)-([^-\s](?:[^-]*[^-\s])?)-(\s|$)`)
+	reJiraUnder    = regexp.MustCompile(`(^|\W)\+([^+\s](?:[^+]*[^+\s])?)\+(\W|$)`)
+	reJiraColor    = regexp.MustCompile(`\{color(:[^}]*)?\}`)
+)
+
+// titles of admonition macros, which have no title by default
+var jiraPanels = map[string]string{
+	"info":    "Info",
+	"note":    "Note",
+	"tip":     "Tip",
+	"warning": "Warning",
+}
+
+// JiraToMarkdown converts Jira wiki markup into GitLab flavoured markdown.
+// Jira [~mentions] are translated with mentions.
+func JiraToMarkdown(text string, mentions Mentions) string {
+	var (
+		buf   = new(bytes.Buffer)
+		lines = scanLines(text)
+	)
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+
+		if m := reJiraBlock.FindStringSubmatch(line); m != nil {
+			macro, params := m[1], macroParams(m[2])
+			body, rest := make([]string, 0), m[3]
+			closing := "{" + macro + "}"
+			// block contents until closing macro, which may be on the same line
+			for {
+				if idx := strings.Index(rest, closing); idx >= 0 {
+					if s := rest[:idx]; strings.TrimSpace(s) != "" {
+						body = append(body, s)
+					}
+					break
+				}
+				if strings.TrimSpace(rest) != "" || len(body) > 0 {
+					body = append(body, rest)
+				}
+				if i++; i >= len(lines) {
+					break
+				}
+				rest = lines[i]
+			}
+
+			switch macro {
+			case "code", "noformat":
+				buf.WriteString("```" + codeLanguage(macro, params) + "\n")
+				for _, l := range body {
+					buf.WriteString(l + "\n")
+				}
+				buf.WriteString("```\n")
+			default:
+				title := params["title"]
+				if title == "" {
+					title = jiraPanels[macro]
+				}
+				if title != "" {
+					buf.WriteString("> **" + title + "**\n>\n")
+				}
+				inner := JiraToMarkdown(strings.Join(body, "\n"), mentions)
+				for _, l := range strings.Split(inner, "\n") {
+					buf.WriteString(strings.TrimRight("> "+l, " ") + "\n")
+				}
+			}
+			continue
+		}
+
+		switch {
+		case reJiraRule.MatchString(line):
+			buf.WriteString("---\n")
+		case reJiraHeading.MatchString(line):
+			m := reJiraHeading.FindStringSubmatch(line)
+			n, _ := strconv.Atoi(m[1])
+			buf.WriteString(strings.Repeat("#", n) + " " + jiraInline(m[2], mentions) + "\n")
+		case reJiraQuote.MatchString(line):
+			buf.WriteString("> " + jiraInline(reJiraQuote.FindStringSubmatch(line)[1], mentions) + "\n")
+		case reJiraList.MatchString(line):
+			m := reJiraList.FindStringSubmatch(line)
+			marker := "-"
+			if strings.HasSuffix(m[1], "#") {
+				marker = "1."
+			}
+			buf.WriteString(strings.Repeat("  ", len(m[1])-1) + marker + " " + jiraInline(m[2], mentions) + "\n")
+		case strings.HasPrefix(strings.TrimSpace(line), "||"):
+			cells := jiraCells(line, "||", mentions)
+			buf.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+			buf.WriteString("|" + strings.Repeat(" --- |", len(cells)) + "\n")
+		case strings.HasPrefix(strings.TrimSpace(line), "|"):
+			buf.WriteString("| " + strings.Join(jiraCells(line, "|", mentions), " | ") + " |\n")
+		default:
+			buf.WriteString(jiraInline(line, mentions) + "\n")
+		}
+	}
+	return strings.TrimRight(buf.String(), "\n")
+}
+
+// jiraCells converts inline markup first, so links won't be split by cell separator.
+func jiraCells(row, sep string, mentions Mentions) []string {
+	return splitRow(jiraInline(row, mentions), sep)
+}
+
+func jiraInline(line string, mentions Mentions) string {
+	return mapInline(reJiraCodeSpan, line, func(s string) string {
+		s = reJiraColor.ReplaceAllString(s, "")
+		s = reJiraMention.ReplaceAllStringFunc(s, func(l string) string {
+			name := reJiraMention.FindStringSubmatch(l)[1]
+			if login, ok := mentions.lookup(name); ok {
+				return "@" + login
+			}
+			return "@" + name
+		})
+		s = reJiraImage.ReplaceAllString(s, "![]($1)")
+		s = reJiraLink.ReplaceAllString(s, "[$1]($2)")
+		s = reJiraURL.ReplaceAllString(s, "<$1>")
+
+		s = reJiraBold.ReplaceAllString(s, "$1**$2**$3")
+		s = reJiraBold.ReplaceAllString(s, "$1**$2**$3")
+		s = reJiraStrike.ReplaceAllString(s, "$1~~$2~~$3")
+		return reJiraUnder.ReplaceAllString(s, "$1$2$3")
+	}, func(code string) string {
+		return "`" + strings.TrimSuffix(strings.TrimPrefix(code, "{{"), "}}") + "`"
+	})
+}
+
+// macroParams parses ':key=value|key2=value2' macro parameters.
+// Parameter without value is stored with empty key.
+func macroParams(raw string) map[string]string {
+	params := make(map[string]string)
+	raw = strings.TrimPrefix(raw, ":")
+	if raw == "" {
+		return params
+	}
+	for _, p := range strings.Split(raw, "|") {
+		kv := strings.SplitN(p, "=", 2)
+		if len(kv) == 1 {
+			params[""] = strings.TrimSpace(kv[0])
+			continue
+		}
+		params[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return params
+}
+
+func codeLanguage(macro string, params map[string]string) string {
+	if macro != "code" {
+		return ""
+	}
+	if lang := params["language"]; lang != "" {
+		return lang
+	}
+	return params[""]
+}
@@ -0,0 +1,177 @@
+package markup
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	reMdHeading  = regexp.MustCompile(`^(#{1,6})\s+(.*?)(\s+#+)?\s*$`)
+	reMdFence    = regexp.MustCompile("^\\s*(```|~~~)\\s*([\\w+#.-]*)")
+	reMdList     = regexp.MustCompile(`^(\s*)([-*+]|\d+[.)])\s+(.*)$`)
+	reMdQuote    = regexp.MustCompile(`^\s*>\s?(.*)$`)
+	reMdRule     = regexp.MustCompile(`^\s*([-*_])(\s*([-*_])){2,}\s*$`)
+	reMdTableSep = regexp.MustCompile(`^\s*\|?(\s*:?-+:?\s*\|)+(\s*:?-+:?\s*)?$`)
+	reMdCodeSpan = regexp.MustCompile("`[^`]+`")
+	reMdImage    = regexp.MustCompile(`!\[([^\]]*)\]\(([^)\s]+)(?:\s+"[^"]*")?\)`)
+	reMdLink     = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)(?:\s+"[^"]*")?\)`)
+	reMdAutoLink = regexp.MustCompile(`<((?:https?|mailto):[^>\s]+)>`)
+	reMdBold     = regexp.MustCompile(`\*\*([^*\s](?:[^*]*[^*\s])?)\*\*|__([^_\s](?:[^_]*[^_\s])?)__`)
+	reMdItalic   = regexp.MustCompile(`(^|[^\w*])\*([^*\s](?:[^*]*[^*\s])?)\*([^\w*]|$)`)
+	reMdStrike   = regexp.MustCompile(`~~([^~\s](?:[^~]*[^~\s])?)~~`)
+	reMdMention  = regexp.MustCompile(`(^|[^\w.@\[])@(\w[\w.-]*\w|\w)`)
+)
+
+// MarkdownToJira converts GitLab flavoured markdown into Jira wiki markup.
+// GitLab @mentions are translated with mentions.
+func MarkdownToJira(md string, mentions Mentions) string {
+	var (
+		buf   = new(bytes.Buffer)
+		lines = scanLines(md)
+		list  listStack
+	)
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+
+		if m := reMdFence.FindStringSubmatch(line); m != nil {
+			if m[2] != "" {
+				buf.WriteString("{code:" + m[2] + "}\n")
+			} else {
+				buf.WriteString("{code}\n")
+			}
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), m[1]); i++ {
+				buf.WriteString(lines[i] + "\n")
+			}
+			buf.WriteString("{code}\n")
+			list.reset()
+			continue
+		}
+
+		if reMdRule.MatchString(line) {
+			buf.WriteString("----\n")
+			list.reset()
+			continue
+		}
+
+		if m := reMdList.FindStringSubmatch(line); m != nil {
+			marker := "*"
+			if m[2][0] >= '0' && m[2][0] <= '9' {
+				marker = "#"
+			}
+			prefix := list.push(len(strings.Replace(m[1], "\t", "    ", -1)), marker)
+			buf.WriteString(prefix + " " + mdInline(m[3], mentions) + "\n")
+			continue
+		}
+		if strings.TrimSpace(line) != "" {
+			list.reset()
+		}
+
+		switch {
+		case reMdHeading.MatchString(line):
+			m := reMdHeading.FindStringSubmatch(line)
+			buf.WriteString("h" + strconv.Itoa(len(m[1])) + ". " + mdInline(m[2], mentions) + "\n")
+		case reMdQuote.MatchString(line):
+			quote := make([]string, 0)
+			for ; i < len(lines) && reMdQuote.MatchString(lines[i]); i++ {
+				quote = append(quote, mdInline(reMdQuote.FindStringSubmatch(lines[i])[1], mentions))
+			}
+			i--
+			if len(quote) == 1 {
+				buf.WriteString("bq. " + quote[0] + "\n")
+				continue
+			}
+			buf.WriteString("{quote}\n" + strings.Join(quote, "\n") + "\n{quote}\n")
+		case strings.Contains(line, "|") && i+1 < len(lines) && reMdTableSep.MatchString(lines[i+1]):
+			buf.WriteString("||" + strings.Join(mdCells(line, mentions), "||") + "||\n")
+			for i += 2; i < len(lines) && strings.Contains(lines[i], "|"); i++ {
+				buf.WriteString("|" + strings.Join(mdCells(lines[i], mentions), "|") + "|\n")
+			}
+			i--
+		default:
+			buf.WriteString(mdInline(line, mentions) + "\n")
+		}
+	}
+	return strings.TrimRight(buf.String(), "\n")
+}
+
+func mdCells(row string, mentions Mentions) []string {
+	cells := splitRow(row, "|")
+	for i := range cells {
+		cells[i] = mdInline(cells[i], mentions)
+		if cells[i] == "" {
+			// Jira collapses empty cells
+			cells[i] = " "
+		}
+	}
+	return cells
+}
+
+func mdInline(line string, mentions Mentions) string {
+	return mapInline(reMdCodeSpan, line, func(s string) string {
+		s = reMdImage.ReplaceAllString(s, "!$2!")
+		s = reMdLink.ReplaceAllStringFunc(s, func(l string) string {
+			m := reMdLink.FindStringSubmatch(l)
+			if m[1] == m[2] {
+				return "[" + m[2] + "]"
+			}
+			return "[" + m[1] + "|" + m[2] + "]"
+		})
+		s = reMdAutoLink.ReplaceAllString(s, "[$1]")
+
+		// italic goes first, so bold result won't be taken as italic;
+		// neighbour matches share boundary, so replace twice
+		s = reMdItalic.ReplaceAllString(s, "${1}_${2}_$3")
+		s = reMdItalic.ReplaceAllString(s, "${1}_${2}_$3")
+		s = reMdBold.ReplaceAllString(s, "*$1$2*")
+		s = reMdStrike.ReplaceAllString(s, "-$1-")
+
+		return reMdMention.ReplaceAllStringFunc(s, func(l string) string {
+			m := reMdMention.FindStringSubmatch(l)
+			if name, ok := mentions.lookup(m[2]); ok {
+				return m[1] + "[~" + name + "]"
+			}
+			return l
+		})
+	}, func(code string) string {
+		return "{{" + strings.Trim(code, "`") + "}}"
+	})
+}
+
+// listStack tracks nesting of markdown lists by their indentation.
+type listStack struct {
+	indents []int
+	markers []string
+}
+
+func (l *listStack) push(indent int, marker string) string {
+	for len(l.indents) > 0 && indent < l.indents[len(l.indents)-1] {
+		l.indents = l.indents[:len(l.indents)-1]
+		l.markers = l.markers[:len(l.markers)-1]
+	}
+	switch {
+	case len(l.indents) == 0 || indent > l.indents[len(l.indents)-1]:
+		l.indents = append(l.indents, indent)
+		l.markers = append(l.markers, marker)
+	default:
+		l.markers[len(l.markers)-1] = marker
+	}
+	return strings.Join(l.markers, "")
+}
+
+func (l *listStack) reset() {
+	l.indents, l.markers = l.indents[:0], l.markers[:0]
+}
+
+func scanLines(text string) []string {
+	lines := make([]string, 0)
+	s := bufio.NewScanner(strings.NewReader(text))
+	s.Buffer(make([]byte, 64*1024), len(text)+1)
+	for s.Scan() {
+		lines = append(lines, strings.TrimRight(s.Text(), "\r"))
+	}
+	return lines
+}
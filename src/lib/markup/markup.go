@@ -0,0 +1,52 @@
+// Package markup converts text between GitLab markdown and Jira wiki markup.
+//
+// Conversion is line based and covers what people actually write in issues:
+// headings, lists, code blocks, tables, links, images, quotes, mentions and
+// the most common Jira macros. Anything unknown is passed through untouched.
+package markup
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+)
+
+// Mentions translates user name from one system to another.
+// If false is returned, user is mentioned as plain @name.
+// Nil Mentions means names are the same in both systems.
+type Mentions func(name string) (string, bool)
+
+func (m Mentions) lookup(name string) (string, bool) {
+	if m == nil {
+		return name, true
+	}
+	return m(name)
+}
+
+// mapInline applies fn to parts of line which are outside of inline code spans,
+// matched by span. Code spans are passed to code function.
+func mapInline(span *regexp.Regexp, line string, fn func(string) string, code func(string) string) string {
+	var (
+		buf  bytes.Buffer
+		last int
+	)
+	for _, loc := range span.FindAllStringIndex(line, -1) {
+		buf.WriteString(fn(line[last:loc[0]]))
+		buf.WriteString(code(line[loc[0]:loc[1]]))
+		last = loc[1]
+	}
+	buf.WriteString(fn(line[last:]))
+	return buf.String()
+}
+
+// splitRow splits table row by cell separator, dropping leading and trailing ones.
+func splitRow(row, sep string) []string {
+	row = strings.TrimSpace(row)
+	row = strings.TrimPrefix(row, sep)
+	row = strings.TrimSuffix(row, sep)
+	cells := strings.Split(row, sep)
+	for i := range cells {
+		cells[i] = strings.TrimSpace(cells[i])
+	}
+	return cells
+}
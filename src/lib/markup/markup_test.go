@@ -0,0 +1,162 @@
+package markup
+
+import (
+	"strings"
+	"testing"
+)
+
+func lines(l ...string) string {
+	return strings.Join(l, "\n")
+}
+
+var users = map[string]string{
+	"john": "john.doe",
+}
+
+func gitToJira(name string) (string, bool) {
+	n, ok := users[name]
+	return n, ok
+}
+
+func jiraToGit(name string) (string, bool) {
+	for login, n := range users {
+		if n == name {
+			return login, true
+		}
+	}
+	return "", false
+}
+
+func TestMarkdownToJira(t *testing.T) {
+	cases := []struct {
+		name, md, jira string
+	}{
+		{"heading", "## Steps to reproduce", "h2. Steps to reproduce"},
+		{"closed heading", "# Title #", "h1. Title"},
+		{"emphasis", "**bold**, *italic*, _also_ and ~~gone~~", "*bold*, _italic_, _also_ and -gone-"},
+		{"inline code", "run `make **all**` now", "run {{make **all**}} now"},
+		{"link", "see [docs](http://example.com/docs)", "see [docs|http://example.com/docs]"},
+		{"bare link", "<http://example.com>", "[http://example.com]"},
+		{"image", "![screenshot](/uploads/abc/shot.png)", "!/uploads/abc/shot.png!"},
+		{"mention", "ping @john and @jane", "ping [~john.doe] and @jane"},
+		{"email is not mention", "mail john@example.com", "mail john@example.com"},
+		{"rule", "---", "----"},
+		{"quote", "> single", "bq. single"},
+		{
+			"multiline quote",
+			lines("> first", "> second"),
+			lines("{quote}", "first", "second", "{quote}"),
+		},
+		{
+			"nested lists",
+			lines("- one", "  - nested", "    1. deep", "- two", "1. first", "2. second"),
+			lines("* one", "** nested", "**# deep", "* two", "# first", "# second"),
+		},
+		{
+			"code block",
+			lines("```go", "x := *y*", "```"),
+			lines("{code:go}", "x := *y*", "{code}"),
+		},
+		{
+			"code block without language",
+			lines("```", "[a](b)", "```"),
+			lines("{code}", "[a](b)", "{code}"),
+		},
+		{
+			"table",
+			lines("| Name | Value |", "|------|:-----:|", "| a | **b** |", "| c | |"),
+			lines("||Name||Value||", "|a|*b*|", "|c| |"),
+		},
+	}
+
+	for _, c := range cases {
+		if res := MarkdownToJira(c.md, gitToJira); res != c.jira {
+			t.Errorf("%s: unexpected conversion result:\n%q\nexpected:\n%q", c.name, res, c.jira)
+		}
+	}
+}
+
+func TestJiraToMarkdown(t *testing.T) {
+	cases := []struct {
+		name, jira, md string
+	}{
+		{"heading", "h3. Details", "### Details"},
+		{"emphasis", "*bold*, _italic_ and -gone- but not a-b-c", "**bold**, _italic_ and ~~gone~~ but not a-b-c"},
+		{"monospace", "call {{*ptr*}}", "call `*ptr*`"},
+		{"link", "see [docs|http://example.com/docs]", "see [docs](http://example.com/docs)"},
+		{"bare link", "[http://example.com]", "<http://example.com>"},
+		{"image", "!shot.png|thumbnail!", "![](shot.png)"},
+		{"mention", "ping [~john.doe] and [~jane]", "ping @john and @jane"},
+		{"color", "{color:red}alert{color}", "alert"},
+		{"rule", "----", "---"},
+		{"quote", "bq. quoted", "> quoted"},
+		{
+			"lists",
+			lines("* one", "** nested", "*# deep", "# first"),
+			lines("- one", "  - nested", "  1. deep", "1. first"),
+		},
+		{
+			"code macro",
+			lines("{code:language=java|title=Foo.java}", "int *x*;", "{code}"),
+			lines("```java", "int *x*;", "```"),
+		},
+		{
+			"inline code macro",
+			"{code:go}fmt.Println(){code}",
+			lines("```go", "fmt.Println()", "```"),
+		},
+		{
+			"noformat",
+			lines("{noformat}", "raw *text*", "{noformat}"),
+			lines("```", "raw *text*", "```"),
+		},
+		{
+			"panel",
+			lines("{panel:title=Heads up}", "*careful*", "{panel}"),
+			lines("> **Heads up**", ">", "> **careful**"),
+		},
+		{
+			"admonition",
+			lines("{warning}", "do not", "{warning}"),
+			lines("> **Warning**", ">", "> do not"),
+		},
+		{
+			"quote macro",
+			lines("{quote}", "first", "second", "{quote}"),
+			lines("> first", "> second"),
+		},
+		{
+			"table",
+			lines("||Name||Link||", "|a|[docs|http://x.y]|"),
+			lines("| Name | Link |", "| --- | --- |", "| a | [docs](http://x.y) |"),
+		},
+	}
+
+	for _, c := range cases {
+		if res := JiraToMarkdown(c.jira, jiraToGit); res != c.md {
+			t.Errorf("%s: unexpected conversion result:\n%q\nexpected:\n%q", c.name, res, c.md)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	md := lines(
+		"## Summary",
+		"",
+		"Crash on **start**, see [log](http://example.com/log) and ask @john.",
+		"",
+		"- first",
+		"  - second",
+		"",
+		"```sh",
+		"make run",
+		"```",
+		"",
+		"| Key | Value |",
+		"| --- | --- |",
+		"| a | b |",
+	)
+	if res := JiraToMarkdown(MarkdownToJira(md, gitToJira), jiraToGit); res != md {
+		t.Fatalf("round trip changed text:\n%s\nexpected:\n%s", res, md)
+	}
+}
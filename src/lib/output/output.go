@@ -0,0 +1,122 @@
+// Package output prints listings in machine-readable formats, so scripts
+// don't have to parse tables.
+package output
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/template"
+)
+
+const (
+	Table = "table"
+	JSON  = "json"
+	JSONL = "jsonl"
+	CSV   = "csv"
+	YAML  = "yaml"
+)
+
+// Flags are command line flags of listing commands.
+type Flags struct {
+	Output   string `short:"o" long:"output" default:"table" choice:"table" choice:"json" choice:"jsonl" choice:"csv" choice:"yaml" description:"output format"`
+	Template string `long:"template" description:"Go template applied to every item instead of output format, e.g. '{{.Key}} {{.Summary}}'"`
+}
+
+// Tabular reports if listing should be printed as table.
+func (fl Flags) Tabular() bool {
+	return fl.Template == "" && (fl.Output == "" || fl.Output == Table)
+}
+
+// Printer writes records one by one. JSON and YAML documents are written
+// on Close, other formats are streamed.
+type Printer struct {
+	w      io.Writer
+	format string
+	tmpl   *template.Template
+	csv    *csv.Writer
+	header []string
+	items  []interface{}
+}
+
+// New returns printer of records in format, or nil if records should be
+// printed as table by the caller. Template takes precedence over format.
+func New(w io.Writer, fl Flags) (*Printer, error) {
+	if fl.Template != "" {
+		t, err := template.New("output").Parse(fl.Template)
+		if err != nil {
+			return nil, fmt.Errorf("bad template: %v", err)
+		}
+		return &Printer{w: w, tmpl: t}, nil
+	}
+	switch fl.Output {
+	case "", Table:
+		return nil, nil
+	case JSON, JSONL, YAML:
+		return &Printer{w: w, format: fl.Output}, nil
+	case CSV:
+		return &Printer{w: w, format: CSV, csv: csv.NewWriter(w)}, nil
+	}
+	return nil, fmt.Errorf("unknown output format '%s'", fl.Output)
+}
+
+// Header sets names of CSV columns, it's written before the first row.
+func (p *Printer) Header(columns ...string) {
+	p.header = columns
+}
+
+// Print writes record. Row holds values of columns set by Header, it's
+// used by CSV format only.
+func (p *Printer) Print(record interface{}, row []string) error {
+	switch {
+	case p.tmpl != nil:
+		buf := new(bytes.Buffer)
+		if err := p.tmpl.Execute(buf, record); err != nil {
+			return err
+		}
+		if buf.Len() == 0 || buf.Bytes()[buf.Len()-1] != '\n' {
+			buf.WriteByte('\n')
+		}
+		_, err := p.w.Write(buf.Bytes())
+		return err
+	case p.format == JSONL:
+		return json.NewEncoder(p.w).Encode(record)
+	case p.format == CSV:
+		if p.header != nil {
+			if err := p.csv.Write(p.header); err != nil {
+				return err
+			}
+			p.header = nil
+		}
+		if err := p.csv.Write(row); err != nil {
+			return err
+		}
+		p.csv.Flush()
+		return p.csv.Error()
+	}
+	p.items = append(p.items, record)
+	return nil
+}
+
+// Close writes JSON or YAML document of all printed records.
+func (p *Printer) Close() error {
+	items := p.items
+	if items == nil {
+		// empty listing is an empty list, not null
+		items = []interface{}{}
+	}
+	switch p.format {
+	case JSON:
+		b, err := json.MarshalIndent(items, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = p.w.Write(append(b, '\n'))
+		return err
+	case YAML:
+		return writeYAML(p.w, items)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+)
+
+type record struct {
+	Key     string   `json:"key"`
+	Summary string   `json:"summary"`
+	Labels  []string `json:"labels"`
+	Fields  struct {
+		Sprint string `json:"sprint"`
+	} `json:"fields"`
+}
+
+func render(t *testing.T, fl Flags, records ...record) string {
+	buf := new(bytes.Buffer)
+	p, err := New(buf, fl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Header("key", "summary")
+	for _, r := range records {
+		if err := p.Print(r, []string{r.Key, r.Summary}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestFormats(t *testing.T) {
+	a := record{Key: "ABC-1", Summary: "Fix: login", Labels: []string{"bug", "ui"}}
+	a.Fields.Sprint = "Sprint 5"
+	b := record{Key: "ABC-2", Summary: "yes"}
+
+	tests := []struct {
+		fl   Flags
+		want string
+	}{
+		{Flags{Output: JSONL}, `{"key":"ABC-1","summary":"Fix: login","labels":["bug","ui"],"fields":{"sprint":"Sprint 5"}}` + "\n" +
+			`{"key":"ABC-2","summary":"yes","labels":null,"fields":{"sprint":""}}` + "\n"},
+		{Flags{Output: CSV}, "key,summary\nABC-1,Fix: login\nABC-2,yes\n"},
+		{Flags{Template: "{{.Key}} {{.Summary}}"}, "ABC-1 Fix: login\nABC-2 yes\n"},
+		{Flags{Output: YAML}, "- fields:\n    sprint: Sprint 5\n  key: ABC-1\n  labels:\n    - bug\n    - ui\n  summary: \"Fix: login\"\n" +
+			"- fields:\n    sprint: \"\"\n  key: ABC-2\n  labels: null\n  summary: \"yes\"\n"},
+	}
+	for _, tt := range tests {
+		if got := render(t, tt.fl, a, b); got != tt.want {
+			t.Errorf("%+v: expected\n%s\ngot\n%s", tt.fl, tt.want, got)
+		}
+	}
+}
+
+func TestEmptyListing(t *testing.T) {
+	if got := render(t, Flags{Output: JSON}); got != "[]\n" {
+		t.Errorf("expected empty JSON list, got %q", got)
+	}
+	if p, _ := New(nil, Flags{Output: Table}); p != nil {
+		t.Error("expected no printer for table format")
+	}
+}
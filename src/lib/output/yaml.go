@@ -0,0 +1,135 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// strings which are safe to leave unquoted
+var rePlain = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_ .,/@()+-]*$`)
+
+// words YAML parsers may read as booleans or null
+var reserved = map[string]bool{
+	"true": true, "false": true, "yes": true, "no": true, "on": true, "off": true,
+	"y": true, "n": true, "null": true,
+}
+
+// writeYAML writes list of items as YAML document. Items are converted
+// through JSON, so keys are the same as in JSON output, sorted.
+func writeYAML(w io.Writer, items []interface{}) error {
+	b, err := json.Marshal(items)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var tree []interface{}
+	if err := dec.Decode(&tree); err != nil {
+		return err
+	}
+
+	buf := new(bytes.Buffer)
+	if len(tree) == 0 {
+		buf.WriteString("[]\n")
+	}
+	writeList(buf, tree, 0)
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+func writeList(b *bytes.Buffer, l []interface{}, indent int) {
+	pad := strings.Repeat(" ", indent)
+	for _, item := range l {
+		switch x := item.(type) {
+		case map[string]interface{}:
+			if len(x) > 0 {
+				writeMap(b, x, indent+2, pad+"- ")
+				continue
+			}
+		case []interface{}:
+			if len(x) > 0 {
+				b.WriteString(pad + "-\n")
+				writeList(b, x, indent+2)
+				continue
+			}
+		}
+		b.WriteString(pad + "- " + scalar(item) + "\n")
+	}
+}
+
+// writeMap writes keys of m, the first one is prefixed with first
+// instead of indentation when map is an item of list.
+func writeMap(b *bytes.Buffer, m map[string]interface{}, indent int, first string) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pad := strings.Repeat(" ", indent)
+	for i, k := range keys {
+		if i == 0 && first != "" {
+			b.WriteString(first)
+		} else {
+			b.WriteString(pad)
+		}
+		b.WriteString(quote(k) + ":")
+		writeValue(b, m[k], indent)
+	}
+}
+
+// writeValue writes value of key at indent.
+func writeValue(b *bytes.Buffer, v interface{}, indent int) {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		if len(x) > 0 {
+			b.WriteString("\n")
+			writeMap(b, x, indent+2, "")
+			return
+		}
+	case []interface{}:
+		if len(x) > 0 {
+			b.WriteString("\n")
+			writeList(b, x, indent+2)
+			return
+		}
+	}
+	b.WriteString(" " + scalar(v) + "\n")
+}
+
+func scalar(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		if x {
+			return "true"
+		}
+		return "false"
+	case json.Number:
+		return x.String()
+	case string:
+		return quote(x)
+	case map[string]interface{}:
+		return "{}"
+	case []interface{}:
+		return "[]"
+	}
+	return ""
+}
+
+// quote leaves plain strings as they are, others are written as JSON
+// strings, which are valid double-quoted YAML scalars.
+func quote(s string) string {
+	if rePlain.MatchString(s) && !strings.HasSuffix(s, " ") && !reserved[strings.ToLower(s)] {
+		return s
+	}
+	buf := new(bytes.Buffer)
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
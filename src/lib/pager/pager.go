@@ -0,0 +1,175 @@
+// Package pager pages long output written to terminal, like git does.
+package pager
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"lib/logging"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+var (
+	disabled   bool
+	configured string
+)
+
+// Disable makes pagers write to stdout, it's what --no-pager does.
+func Disable() {
+	disabled = true
+}
+
+// Configure sets pager command from configuration. JIGIT_PAGER takes
+// precedence over it, PAGER is used if it's empty.
+func Configure(command string) {
+	configured = command
+}
+
+// command returns pager command, "less" by default.
+func command() string {
+	if c, ok := os.LookupEnv("JIGIT_PAGER"); ok {
+		return c
+	}
+	if configured != "" {
+		return configured
+	}
+	if c, ok := os.LookupEnv("PAGER"); ok {
+		return c
+	}
+	return "less"
+}
+
+// Pager writes to stdout. Output is held until it doesn't fit the screen,
+// then pager is started and output is streamed to it through a pipe.
+type Pager struct {
+	out     io.Writer
+	command string
+	width   int
+	height  int
+
+	buf    bytes.Buffer
+	lines  int
+	col    int
+	escape bool
+
+	cmd  *exec.Cmd
+	pipe io.WriteCloser
+	quit bool
+}
+
+// New returns pager, which writes to stdout directly if stdout is not
+// a terminal, paging is disabled or pager command is empty or "cat".
+func New() *Pager {
+	p := &Pager{out: os.Stdout}
+	fd := int(os.Stdout.Fd())
+	if disabled || !terminal.IsTerminal(fd) {
+		return p
+	}
+	c := strings.TrimSpace(command())
+	if c == "" || c == "cat" {
+		return p
+	}
+	w, h, err := terminal.GetSize(fd)
+	if err != nil || w <= 0 || h <= 0 {
+		return p
+	}
+	p.command, p.width, p.height = c, w, h
+	return p
+}
+
+func (p *Pager) Write(b []byte) (int, error) {
+	switch {
+	case p.pipe != nil:
+		p.send(b)
+		return len(b), nil
+	case p.command == "":
+		return p.out.Write(b)
+	}
+	p.buf.Write(b)
+	p.count(b)
+	// the last line of screen is taken by prompt
+	if p.lines >= p.height-1 {
+		p.start()
+	}
+	return len(b), nil
+}
+
+// count counts screen lines taken by b, long lines are wrapped. Color
+// sequences take no space.
+func (p *Pager) count(b []byte) {
+	for _, r := range string(b) {
+		if p.escape || r == '\x1b' {
+			p.escape = r != 'm'
+			continue
+		}
+		if r == '\n' {
+			p.lines++
+			p.col = 0
+			continue
+		}
+		w := runewidth.RuneWidth(r)
+		if p.col+w > p.width {
+			p.lines++
+			p.col = 0
+		}
+		p.col += w
+	}
+}
+
+// start starts pager and sends it output held so far. Output goes to
+// stdout if pager can't be started.
+func (p *Pager) start() {
+	cmd := exec.Command("sh", "-c", p.command)
+	cmd.Stdout, cmd.Stderr = p.out, os.Stderr
+	if _, ok := os.LookupEnv("LESS"); !ok {
+		// raw colors, no screen clearing on exit
+		cmd.Env = append(os.Environ(), "LESS=FRX")
+	}
+	pipe, err := cmd.StdinPipe()
+	if err == nil {
+		err = cmd.Start()
+	}
+	if err != nil {
+		logging.Warnf("can't start pager '%s': %s", p.command, err)
+		p.command = ""
+		p.out.Write(p.buf.Bytes())
+		p.buf.Reset()
+		return
+	}
+	p.cmd, p.pipe = cmd, pipe
+	p.send(p.buf.Bytes())
+	p.buf.Reset()
+}
+
+func (p *Pager) send(b []byte) {
+	if p.quit {
+		return
+	}
+	if _, err := p.pipe.Write(b); err != nil {
+		// pager was closed before the end of output, the rest isn't wanted
+		p.quit = true
+	}
+}
+
+// Close writes output which fits the screen to stdout, or waits until
+// user quits pager.
+func (p *Pager) Close() error {
+	if p.cmd == nil {
+		_, err := p.out.Write(p.buf.Bytes())
+		p.buf.Reset()
+		return err
+	}
+	p.pipe.Close()
+	err := p.cmd.Wait()
+	p.cmd, p.pipe = nil, nil
+	if err != nil && !p.quit {
+		return errors.Wrapf(err, "pager '%s' failed", p.command)
+	}
+	return nil
+}
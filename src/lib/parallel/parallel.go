@@ -0,0 +1,104 @@
+// Package parallel runs tasks concurrently with bounded number of workers.
+package parallel
+
+import (
+	"strings"
+	"sync"
+)
+
+// DefaultWorkers is a number of concurrent requests APIs tolerate well.
+const DefaultWorkers = 8
+
+// Errors is a list of errors of failed tasks.
+type Errors []error
+
+func (e Errors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Pool runs tasks with at most limit of them at once. Errors of all tasks
+// are collected and returned by Wait. Once pool is canceled, tasks which
+// haven't been started yet are skipped.
+type Pool struct {
+	sem  chan struct{}
+	wg   sync.WaitGroup
+	done chan struct{}
+	once sync.Once
+
+	// FailFast cancels the pool on the first failed task.
+	FailFast bool
+
+	mu   sync.Mutex
+	errs Errors
+}
+
+// New returns pool of limit workers, DefaultWorkers if limit isn't positive.
+func New(limit int) *Pool {
+	if limit <= 0 {
+		limit = DefaultWorkers
+	}
+	return &Pool{
+		sem:  make(chan struct{}, limit),
+		done: make(chan struct{}),
+	}
+}
+
+// Go schedules the task, blocking while all workers are busy.
+func (p *Pool) Go(task func() error) {
+	select {
+	case p.sem <- struct{}{}:
+	case <-p.done:
+		return
+	}
+	p.wg.Add(1)
+	go func() {
+		defer func() {
+			<-p.sem
+			p.wg.Done()
+		}()
+		// cancellation could come while waiting for the worker
+		select {
+		case <-p.done:
+			return
+		default:
+		}
+		if err := task(); err != nil {
+			p.mu.Lock()
+			p.errs = append(p.errs, err)
+			p.mu.Unlock()
+			if p.FailFast {
+				p.Cancel()
+			}
+		}
+	}()
+}
+
+// Cancel skips tasks which haven't been started yet. Long running tasks
+// may watch Done to stop early.
+func (p *Pool) Cancel() {
+	p.once.Do(func() { close(p.done) })
+}
+
+// Done is closed once the pool is canceled.
+func (p *Pool) Done() <-chan struct{} {
+	return p.done
+}
+
+// Wait blocks until all started tasks are finished and returns errors of
+// failed ones, nil if all of them succeed.
+func (p *Pool) Wait() error {
+	p.wg.Wait()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.errs) == 0 {
+		return nil
+	}
+	return p.errs
+}
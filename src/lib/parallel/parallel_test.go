@@ -0,0 +1,72 @@
+package parallel
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPoolLimit(t *testing.T) {
+	var running, peak int32
+	p := New(3)
+	for i := 0; i < 20; i++ {
+		p.Go(func() error {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				max := atomic.LoadInt32(&peak)
+				if n <= max || atomic.CompareAndSwapInt32(&peak, max, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			atomic.AddInt32(&running, -1)
+			return nil
+		})
+	}
+	if err := p.Wait(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if peak > 3 {
+		t.Errorf("%d tasks were running at once, limit is 3", peak)
+	}
+}
+
+func TestPoolErrors(t *testing.T) {
+	p := New(2)
+	for i := 0; i < 5; i++ {
+		i := i
+		p.Go(func() error {
+			if i%2 == 0 {
+				return errors.New("failed")
+			}
+			return nil
+		})
+	}
+	err := p.Wait()
+	errs, ok := err.(Errors)
+	if !ok {
+		t.Fatalf("expected Errors, got %#v", err)
+	}
+	if len(errs) != 3 {
+		t.Errorf("expected 3 errors, got %d", len(errs))
+	}
+}
+
+func TestPoolFailFast(t *testing.T) {
+	var started int32
+	p := New(1)
+	p.FailFast = true
+	for i := 0; i < 10; i++ {
+		p.Go(func() error {
+			atomic.AddInt32(&started, 1)
+			return errors.New("failed")
+		})
+	}
+	if err := p.Wait(); err == nil {
+		t.Fatal("expected error")
+	}
+	if started != 1 {
+		t.Errorf("expected pool to stop after the first failure, %d tasks started", started)
+	}
+}
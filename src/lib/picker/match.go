@@ -0,0 +1,60 @@
+package picker
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// score tells how well text matches query: letters of query must appear
+// in text in the same order. Adjacent letters and letters starting words
+// score higher. Negative score means text doesn't match.
+func score(query, text string) int {
+	q := []rune(strings.ToLower(query))
+	if len(q) == 0 {
+		return 0
+	}
+	var (
+		t    = []rune(strings.ToLower(text))
+		s    = 0
+		n    = 0
+		prev = -2
+	)
+	for i, r := range t {
+		if n == len(q) {
+			break
+		}
+		if r != q[n] {
+			continue
+		}
+		s++
+		if i == prev+1 {
+			s += 4
+		}
+		if i == 0 || !unicode.IsLetter(t[i-1]) && !unicode.IsDigit(t[i-1]) {
+			s += 2
+		}
+		prev = i
+		n++
+	}
+	if n < len(q) {
+		return -1
+	}
+	return s
+}
+
+// filter returns indexes of items matching query, the best matches first.
+func filter(query string, items []string) []int {
+	matched := make([]int, 0, len(items))
+	scores := make(map[int]int, len(items))
+	for i, item := range items {
+		if s := score(query, item); s >= 0 {
+			matched = append(matched, i)
+			scores[i] = s
+		}
+	}
+	sort.SliceStable(matched, func(a, b int) bool {
+		return scores[matched[a]] > scores[matched[b]]
+	})
+	return matched
+}
@@ -0,0 +1,34 @@
+package picker
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestScore(t *testing.T) {
+	tests := []struct {
+		query, text string
+		want        int
+	}{
+		{"", "anything", 0},
+		{"abc", "ab", -1},
+		{"ca", "abc", -1},
+		{"ab", "ab", 1 + 2 + 1 + 4},
+		{"JI", "group/jigit", 1 + 2 + 1 + 4},
+	}
+	for _, tt := range tests {
+		if got := score(tt.query, tt.text); got != tt.want {
+			t.Errorf("score(%q, %q) = %d, expected %d", tt.query, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	items := []string{"backend#3 fix login", "frontend#1 login page", "docs#7 typo"}
+	if got, want := filter("lp", items), []int{1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if got, want := filter("fi", items), []int{0, 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
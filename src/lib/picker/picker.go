@@ -0,0 +1,185 @@
+// Package picker lets user choose among candidates with fuzzy search when
+// command is called without an argument it needs.
+package picker
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// ErrCanceled is returned when user picks nothing.
+var ErrCanceled = errors.New("nothing has been picked")
+
+// maxShown is the number of candidates shown under the query.
+const maxShown = 10
+
+var command string
+
+// Configure sets external finder, e.g. "fzf", candidates are piped to it
+// one per line. Built-in finder is used if it's empty.
+func Configure(finder string) {
+	command = strings.TrimSpace(finder)
+}
+
+// Available reports if user can be asked to pick, which needs terminal.
+func Available() bool {
+	return terminal.IsTerminal(int(os.Stdin.Fd())) && terminal.IsTerminal(int(os.Stderr.Fd()))
+}
+
+// Pick returns index of item chosen by user.
+func Pick(prompt string, items []string) (int, error) {
+	if len(items) == 0 {
+		return 0, errors.Errorf("there is nothing to pick %s from", prompt)
+	}
+	if command != "" {
+		return external(prompt, items)
+	}
+	return builtin(prompt, items)
+}
+
+// external pipes items to finder, which prints the chosen one.
+func external(prompt string, items []string) (int, error) {
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stdin = strings.NewReader(strings.Join(items, "\n") + "\n")
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), "JIGIT_PICK="+prompt)
+	out, err := cmd.Output()
+	if err != nil {
+		if _, ok := err.(*exec.ExitError); ok {
+			// fzf exits with 1 if nothing matched and with 130 on Esc
+			return 0, ErrCanceled
+		}
+		return 0, errors.Wrapf(err, "can't run finder '%s'", command)
+	}
+	chosen := strings.TrimRight(string(out), "\r\n")
+	for i, item := range items {
+		if item == chosen {
+			return i, nil
+		}
+	}
+	return 0, ErrCanceled
+}
+
+// builtin draws query and best matches under the cursor on stderr.
+func builtin(prompt string, items []string) (int, error) {
+	fd := int(os.Stdin.Fd())
+	state, err := terminal.MakeRaw(fd)
+	if err != nil {
+		return 0, errors.Wrap(err, "can't switch terminal to raw mode")
+	}
+	defer terminal.Restore(fd, state)
+
+	width, _, err := terminal.GetSize(int(os.Stderr.Fd()))
+	if err != nil || width <= 0 {
+		width = 80
+	}
+	var (
+		query   []rune
+		cur     int
+		drawn   int
+		matched = filter("", items)
+		out     = bufio.NewWriter(os.Stderr)
+		in      = make([]byte, 16)
+	)
+	// clear removes lines drawn last time
+	clear := func() {
+		if drawn > 0 {
+			fmt.Fprintf(out, "\x1b[%dA", drawn)
+		}
+		out.WriteString("\r\x1b[J")
+	}
+	defer func() {
+		clear()
+		out.Flush()
+	}()
+
+	for {
+		clear()
+		fmt.Fprintf(out, "Pick %s> %s", prompt, string(query))
+		shown := matched
+		if len(shown) > maxShown {
+			shown = shown[:maxShown]
+		}
+		for i, n := range shown {
+			line := cut(items[n], width-3)
+			if i == cur {
+				fmt.Fprintf(out, "\r\n\x1b[7m> %s\x1b[0m", line)
+			} else {
+				fmt.Fprintf(out, "\r\n  %s", line)
+			}
+		}
+		fmt.Fprintf(out, "\r\n  %d/%d", len(matched), len(items))
+		drawn = len(shown) + 1
+		// cursor goes back to the query
+		fmt.Fprintf(out, "\x1b[%dA\r\x1b[%dC", drawn, runewidth.StringWidth("Pick "+prompt+"> "+string(query)))
+		drawn = 0
+		out.Flush()
+
+		n, err := os.Stdin.Read(in)
+		if err != nil {
+			return 0, err
+		}
+		if n == 0 {
+			continue
+		}
+		b := in[:n]
+		switch {
+		case bytes.Equal(b, []byte{3}) || bytes.Equal(b, []byte{27}):
+			return 0, ErrCanceled
+		case b[0] == '\r' || b[0] == '\n':
+			if len(matched) == 0 {
+				continue
+			}
+			return matched[cur], nil
+		case bytes.Equal(b, []byte("\x1b[A")) || b[0] == 16: // up, Ctrl-P
+			if cur > 0 {
+				cur--
+			}
+			continue
+		case bytes.Equal(b, []byte("\x1b[B")) || b[0] == 14: // down, Ctrl-N
+			if cur < len(shown)-1 {
+				cur++
+			}
+			continue
+		case b[0] == 27: // left, right, Home, End, F-keys and so on
+			continue
+		case b[0] == 127 || b[0] == 8:
+			if len(query) > 0 {
+				query = query[:len(query)-1]
+			}
+		case b[0] == 21: // Ctrl-U
+			query = query[:0]
+		default:
+			for len(b) > 0 {
+				r, size := utf8.DecodeRune(b)
+				if unicode.IsPrint(r) {
+					query = append(query, r)
+				}
+				b = b[size:]
+			}
+		}
+		matched, cur = filter(string(query), items), 0
+	}
+}
+
+// cut shortens s to width cells.
+func cut(s string, width int) string {
+	s = strings.Replace(s, "\t", " ", -1)
+	w := 0
+	for i, r := range s {
+		if w += runewidth.RuneWidth(r); w > width {
+			return s[:i]
+		}
+	}
+	return s
+}
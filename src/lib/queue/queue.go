@@ -0,0 +1,158 @@
+package queue
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+
+	"lib/apierr"
+	"lib/storage"
+	"lib/util"
+
+	"github.com/pkg/errors"
+)
+
+var ErrNotFound = errors.New("queued operation not found")
+
+const (
+	// ArgOperation keeps ID of journal operation started by push of entry.
+	// If push was interrupted, operation is resumed instead of started again.
+	ArgOperation = "operation"
+	// ArgEntry keeps ID of entry in arguments of operation started by its push.
+	ArgEntry = "queue_entry"
+)
+
+// Queue keeps operations which were postponed because remote was unreachable
+// or user asked to work offline. Entries are pushed in order they were added.
+type Queue struct {
+	storage *storage.Storage
+}
+
+func New(store *storage.Storage) *Queue {
+	return &Queue{storage: store}
+}
+
+// Enqueue appends operation of provided kind to the end of queue.
+// Kinds are the same as journal operation kinds.
+func (q *Queue) Enqueue(kind string, args map[string]string) (*Entry, error) {
+	id, err := q.storage.NextID(storage.BucketQueue)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't allocate queue id")
+	}
+	e := &Entry{
+		ID:      id,
+		Kind:    kind,
+		Args:    args,
+		Created: time.Now(),
+	}
+	return e, q.Update(e)
+}
+
+// Entries returns all queued operations, oldest first.
+func (q *Queue) Entries() ([]*Entry, error) {
+	entries := make([]*Entry, 0)
+	fn := func(k, v []byte) error {
+		e := new(Entry)
+		if err := e.Decode(v); err != nil {
+			return errors.Wrapf(err, "can't decode queued operation %d", util.Btoi(k))
+		}
+		entries = append(entries, e)
+		return nil
+	}
+	if err := q.storage.ForEach(storage.BucketQueue, fn); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (q *Queue) Entry(id int) (*Entry, error) {
+	b, err := q.storage.Get(storage.BucketQueue, util.Itob(id))
+	if err != nil {
+		if err == storage.ErrNoData {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	e := new(Entry)
+	return e, e.Decode(b)
+}
+
+func (q *Queue) Update(e *Entry) error {
+	buf := new(bytes.Buffer)
+	if err := e.Encode(buf); err != nil {
+		return errors.Wrapf(err, "can't encode queued operation %d", e.ID)
+	}
+	return q.storage.Set(storage.BucketQueue, util.Itob(e.ID), buf.Bytes())
+}
+
+func (q *Queue) Drop(id int) error {
+	if _, err := q.Entry(id); err != nil {
+		return err
+	}
+	return q.storage.Delete(storage.BucketQueue, util.Itob(id))
+}
+
+// Owns reports if operation is being pushed from queue. Such operations are
+// resumed by next push, so recovery must leave them alone.
+func (q *Queue) Owns(opID int, opArgs map[string]string) bool {
+	id, err := strconv.Atoi(opArgs[ArgEntry])
+	if err != nil {
+		return false
+	}
+	e, err := q.Entry(id)
+	if err != nil {
+		return false
+	}
+	return e.Args[ArgOperation] == strconv.Itoa(opID)
+}
+
+type Entry struct {
+	ID      int
+	Kind    string
+	Args    map[string]string
+	Created time.Time
+	// LastError keeps reason of last failed push attempt.
+	LastError string
+}
+
+func (e *Entry) Encode(into io.Writer) error {
+	return gob.NewEncoder(into).Encode(e)
+}
+
+func (e *Entry) Decode(v []byte) error {
+	return gob.NewDecoder(bytes.NewBuffer(v)).Decode(e)
+}
+
+// Unreachable reports if err was caused by network failure or timeout,
+// so operation could be postponed instead of failing. Requests canceled
+// by user are not postponed, neither are ones rejected by remote, unless
+// it kept being rate limited or unavailable after retries.
+func Unreachable(err error) bool {
+	if err == nil {
+		return false
+	}
+	cause := errors.Cause(err)
+	if e, ok := cause.(*apierr.Error); ok {
+		if _, ok := e.Err.(*url.Error); !ok {
+			return false
+		}
+		cause = e.Err
+	}
+	inner := cause
+	if e, ok := cause.(*url.Error); ok {
+		inner = e.Err
+	}
+	if inner == context.Canceled {
+		return false
+	}
+	switch cause.(type) {
+	case *url.Error, net.Error:
+		return true
+	}
+	return inner == context.DeadlineExceeded
+}
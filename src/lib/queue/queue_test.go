@@ -0,0 +1,119 @@
+package queue
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"lib/apierr"
+	"lib/storage"
+
+	"github.com/pkg/errors"
+)
+
+func newQueue(t *testing.T) *Queue {
+	dir, err := ioutil.TempDir("", "jigit-queue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	disk, err := storage.NewStorage(filepath.Join(dir, "jigit.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		disk.Close()
+		os.RemoveAll(dir)
+	})
+	return New(disk)
+}
+
+func TestEntries(t *testing.T) {
+	q := newQueue(t)
+	ids := make([]int, 0)
+	for i := 0; i < 300; i++ {
+		e, err := q.Enqueue("commit", map[string]string{"body": strconv.Itoa(i)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, e.ID)
+	}
+	if err := q.Drop(ids[1]); err != nil {
+		t.Fatal(err)
+	}
+	ids = append(ids[:1], ids[2:]...)
+
+	entries, err := q.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(ids) {
+		t.Fatalf("expected %d entries, got %d", len(ids), len(entries))
+	}
+	for n, e := range entries {
+		if e.ID != ids[n] {
+			t.Fatalf("expected entry %d at %d, got %d", ids[n], n, e.ID)
+		}
+	}
+	if err := q.Drop(ids[0] + 1000); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound for unknown entry, got %v", err)
+	}
+}
+
+func TestOwns(t *testing.T) {
+	q := newQueue(t)
+	pushed, err := q.Enqueue("add", map[string]string{ArgOperation: "5"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waiting, err := q.Enqueue("add", map[string]string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		opID int
+		args map[string]string
+		owns bool
+	}{
+		{"pushed", 5, map[string]string{ArgEntry: strconv.Itoa(pushed.ID)}, true},
+		{"other operation", 6, map[string]string{ArgEntry: strconv.Itoa(pushed.ID)}, false},
+		{"entry not pushed yet", 5, map[string]string{ArgEntry: strconv.Itoa(waiting.ID)}, false},
+		{"entry dropped", 5, map[string]string{ArgEntry: "1000"}, false},
+		{"not from queue", 5, map[string]string{}, false},
+	}
+	for _, tt := range tests {
+		if owns := q.Owns(tt.opID, tt.args); owns != tt.owns {
+			t.Errorf("%s: Owns = %v, expected %v", tt.name, owns, tt.owns)
+		}
+	}
+}
+
+func TestUnreachable(t *testing.T) {
+	dial := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"plain", errors.New("boom"), false},
+		{"dial", &url.Error{Op: "Get", URL: "https://gitlab", Err: dial}, true},
+		{"wrapped dial", errors.Wrap(&url.Error{Op: "Get", URL: "https://gitlab", Err: dial}, "can't fetch"), true},
+		{"net", dial, true},
+		{"timeout", &url.Error{Op: "Get", URL: "https://jira", Err: context.DeadlineExceeded}, true},
+		{"canceled", &url.Error{Op: "Get", URL: "https://jira", Err: context.Canceled}, false},
+		{"rejected", &apierr.Error{Kind: apierr.Validation, Service: "Jira", Status: 400}, false},
+		{"api unreachable", &apierr.Error{Service: "Jira", Err: &url.Error{Op: "Get", URL: "https://jira", Err: dial}}, true},
+	}
+	for _, tt := range tests {
+		if got := Unreachable(tt.err); got != tt.want {
+			t.Errorf("%s: Unreachable = %v, expected %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+// Package ref parses references to GitLab issues, merge requests and Jira
+// tickets given on command line, and resolves them through links.
+package ref
+
+import (
+	"fmt"
+	"net/url"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"lib/util"
+
+	"github.com/pkg/errors"
+)
+
+// Kind is the kind of referenced item.
+type Kind int
+
+const (
+	Issue        Kind = iota + 1 // GitLab issue, project#12
+	MergeRequest                 // GitLab merge request, project!34
+	Ticket                       // Jira ticket, ABC-123
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Issue:
+		return "GitLab issue"
+	case MergeRequest:
+		return "merge request"
+	case Ticket:
+		return "Jira ticket"
+	}
+	return "unknown"
+}
+
+// Ref references GitLab issue, merge request or Jira ticket.
+type Ref struct {
+	Kind Kind
+	// Project is GitLab project name or path with namespace,
+	// e.g. "group/sub/project"
+	Project string
+	IID     int
+	// Key is Jira ticket key
+	Key string
+}
+
+func (r Ref) String() string {
+	switch r.Kind {
+	case Issue:
+		return fmt.Sprintf("%s#%d", r.Project, r.IID)
+	case MergeRequest:
+		return fmt.Sprintf("%s!%d", r.Project, r.IID)
+	}
+	return r.Key
+}
+
+// IsGitLab reports if r references GitLab issue or merge request.
+func (r Ref) IsGitLab() bool {
+	return r.Kind == Issue || r.Kind == MergeRequest
+}
+
+// HasPath reports if project of r is given by path with namespace rather
+// than by name.
+func (r Ref) HasPath() bool {
+	return strings.Contains(r.Project, "/")
+}
+
+var (
+	gitRef  = regexp.MustCompile(`^(.*)([#!])(\w+)$`)
+	jiraKey = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*-[0-9]+$`)
+	number  = regexp.MustCompile(`^[0-9]+$`)
+
+	// GitLab puts "-" between project path and the item since 11.x
+	gitURL  = regexp.MustCompile(`^/(.+?)(?:/-)?/(issues|merge_requests)/([0-9]+)/?$`)
+	jiraURL = regexp.MustCompile(`/(?:browse|issues)/([A-Za-z][A-Za-z0-9_]*-[0-9]+)/?$`)
+)
+
+// Parse parses reference given as project#12, group/sub/project#12, #12,
+// project!34, !34, ABC-123 or URL of any of them. Project of #12 and !34
+// is the GitLab project of the repository in the working directory.
+func Parse(s string) (Ref, error) {
+	s = strings.TrimSpace(s)
+	switch {
+	case s == "":
+		return Ref{}, util.Usage("empty issue reference")
+	case strings.Contains(s, "://"):
+		return parseURL(s)
+	case jiraKey.MatchString(s):
+		return Ref{Kind: Ticket, Key: strings.ToUpper(s)}, nil
+	case number.MatchString(s):
+		return Ref{}, util.Usage("'%[1]s' is ambiguous, use project#%[1]s or #%[1]s for GitLab issue and KEY-%[1]s for Jira ticket", s)
+	}
+
+	m := gitRef.FindStringSubmatch(s)
+	if m == nil {
+		return Ref{}, util.Usage("can't recognize '%s', expected project#12, #12, project!34, ABC-123 or URL", s)
+	}
+	r := Ref{Kind: Issue, Project: m[1]}
+	if m[2] == "!" {
+		r.Kind = MergeRequest
+	}
+	iid, err := strconv.Atoi(m[3])
+	if err != nil || iid <= 0 {
+		return Ref{}, util.Usage("bad %s ID '%s' in '%s'", r.Kind, m[3], s)
+	}
+	r.IID = iid
+	if r.Project == "" {
+		if r.Project, err = currentProject(); err != nil {
+			return Ref{}, util.Usage("can't tell project of '%s': %s, use project%[1]s instead", s, err)
+		}
+	}
+	return r, nil
+}
+
+func parseURL(s string) (Ref, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return Ref{}, util.Usage("bad URL '%s': %s", s, err)
+	}
+	if m := gitURL.FindStringSubmatch(u.Path); m != nil {
+		r := Ref{Kind: Issue, Project: m[1]}
+		if m[2] == "merge_requests" {
+			r.Kind = MergeRequest
+		}
+		r.IID, _ = strconv.Atoi(m[3])
+		return r, nil
+	}
+	if m := jiraURL.FindStringSubmatch(u.Path); m != nil {
+		return Ref{Kind: Ticket, Key: strings.ToUpper(m[1])}, nil
+	}
+	// boards show tickets as ?selectedIssue=ABC-123
+	if key := u.Query().Get("selectedIssue"); jiraKey.MatchString(key) {
+		return Ref{Kind: Ticket, Key: strings.ToUpper(key)}, nil
+	}
+	return Ref{}, util.Usage("URL '%s' points neither to GitLab issue or merge request nor to Jira ticket", s)
+}
+
+// ParseAll parses references and requires at most one reference to GitLab
+// and one to Jira, either of them is nil if it's missing.
+func ParseAll(args []string) (gitlab, jira *Ref, err error) {
+	for _, arg := range args {
+		r, err := Parse(arg)
+		if err != nil {
+			return nil, nil, err
+		}
+		dst, side := &jira, "Jira"
+		if r.IsGitLab() {
+			dst, side = &gitlab, "GitLab"
+		}
+		if *dst != nil {
+			return nil, nil, util.Usage("both '%s' and '%s' refer to %s, expected one", *dst, r, side)
+		}
+		*dst = &r
+	}
+	return gitlab, jira, nil
+}
+
+// currentProject returns path of the project the working directory is
+// cloned from.
+var currentProject = func() (string, error) {
+	out, err := exec.Command("git", "config", "--get", "remote.origin.url").Output()
+	if err != nil {
+		return "", errors.New("working directory is not a git repository with origin remote")
+	}
+	return remotePath(strings.TrimSpace(string(out)))
+}
+
+// remotePath extracts project path from git remote URL, which is either
+// URL or scp-like git@host:group/project.git.
+func remotePath(remote string) (string, error) {
+	p := remote
+	if strings.Contains(remote, "://") {
+		u, err := url.Parse(remote)
+		if err != nil {
+			return "", errors.Wrapf(err, "bad remote '%s'", remote)
+		}
+		p = u.Path
+	} else if n := strings.Index(remote, ":"); n >= 0 {
+		p = remote[n+1:]
+	}
+	p = strings.TrimSuffix(strings.Trim(p, "/"), ".git")
+	if p == "" {
+		return "", errors.Errorf("can't find project path in remote '%s'", remote)
+	}
+	return p, nil
+}
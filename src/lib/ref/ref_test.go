@@ -0,0 +1,79 @@
+package ref
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	currentProject = func() (string, error) { return "group/current", nil }
+
+	tests := []struct {
+		in   string
+		want Ref
+	}{
+		{"backend#12", Ref{Kind: Issue, Project: "backend", IID: 12}},
+		{"group/sub/backend#12", Ref{Kind: Issue, Project: "group/sub/backend", IID: 12}},
+		{"#12", Ref{Kind: Issue, Project: "group/current", IID: 12}},
+		{"backend!34", Ref{Kind: MergeRequest, Project: "backend", IID: 34}},
+		{"!34", Ref{Kind: MergeRequest, Project: "group/current", IID: 34}},
+		{"abc-123", Ref{Kind: Ticket, Key: "ABC-123"}},
+		{"https://gitlab.example.com/group/sub/backend/-/issues/12", Ref{Kind: Issue, Project: "group/sub/backend", IID: 12}},
+		{"https://gitlab.example.com/group/backend/issues/12/", Ref{Kind: Issue, Project: "group/backend", IID: 12}},
+		{"https://gitlab.example.com/group/backend/-/merge_requests/34#note_1", Ref{Kind: MergeRequest, Project: "group/backend", IID: 34}},
+		{"https://jira.example.com/browse/ABC-123", Ref{Kind: Ticket, Key: "ABC-123"}},
+		{"https://jira.example.com/projects/ABC/issues/ABC-123", Ref{Kind: Ticket, Key: "ABC-123"}},
+		{"https://jira.example.com/secure/RapidBoard.jspa?rapidView=1&selectedIssue=ABC-7", Ref{Kind: Ticket, Key: "ABC-7"}},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if err != nil {
+			t.Errorf("Parse(%q): %s", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Parse(%q) = %+v, expected %+v", tt.in, got, tt.want)
+		}
+	}
+
+	for _, in := range []string{"", "12", "backend", "backend#x", "backend#0", "https://gitlab.example.com/group/backend"} {
+		if r, err := Parse(in); err == nil {
+			t.Errorf("Parse(%q) = %+v, expected error", in, r)
+		}
+	}
+}
+
+func TestParseOutsideRepository(t *testing.T) {
+	currentProject = func() (string, error) { return "", errors.New("not a git repository") }
+	if _, err := Parse("#12"); err == nil {
+		t.Error("expected error for #12 outside of repository")
+	}
+}
+
+func TestParseAll(t *testing.T) {
+	git, ticket, err := ParseAll([]string{"ABC-1", "backend#2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if git == nil || git.String() != "backend#2" || ticket == nil || ticket.String() != "ABC-1" {
+		t.Errorf("unexpected references %v and %v", git, ticket)
+	}
+	if _, _, err := ParseAll([]string{"backend#2", "backend!3"}); err == nil {
+		t.Error("expected error for two GitLab references")
+	}
+}
+
+func TestRemotePath(t *testing.T) {
+	tests := map[string]string{
+		"git@gitlab.example.com:group/sub/backend.git":        "group/sub/backend",
+		"ssh://git@gitlab.example.com:2222/group/backend.git": "group/backend",
+		"https://gitlab.example.com/group/backend.git":        "group/backend",
+		"https://gitlab.example.com/group/backend/":           "group/backend",
+	}
+	for remote, want := range tests {
+		got, err := remotePath(remote)
+		if err != nil || got != want {
+			t.Errorf("remotePath(%q) = %q, %v, expected %q", remote, got, err, want)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package ref
+
+import (
+	"context"
+
+	"lib/git"
+	"lib/storage"
+
+	"github.com/pkg/errors"
+)
+
+// Resolver follows links between GitLab issues and Jira tickets.
+type Resolver struct {
+	disk *storage.Storage
+	gitc *git.Git // created on first use if nil
+}
+
+func NewResolver(disk *storage.Storage, gitc *git.Git) *Resolver {
+	return &Resolver{disk: disk, gitc: gitc}
+}
+
+// Project returns GitLab project of r.
+func (res *Resolver) Project(ctx context.Context, r Ref) (*git.Project, error) {
+	if !r.IsGitLab() {
+		return nil, errors.Errorf("%s is not in GitLab", r)
+	}
+	if res.gitc == nil {
+		gitc, err := git.NewWithStorage(res.disk)
+		if err != nil {
+			return nil, err
+		}
+		res.gitc = gitc
+	}
+	return res.gitc.FindProject(ctx, r.Project)
+}
+
+// Key returns r with project name, which links are stored by. Project is
+// fetched only if r has project path.
+func (res *Resolver) Key(ctx context.Context, r Ref) (Ref, error) {
+	if !r.IsGitLab() || !r.HasPath() {
+		return r, nil
+	}
+	p, err := res.Project(ctx, r)
+	if err != nil {
+		return Ref{}, err
+	}
+	if r.Project, err = res.linkName(ctx, p); err != nil {
+		return Ref{}, err
+	}
+	return r, nil
+}
+
+// linkName returns name of project p, which must be unique, otherwise links
+// of projects sharing it would be mixed up.
+func (res *Resolver) linkName(ctx context.Context, p *git.Project) (string, error) {
+	named, err := res.gitc.ProjectByName(ctx, p.Name, false, false)
+	if err == nil && named.ID != p.ID {
+		err = errors.Errorf("project name '%s' is shared with %s", p.Name, named.Path)
+	}
+	if err != nil {
+		return "", errors.Wrapf(err, "issues of %s can't be linked", p.Path)
+	}
+	return p.Name, nil
+}
+
+// Linked returns reference to the other side of link of r, ok is false if
+// r isn't linked.
+func (res *Resolver) Linked(ctx context.Context, r Ref) (linked Ref, ok bool, err error) {
+	if r.Kind == MergeRequest {
+		return Ref{}, false, errors.Errorf("%s can't be linked to Jira, only issues can", r)
+	}
+	if r, err = res.Key(ctx, r); err != nil {
+		return Ref{}, false, err
+	}
+	v, err := res.disk.GetString(storage.BucketIssueLinks, []byte(r.String()))
+	if err != nil || v == "" {
+		return Ref{}, false, nil
+	}
+	if linked, err = Parse(v); err != nil {
+		return Ref{}, false, errors.Wrapf(err, "bad link of %s", r)
+	}
+	return linked, true, nil
+}
+
+// GitLab returns GitLab issue r refers to with its project, Jira ticket is
+// replaced with GitLab issue linked to it. Project of returned reference
+// is the project name.
+func (res *Resolver) GitLab(ctx context.Context, r Ref) (Ref, *git.Project, error) {
+	if r.Kind == Ticket {
+		linked, ok, err := res.Linked(ctx, r)
+		if err != nil {
+			return Ref{}, nil, err
+		}
+		if !ok {
+			return Ref{}, nil, errors.Errorf("%s is not linked to GitLab issue, link it with 'jigit ln'", r)
+		}
+		r = linked
+	}
+	p, err := res.Project(ctx, r)
+	if err != nil {
+		return Ref{}, nil, err
+	}
+	if !r.HasPath() {
+		r.Project = p.Name
+	} else if r.Project, err = res.linkName(ctx, p); err != nil {
+		return Ref{}, nil, err
+	}
+	return r, p, nil
+}
+
+// Jira returns key of Jira ticket r refers to, GitLab issue is replaced
+// with Jira ticket linked to it.
+func (res *Resolver) Jira(ctx context.Context, r Ref) (string, error) {
+	if r.Kind == Ticket {
+		return r.Key, nil
+	}
+	linked, ok, err := res.Linked(ctx, r)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", errors.Errorf("%s has no linked Jira ticket, link it with 'jigit ln'", r)
+	}
+	return linked.Key, nil
+}
@@ -21,7 +21,11 @@ var (
 	BucketGitProjectCache = []byte("git-project-cache")
 	BucketGitIssueCache   = []byte("git-issue-cache")
 	BucketJiraIssueCache  = []byte("jira-issue-cache")
+	BucketJiraFieldCache  = []byte("jira-field-cache")
 	BucketIssueLinks      = []byte("issue-links")
+	BucketJournal         = []byte("journal")
+	BucketQueue           = []byte("queue")
+	BucketUserMap         = []byte("user-map")
 
 	KeyGitlabUser = []byte("gitlab.user")
 	KeyGitlabPass = []byte("gitlab.pass")
@@ -40,16 +44,17 @@ func NewStorage(filepath string) (*Storage, error) {
 		BucketGitIssueCache,
 		BucketGitProjectCache,
 		BucketJiraIssueCache,
+		BucketJiraFieldCache,
 		BucketIssueLinks,
+		BucketJournal,
+		BucketQueue,
+		BucketUserMap,
 	}
 
 	for _, key := range buckets {
 		fn := func(tx *bolt.Tx) error {
 			_, err := tx.CreateBucketIfNotExists(key)
-			if err != nil {
-				fmt.Printf("can't initalize bucket '%s': %s\n", string(key), err)
-			}
-			return err
+			return errors.Wrapf(err, "can't initialize bucket '%s'", key)
 		}
 		if err := b.Update(fn); err != nil {
 			return nil, err
@@ -66,6 +71,35 @@ func (s *Storage) Set(bucket, key, value []byte) error {
 	return s.b.Update(fn)
 }
 
+func (s *Storage) Delete(bucket, key []byte) error {
+	fn := func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucket)
+		if b == nil {
+			return ErrBucketNotExist
+		}
+		return b.Delete(key)
+	}
+	return s.b.Update(fn)
+}
+
+// NextID returns next unique identifier for provided bucket.
+func (s *Storage) NextID(bucket []byte) (int, error) {
+	var id uint64
+	fn := func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucket)
+		if b == nil {
+			return ErrBucketNotExist
+		}
+		var err error
+		id, err = b.NextSequence()
+		return err
+	}
+	if err := s.b.Update(fn); err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
+
 func (s *Storage) CreateSymlink(jiraKey, gitProject string, gitIssueID int) error {
 	fn := func(tx *bolt.Tx) error {
 		b := tx.Bucket(BucketIssueLinks)
@@ -0,0 +1,142 @@
+// Package theme colors detailed views and fits them to terminal width.
+package theme
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Color modes of --color flag.
+const (
+	Auto   = "auto"
+	Always = "always"
+	Never  = "never"
+)
+
+// DefaultWidth is the text width when output is not a terminal.
+const DefaultWidth = 100
+
+// minWidth keeps text readable in narrow terminals.
+const minWidth = 40
+
+const (
+	reset   = "\x1b[0m"
+	bold    = "\x1b[1m"
+	faint   = "\x1b[2m"
+	reverse = "\x1b[7m"
+	red     = "\x1b[31m"
+	green   = "\x1b[32m"
+	yellow  = "\x1b[33m"
+	blue    = "\x1b[34m"
+	magenta = "\x1b[35m"
+	cyan    = "\x1b[36m"
+)
+
+var (
+	enabled bool
+	width   = DefaultWidth
+)
+
+// Setup enables colors by mode. Auto mode colors output of terminal unless
+// NO_COLOR is set. Text width follows columns of terminal, if they're known.
+func Setup(mode string, tty bool, columns int) error {
+	switch mode {
+	case "", Auto:
+		enabled = tty && os.Getenv("NO_COLOR") == ""
+	case Always:
+		enabled = true
+	case Never:
+		enabled = false
+	default:
+		return fmt.Errorf("unknown color mode '%s', expected auto, always or never", mode)
+	}
+	width = DefaultWidth
+	if tty && columns > 0 {
+		// text is indented by a space and shouldn't touch the edge
+		width = columns - 2
+		if width < minWidth {
+			width = minWidth
+		}
+	}
+	return nil
+}
+
+// Enabled reports if output is colored.
+func Enabled() bool {
+	return enabled
+}
+
+// Width returns width of text in detailed views.
+func Width() int {
+	return width
+}
+
+func paint(style, s string) string {
+	if !enabled || s == "" {
+		return s
+	}
+	return style + s + reset
+}
+
+// Key highlights issue keys and IDs.
+func Key(s string) string {
+	return paint(bold, s)
+}
+
+// State colors issue state: open ones are green, ones in progress are
+// yellow and closed ones are red.
+func State(s string) string {
+	l := strings.ToLower(s)
+	switch {
+	case l == "closed" || l == "done" || l == "resolved" || l == "rejected" || strings.HasPrefix(l, "won"):
+		return paint(red, s)
+	case strings.Contains(l, "progress") || strings.Contains(l, "review") || strings.Contains(l, "test"):
+		return paint(yellow, s)
+	}
+	return paint(green, s)
+}
+
+// Priority colors Jira priority by its urgency.
+func Priority(s string) string {
+	switch strings.ToLower(s) {
+	case "highest", "blocker", "critical":
+		return paint(bold+red, s)
+	case "high", "major":
+		return paint(red, s)
+	case "medium":
+		return paint(yellow, s)
+	case "low", "lowest", "minor", "trivial":
+		return paint(blue, s)
+	}
+	return s
+}
+
+// Labels colors labels and joins them by comma.
+func Labels(labels []string) string {
+	colored := make([]string, len(labels))
+	for i, l := range labels {
+		colored[i] = paint(cyan, l)
+	}
+	return strings.Join(colored, ", ")
+}
+
+// Author colors names of people.
+func Author(s string) string {
+	return paint(magenta, s)
+}
+
+// Time dims times, they are the least important.
+func Time(s string) string {
+	return paint(faint, s)
+}
+
+// Selected highlights current line of lists.
+func Selected(s string) string {
+	return paint(reverse, s)
+}
+
+// Rule returns separator line of c spanning text width.
+func Rule(c rune) string {
+	return " " + paint(faint, strings.Repeat(string(c), width)) + "\n"
+}
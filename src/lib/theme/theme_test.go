@@ -0,0 +1,73 @@
+package theme
+
+import (
+	"os"
+	"testing"
+)
+
+func TestSetup(t *testing.T) {
+	defer Setup(Never, false, 0)
+	os.Unsetenv("NO_COLOR")
+
+	tests := []struct {
+		mode    string
+		tty     bool
+		noColor string
+		enabled bool
+	}{
+		{Auto, true, "", true},
+		{Auto, false, "", false},
+		{Auto, true, "1", false},
+		{Always, false, "1", true},
+		{Never, true, "", false},
+	}
+	for _, tt := range tests {
+		os.Setenv("NO_COLOR", tt.noColor)
+		if err := Setup(tt.mode, tt.tty, 80); err != nil {
+			t.Fatal(err)
+		}
+		if Enabled() != tt.enabled {
+			t.Errorf("%s, tty %t, NO_COLOR=%q: expected enabled %t", tt.mode, tt.tty, tt.noColor, tt.enabled)
+		}
+	}
+	os.Unsetenv("NO_COLOR")
+
+	if err := Setup("rainbow", true, 80); err == nil {
+		t.Error("expected error for unknown mode")
+	}
+}
+
+func TestWidth(t *testing.T) {
+	defer Setup(Never, false, 0)
+	tests := []struct {
+		tty     bool
+		columns int
+		width   int
+	}{
+		{true, 80, 78},
+		{true, 20, minWidth},
+		{false, 80, DefaultWidth},
+		{true, 0, DefaultWidth},
+	}
+	for _, tt := range tests {
+		Setup(Never, tt.tty, tt.columns)
+		if Width() != tt.width {
+			t.Errorf("tty %t, %d columns: expected width %d, got %d", tt.tty, tt.columns, tt.width, Width())
+		}
+	}
+}
+
+func TestPaint(t *testing.T) {
+	defer Setup(Never, false, 0)
+	Setup(Always, false, 0)
+	if s := State("In Progress"); s != yellow+"In Progress"+reset {
+		t.Errorf("unexpected state %q", s)
+	}
+	if s := State("closed"); s != red+"closed"+reset {
+		t.Errorf("unexpected state %q", s)
+	}
+	Setup(Never, false, 0)
+	if s := Priority("Blocker"); s != "Blocker" {
+		t.Errorf("expected no color, got %q", s)
+	}
+}
@@ -0,0 +1,70 @@
+package transport
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"lib/logging"
+)
+
+const redacted = "REDACTED"
+
+// secret headers and query parameters are never logged
+var (
+	secretHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "Private-Token", "Job-Token"}
+	secretParams  = []string{"private_token", "access_token", "password", "token"}
+)
+
+// trace logs request and its outcome at debug level.
+func (t *Transport) trace(req *http.Request, resp *http.Response, err error, attempt int, took time.Duration) {
+	if !logging.Enabled(logging.Debug) {
+		return
+	}
+	fields := logging.Fields{
+		"service":  t.Service,
+		"method":   req.Method,
+		"url":      redactURL(req.URL),
+		"attempt":  attempt,
+		"duration": took.Round(time.Millisecond).String(),
+		"request":  redactHeader(req.Header),
+	}
+	if err != nil {
+		fields["error"] = err.Error()
+	} else {
+		fields["status"] = resp.StatusCode
+		fields["response"] = redactHeader(resp.Header)
+	}
+	logging.Log(logging.Debug, "http", fields)
+}
+
+func redactURL(u *url.URL) string {
+	c := *u
+	if c.User != nil {
+		c.User = url.User(redacted)
+	}
+	q := c.Query()
+	for key := range q {
+		for _, s := range secretParams {
+			if strings.EqualFold(key, s) {
+				q.Set(key, redacted)
+			}
+		}
+	}
+	c.RawQuery = q.Encode()
+	return c.String()
+}
+
+func redactHeader(h http.Header) map[string]string {
+	out := make(map[string]string, len(h))
+	for key, values := range h {
+		out[key] = strings.Join(values, ", ")
+		for _, s := range secretHeaders {
+			if strings.EqualFold(key, s) {
+				out[key] = redacted
+			}
+		}
+	}
+	return out
+}
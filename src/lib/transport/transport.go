@@ -0,0 +1,233 @@
+// Package transport retries requests to GitLab and Jira which failed
+// because of rate limits or temporary outages.
+package transport
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"lib/logging"
+)
+
+const (
+	maxAttempts = 4
+	minDelay    = 500 * time.Millisecond
+	maxDelay    = 10 * time.Second
+	// longer waits asked by server are not worth blocking the command
+	maxServerDelay = time.Minute
+)
+
+// RetryError is returned once request is still failing after all attempts
+// or server asks to wait for too long.
+type RetryError struct {
+	Service  string
+	Status   int
+	Attempts int
+	Wait     time.Duration // how long server asked to wait, if it did
+}
+
+func (e *RetryError) Error() string {
+	msg := fmt.Sprintf("%s responded with %d %s", e.Service, e.Status, http.StatusText(e.Status))
+	if e.Attempts > 1 {
+		msg += fmt.Sprintf(" %d times", e.Attempts)
+	}
+	if e.Wait > 0 {
+		msg += fmt.Sprintf(", try again in %s", e.Wait.Round(time.Second))
+	}
+	return msg
+}
+
+// Transport retries requests with jittered exponential backoff, waiting for
+// as long as Retry-After or RateLimit-Reset headers ask. Requests which may
+// change something on server are retried only if they surely weren't
+// processed: rejected by rate limit or not sent at all.
+type Transport struct {
+	Service string
+	// Timeout limits every single attempt, zero means no limit.
+	Timeout time.Duration
+	Base    http.RoundTripper
+}
+
+// New returns transport retrying requests to service over http.DefaultTransport.
+func New(service string, timeout time.Duration) *Transport {
+	return &Transport{Service: service, Timeout: timeout}
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return http.DefaultTransport
+}
+
+// RoundTrip doesn't modify req, every retry is sent as its clone with
+// a fresh body.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := req
+	for attempt := 1; ; attempt++ {
+		start := time.Now()
+		resp, err := t.try(r)
+		t.trace(r, resp, err, attempt, time.Since(start))
+		if attempt == maxAttempts || !retryable(req, resp, err) {
+			if err == nil && attempt > 1 && retryStatus(resp.StatusCode) {
+				wait, _ := serverDelay(resp)
+				drain(resp)
+				return nil, &RetryError{Service: t.Service, Status: resp.StatusCode, Attempts: attempt, Wait: wait}
+			}
+			return resp, err
+		}
+
+		delay := backoff(attempt)
+		if resp != nil {
+			if wait, ok := serverDelay(resp); ok {
+				if wait > maxServerDelay {
+					drain(resp)
+					return nil, &RetryError{Service: t.Service, Status: resp.StatusCode, Attempts: attempt, Wait: wait}
+				}
+				delay = wait
+			}
+			drain(resp)
+		}
+		r = req.Clone(req.Context())
+		if req.Body != nil {
+			if r.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+
+		logging.Debugf("%s %s failed, retrying in %s", req.Method, req.URL.Path, delay.Round(time.Millisecond))
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+// try sends request once. Timeout covers reading of response body as well,
+// so it's released only when the body is closed.
+func (t *Transport) try(req *http.Request) (*http.Response, error) {
+	if t.Timeout == 0 {
+		return t.base().RoundTrip(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), t.Timeout)
+	resp, err := t.base().RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// retryable reports if failed request could be sent once more.
+func retryable(req *http.Request, resp *http.Response, err error) bool {
+	if req.Body != nil && req.GetBody == nil {
+		// body is consumed already
+		return false
+	}
+	if req.Context().Err() != nil {
+		return false
+	}
+	if err != nil {
+		return idempotent(req) || notSent(err)
+	}
+	if !retryStatus(resp.StatusCode) {
+		return false
+	}
+	return idempotent(req) || rateLimited(resp)
+}
+
+func retryStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case "GET", "HEAD", "OPTIONS", "PUT", "DELETE":
+		return true
+	}
+	return false
+}
+
+// rateLimited responses are rejected before request is processed. Jira
+// responds 503 with Retry-After when it throttles.
+func rateLimited(resp *http.Response) bool {
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	return resp.StatusCode == http.StatusServiceUnavailable && resp.Header.Get("Retry-After") != ""
+}
+
+// notSent reports if request failed before reaching server.
+func notSent(err error) bool {
+	e, ok := err.(*net.OpError)
+	return ok && e.Op == "dial"
+}
+
+// serverDelay returns how long server asked to wait: Retry-After in seconds
+// or HTTP date, or RateLimit-Reset as Unix time.
+func serverDelay(resp *http.Response) (time.Duration, bool) {
+	if v := resp.Header.Get("Retry-After"); v != "" {
+		if s, err := strconv.Atoi(v); err == nil {
+			return time.Duration(s) * time.Second, true
+		}
+		if t, err := http.ParseTime(v); err == nil {
+			return nonNegative(time.Until(t)), true
+		}
+	}
+	if v := resp.Header.Get("RateLimit-Reset"); v != "" {
+		if s, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return nonNegative(time.Until(time.Unix(s, 0))), true
+		}
+	}
+	return 0, false
+}
+
+func nonNegative(d time.Duration) time.Duration {
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+// backoff returns delay before the next attempt, growing twice with every
+// attempt. Half of it is random, so parallel requests don't retry at once.
+func backoff(attempt int) time.Duration {
+	d := minDelay << uint(attempt-1)
+	if d > maxDelay {
+		d = maxDelay
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// drain reads the rest of response body, so connection could be reused.
+func drain(resp *http.Response) {
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 4096))
+	resp.Body.Close()
+}
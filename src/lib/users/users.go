@@ -0,0 +1,86 @@
+package users
+
+import (
+	"strings"
+
+	"lib/storage"
+)
+
+const (
+	prefixGit  = "gitlab:"
+	prefixJira = "jira:"
+)
+
+// Map keeps pairs of GitLab username and Jira user name (account ID on
+// Jira Cloud) which belong to the same person.
+type Map struct {
+	storage *storage.Storage
+}
+
+func New(store *storage.Storage) *Map {
+	return &Map{storage: store}
+}
+
+type Pair struct {
+	GitLogin string
+	JiraName string
+}
+
+// Set links GitLab user with Jira user. Previous links of both users are removed.
+func (m *Map) Set(gitLogin, jiraName string) error {
+	if old, ok := m.JiraName(gitLogin); ok {
+		m.storage.Delete(storage.BucketUserMap, []byte(prefixJira+old))
+	}
+	if old, ok := m.GitLogin(jiraName); ok {
+		m.storage.Delete(storage.BucketUserMap, []byte(prefixGit+old))
+	}
+
+	err := m.storage.Set(storage.BucketUserMap, []byte(prefixGit+gitLogin), []byte(jiraName))
+	if err != nil {
+		return err
+	}
+	return m.storage.Set(storage.BucketUserMap, []byte(prefixJira+jiraName), []byte(gitLogin))
+}
+
+// Drop removes link of provided GitLab user.
+func (m *Map) Drop(gitLogin string) error {
+	jiraName, ok := m.JiraName(gitLogin)
+	if !ok {
+		return storage.ErrNoData
+	}
+	if err := m.storage.Delete(storage.BucketUserMap, []byte(prefixGit+gitLogin)); err != nil {
+		return err
+	}
+	return m.storage.Delete(storage.BucketUserMap, []byte(prefixJira+jiraName))
+}
+
+func (m *Map) JiraName(gitLogin string) (string, bool) {
+	name, err := m.storage.GetString(storage.BucketUserMap, []byte(prefixGit+gitLogin))
+	return name, err == nil
+}
+
+func (m *Map) GitLogin(jiraName string) (string, bool) {
+	login, err := m.storage.GetString(storage.BucketUserMap, []byte(prefixJira+jiraName))
+	return login, err == nil
+}
+
+// Pairs returns all known links.
+func (m *Map) Pairs() ([]Pair, error) {
+	pairs := make([]Pair, 0)
+	fn := func(k, v []byte) error {
+		if login := string(k); strings.HasPrefix(login, prefixGit) {
+			pairs = append(pairs, Pair{GitLogin: strings.TrimPrefix(login, prefixGit), JiraName: string(v)})
+		}
+		return nil
+	}
+	return pairs, m.storage.ForEach(storage.BucketUserMap, fn)
+}
+
+// JiraAuthor returns markdown mention of GitLab user if the user is known in Jira,
+// so it becomes Jira mention on the way, or name with GitLab username otherwise.
+func (m *Map) JiraAuthor(gitLogin, name string) string {
+	if _, ok := m.JiraName(gitLogin); ok {
+		return "@" + gitLogin
+	}
+	return name + " (@" + gitLogin + ")"
+}
@@ -0,0 +1,20 @@
+package util
+
+import "fmt"
+
+var sizeUnits = []string{"B", "KiB", "MiB", "GiB", "TiB"}
+
+// HumanSize formats size in bytes with binary unit prefix.
+//
+// HumanSize(1536) -> "1.5 KiB"
+func HumanSize(size int) string {
+	if size < 1024 {
+		return fmt.Sprintf("%d %s", size, sizeUnits[0])
+	}
+	s, unit := float64(size), 0
+	for s >= 1024 && unit < len(sizeUnits)-1 {
+		s /= 1024
+		unit++
+	}
+	return fmt.Sprintf("%.1f %s", s, sizeUnits[unit])
+}
@@ -11,68 +11,96 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"runtime"
 	"strings"
 	"syscall"
-	"unicode/utf8"
 
+	"github.com/mattn/go-runewidth"
 	"golang.org/x/crypto/ssh/terminal"
 )
 
+// UsageError is returned when command is called with wrong arguments.
+type UsageError string
+
+func (e UsageError) Error() string {
+	return string(e)
+}
+
+// Usage returns UsageError with formatted message.
+func Usage(format string, args ...interface{}) error {
+	return UsageError(fmt.Sprintf(format, args...))
+}
+
 // AskCredentials gets login and password unencrypted from user's input.
-func AskCredentials(site string) (login string, pass string) {
-	fmt.Printf("Username for '%s': ", site)
+// Prompts go to stderr, so they don't mix with output of commands.
+func AskCredentials(site string) (login string, pass string, err error) {
+	fmt.Fprintf(os.Stderr, "Username for '%s': ", site)
 	fmt.Scanf("%s", &login)
-	fmt.Printf("Password for '%s': ", site)
+	fmt.Fprintf(os.Stderr, "Password for '%s': ", site)
 	b, err := terminal.ReadPassword(syscall.Stdin)
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		return "", "", fmt.Errorf("can't read password: %v", err)
 	}
-	fmt.Printf("\n")
-	pass = string(b)
-	return
+	fmt.Fprintln(os.Stderr)
+	return login, string(b), nil
 }
 
 // AskPassphrase gets encryption key from user and calculates it's SHA256 hash.
 // Input is covered by standard terminal trick.
-func AskPassphrase() []byte {
-	fmt.Printf("Enter passphrase: ")
+func AskPassphrase() ([]byte, error) {
+	fmt.Fprintf(os.Stderr, "Enter passphrase: ")
 	key, err := terminal.ReadPassword(syscall.Stdin)
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		return nil, fmt.Errorf("can't read passphrase: %v", err)
 	}
-	fmt.Printf("\n")
+	fmt.Fprintln(os.Stderr)
 
 	hasher := sha256.New()
 	hasher.Write([]byte(key))
-	return hasher.Sum(nil)
+	return hasher.Sum(nil), nil
 }
 
-// TruncateString truncates str to witdh if needed and append ... to truncated string.
+// TruncateString truncates str to width if needed and append ... to truncated string.
+// Width is counted in terminal cells, so wide CJK characters and emoji take two.
 func TruncateString(str string, width int) string {
-	l := utf8.RuneCountInString(str)
-	if l <= width {
+	if runewidth.StringWidth(str) <= width {
 		return str
 	}
-	str = fmt.Sprintf(fmt.Sprintf("%%.%ds", width), str)
-	if l > width {
-		str += "..."
+	w := 0
+	for i, r := range str {
+		if w += runewidth.RuneWidth(r); w > width {
+			return str[:i] + "..."
+		}
+	}
+	return str
+}
+
+// PadString truncates str if needed and pads it with spaces, so it takes exactly
+// width cells. Tables printed page by page keep their columns aligned this way.
+func PadString(str string, width int) string {
+	if runewidth.StringWidth(str) > width {
+		if width < 3 {
+			// no room for ellipsis, just cut
+			str = runewidth.Truncate(str, width, "")
+		} else {
+			str = TruncateString(str, width-3)
+		}
+	}
+	if pad := width - runewidth.StringWidth(str); pad > 0 {
+		str += strings.Repeat(" ", pad)
 	}
 	return str
 }
 
 // StringToFixedWidth rewrites provided str to fit provided width adding '\n' when needed.
+// Width is counted in terminal cells.
 func StringToFixedWidth(str string, width int) string {
-	str = strings.Replace(str, "{noformat}", "", -1)
 	s := bufio.NewScanner(strings.NewReader(str))
 
 	buf := new(bytes.Buffer)
 	for s.Scan() {
 		buf.WriteString(" ")
 		line := s.Text()
-		if utf8.RuneCountInString(line)-1 < width { // take inserted space into account
+		if runewidth.StringWidth(line)-1 < width { // take inserted space into account
 			buf.WriteString(line)
 			buf.WriteString("\n")
 			continue
@@ -80,8 +108,8 @@ func StringToFixedWidth(str string, width int) string {
 		f := strings.Fields(line)
 		tot := 0
 		for i := 0; i < len(f); i++ {
-			l := utf8.RuneCountInString(f[i])
-			if tot+l >= width {
+			l := runewidth.StringWidth(f[i])
+			if tot+l >= width && tot > 0 {
 				buf.WriteString("\n ")
 				tot = 0
 			}
@@ -93,17 +121,6 @@ func StringToFixedWidth(str string, width int) string {
 	return buf.String()
 }
 
-func Debug(format string, argv ...interface{}) {
-	var srcFileInfo string
-	if pc, file, line, ok := runtime.Caller(1); ok {
-		fnameElems := strings.Split(file, "/")
-		funcNameElems := strings.Split(runtime.FuncForPC(pc).Name(), "/")
-		srcFileInfo = fmt.Sprintf("[caused by %s:%d %s]",
-			strings.Join(fnameElems[len(fnameElems)-3:], "/"), line, funcNameElems[len(funcNameElems)-1])
-	}
-	fmt.Printf("[DEBUG] "+format+" "+srcFileInfo+"\n", argv...)
-}
-
 // Encrypt encrypts provided data by provided key with AES-256.
 func Encrypt(key []byte, data string) ([]byte, error) {
 	c, err := aes.NewCipher(key)
@@ -44,3 +44,50 @@ func TestDecrypt(t *testing.T) {
 
 	t.Logf("%v -> %s", ciphertext, string(c))
 }
+
+func TestTruncateString(t *testing.T) {
+	tests := []struct {
+		str   string
+		width int
+		want  string
+	}{
+		{"short", 10, "short"},
+		{"abcdef", 3, "abc..."},
+		{"日本語のテキスト", 6, "日本語..."},
+		{"日本語", 5, "日本..."},
+	}
+	for _, tt := range tests {
+		if got := TruncateString(tt.str, tt.width); got != tt.want {
+			t.Errorf("TruncateString(%q, %d) = %q, expected %q", tt.str, tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestStringToFixedWidth(t *testing.T) {
+	got := StringToFixedWidth("日本語 日本語 日本語", 14)
+	if want := " 日本語 日本語 \n 日本語 \n"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestPadString(t *testing.T) {
+	tests := []struct {
+		str   string
+		width int
+		want  string
+	}{
+		{"short", 8, "short   "},
+		{"exactly", 7, "exactly"},
+		{"truncated", 7, "trun..."},
+		{"日本語のテキスト", 10, "日本語... "},
+		{"narrow", 2, "na"},
+		{"日本語", 1, " "},
+		{"none", 0, ""},
+		{"negative", -1, ""},
+	}
+	for _, tt := range tests {
+		if got := PadString(tt.str, tt.width); got != tt.want {
+			t.Errorf("PadString(%q, %d) = %q, expected %q", tt.str, tt.width, got, tt.want)
+		}
+	}
+}
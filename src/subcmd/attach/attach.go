@@ -0,0 +1,192 @@
+package attach
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"lib/git"
+	"lib/interrupt"
+	"lib/jira"
+	"lib/picker"
+	"lib/ref"
+	"lib/storage"
+	"lib/util"
+	"subcmd/config"
+	"subcmd/pick"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	sideGitLab = "gitlab"
+	sideJira   = "jira"
+)
+
+type Cmd struct {
+	Message string `short:"m" long:"message" description:"comment to post along with files"`
+	Only    string `long:"only" choice:"gitlab" choice:"jira" description:"attach files to one side only"`
+
+	Active bool
+	Argv   []string
+}
+
+func (c *Cmd) Execute(argv []string) error {
+	c.Active, c.Argv = true, argv
+	return process(interrupt.Context(), c)
+}
+
+func process(ctx context.Context, c *Cmd) error {
+	// issue is picked if only files are given
+	picking := len(c.Argv) > 0 && isFile(c.Argv[0]) && picker.Available()
+	if len(c.Argv) < 2 && !picking {
+		return util.Usage("Provide issue and files to attach: jigit attach project_name#issue_id|JIRA-ID file [file...]")
+	}
+	files := c.Argv[1:]
+	if picking {
+		files = c.Argv
+	}
+	for _, f := range files {
+		if _, err := os.Stat(f); err != nil {
+			return err
+		}
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	picker.Configure(cfg.Picker)
+	disk, err := storage.NewStorage(cfg.Storage.Path)
+	if err != nil {
+		return err
+	}
+	defer disk.Close()
+
+	if picking {
+		picked, err := pick.Issue(ctx, cfg, disk)
+		if err != nil {
+			return err
+		}
+		c.Argv = append([]string{picked}, files...)
+	}
+
+	r, err := ref.Parse(c.Argv[0])
+	if err != nil {
+		return err
+	}
+	if r.Kind == ref.MergeRequest {
+		return errors.Errorf("%s is a merge request, files can be attached to issues only", r)
+	}
+	// issue could be referenced from both sides, links are symmetric
+	var gitRef, ticketRef *ref.Ref
+	if r.Kind == ref.Ticket {
+		ticketRef = &r
+	} else {
+		gitRef = &r
+	}
+	linked, ok, err := ref.NewResolver(disk, nil).Linked(ctx, r)
+	if err != nil {
+		return err
+	}
+	if ok && gitRef == nil {
+		gitRef = &linked
+	} else if ok {
+		ticketRef = &linked
+	}
+	if c.Only == sideGitLab && gitRef == nil || c.Only == sideJira && ticketRef == nil {
+		return errors.Errorf("%s has no linked %s issue", c.Argv[0], c.Only)
+	}
+
+	if gitRef != nil && c.Only != sideJira {
+		if err := attachToGit(ctx, *gitRef, files, c.Message, disk); err != nil {
+			return err
+		}
+	}
+	if ticketRef != nil && c.Only != sideGitLab {
+		if err := attachToJira(ctx, ticketRef.Key, files, c.Message, disk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isFile(name string) bool {
+	fi, err := os.Stat(name)
+	return err == nil && !fi.IsDir()
+}
+
+// GitLab issues have no attachments, so files are uploaded to the project
+// and posted as a comment.
+func attachToGit(ctx context.Context, r ref.Ref, files []string, message string, disk *storage.Storage) error {
+	gitc, err := git.NewWithStorage(disk)
+	if err != nil {
+		return err
+	}
+	p, err := gitc.FindProject(ctx, r.Project)
+	if err != nil {
+		return err
+	}
+	iid := r.IID
+
+	refs := make([]string, 0, len(files))
+	for _, f := range files {
+		upload, err := gitc.UploadFile(ctx, p.ID, f)
+		if err != nil {
+			return errors.Wrapf(err, "can't upload %s", f)
+		}
+		refs = append(refs, upload.Markdown)
+	}
+	body := strings.Join(refs, "\n")
+	if message != "" {
+		body = message + "\n\n" + body
+	}
+	if _, err := gitc.Comment(ctx, p.ID, iid, body); err != nil {
+		return err
+	}
+	fmt.Printf("%s attached to %s#%d.\n", countFiles(files), p.Name, iid)
+	return nil
+}
+
+func attachToJira(ctx context.Context, ticket string, files []string, message string, disk *storage.Storage) error {
+	jirac, err := jira.NewWithStorage(disk)
+	if err != nil {
+		return err
+	}
+
+	refs := make([]string, 0, len(files))
+	for _, name := range files {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		a, err := jirac.Attach(ctx, ticket, filepath.Base(name), f)
+		f.Close()
+		if err != nil {
+			return errors.Wrapf(err, "can't attach %s", name)
+		}
+		image := strings.HasPrefix(a.MimeType, "image/")
+		link := "[" + a.Filename + "](" + jirac.AttachmentRef(a, image) + ")"
+		if image {
+			link = "!" + link
+		}
+		refs = append(refs, link)
+	}
+	// attachments are visible without comment, but message deserves a context
+	if message != "" {
+		if _, err := jirac.Comment(ctx, ticket, message+"\n\n"+strings.Join(refs, "\n")); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("%s attached to %s.\n", countFiles(files), ticket)
+	return nil
+}
+
+func countFiles(files []string) string {
+	if len(files) == 1 {
+		return filepath.Base(files[0])
+	}
+	return fmt.Sprintf("%d files", len(files))
+}
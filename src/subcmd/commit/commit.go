@@ -2,7 +2,7 @@ package commit
 
 import (
 	"bufio"
-	"bytes"
+	"context"
 	"fmt"
 	"os"
 	"strconv"
@@ -10,18 +10,26 @@ import (
 
 	"lib/editor"
 	"lib/git"
+	"lib/interrupt"
 	"lib/jira"
+	"lib/journal"
+	"lib/picker"
+	"lib/queue"
+	"lib/ref"
 	"lib/storage"
+	"lib/util"
 	"subcmd/config"
+	"subcmd/pick"
+	"subcmd/recovery"
 
-	bfconf "github.com/kentaro-m/blackfriday-confluence"
-	bf "gopkg.in/russross/blackfriday.v2"
+	"github.com/pkg/errors"
 )
 
 type Cmd struct {
 	Message string `short:"m" long:"message" description:"commit message"`
 	Issue   string `short:"i" description:"gitlab issue id to commit on"`
 	Status  string `short:"s" long:"status" description:"new issue status.Changes both"`
+	Offline bool   `long:"offline" description:"queue commit and send it later with 'jigit queue push'"`
 
 	Active bool
 	Argv   []string
@@ -29,42 +37,23 @@ type Cmd struct {
 
 func (c *Cmd) Execute(v []string) error {
 	c.Active, c.Argv = true, v
-	return process(c)
+	return process(interrupt.Context(), c)
 }
 
-func parseNameID(argv []string) (gitlabProject string, issueID int) {
-	for i := 0; i < len(argv); i++ {
-		if !strings.Contains(argv[i], "#") {
-			continue
-		}
-		parts := strings.Split(argv[i], "#")
-		gitlabProject = parts[0]
-
-		var err error
-		issueID, err = strconv.Atoi(parts[1])
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Bad issue id '%s': %s\n", parts[1], err)
-			os.Exit(1)
-		}
-	}
-	return
-}
-
-func process(c *Cmd) error {
+func process(ctx context.Context, c *Cmd) error {
 	//if c.Issue == "" {
 	//	fmt.Fprintln(os.Stderr, "You should provide issue ID to commit.")
 	//	os.Exit(1)
 	//}
-	if len(c.Argv) < 1 {
-		fmt.Fprintln(os.Stderr,
-			"Provide issue ID to commit on with next syntax: project_name#issue_id or via -i flag.")
-		os.Exit(1)
+	if len(c.Argv) < 1 && !picker.Available() {
+		return util.Usage("Provide issue to commit on as project_name#issue_id, #issue_id, Jira ticket key or URL.")
 	}
 
 	cfg, err := config.Load()
 	if err != nil {
 		return err
 	}
+	picker.Configure(cfg.Picker)
 
 	disk, err := storage.NewStorage(cfg.Storage.Path)
 	if err != nil {
@@ -72,14 +61,49 @@ func process(c *Cmd) error {
 	}
 	defer disk.Close()
 
-	projectName, issueID := parseNameID(c.Argv)
-	if projectName == "" || issueID == 0 {
-		fmt.Fprintf(os.Stderr, "You should specify on which issue you want to commit on. See --help for details.")
-		os.Exit(1)
+	if len(c.Argv) < 1 {
+		ref, err := pick.Issue(ctx, cfg, disk)
+		if err != nil {
+			return err
+		}
+		c.Argv = []string{ref}
 	}
 
-	ticketID, err := disk.GetString(storage.BucketIssueLinks, []byte(c.Argv[0]))
+	r, err := ref.Parse(c.Argv[0])
 	if err != nil {
+		return err
+	}
+	if r.Kind == ref.MergeRequest {
+		return util.Usage("%s is a merge request, only issues can be committed on", r)
+	}
+	// issue could be referenced from both sides, links are symmetric
+	res := ref.NewResolver(disk, nil)
+	issueRef, ticketRef := r, r
+	if r.Kind == ref.Ticket {
+		linked, ok, err := res.Linked(ctx, r)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.Errorf("%s is not linked to GitLab issue, link it with 'jigit ln'", r)
+		}
+		issueRef = linked
+	} else {
+		if issueRef, err = res.Key(ctx, r); err != nil {
+			return err
+		}
+		linked, ok, err := res.Linked(ctx, issueRef)
+		if err != nil {
+			return err
+		}
+		ticketRef = linked
+		if !ok {
+			ticketRef = ref.Ref{}
+		}
+	}
+	projectName, issueID, ticketID := issueRef.Project, issueRef.IID, ticketRef.Key
+
+	if ticketID == "" {
 		fmt.Fprintf(os.Stderr,
 			"Linked ticket was not not found for issue %s#%d, continue commit only in GitLab (y/n)?\n",
 			projectName, issueID)
@@ -112,39 +136,94 @@ func process(c *Cmd) error {
 
 	c.Message = strings.Trim(c.Message, "\n ")
 	if c.Message == "" {
-		fmt.Fprintln(os.Stderr, "Nothing to commit. Provide commit message via -m or --message flag.")
-		os.Exit(1)
+		return util.Usage("Nothing to commit. Provide commit message via -m or --message flag.")
+	}
+
+	args := map[string]string{
+		"project": projectName,
+		"issue":   strconv.Itoa(issueID),
+		"ticket":  ticketID,
+		"body":    c.Message,
+	}
+	if c.Offline {
+		return enqueue(disk, args)
 	}
 
-	jiraText := md2jira(c.Message)
 	git, err := git.NewWithStorage(disk)
 	if err != nil {
 		return err
 	}
 
-	p, err := git.ProjectByName(projectName, false, false)
+	p, err := git.ProjectByName(ctx, projectName, false, false)
 	if err != nil {
+		if queue.Unreachable(err) {
+			return enqueue(disk, args)
+		}
 		return err
 	}
+	args["pid"] = strconv.Itoa(p.ID)
 
 	jira, err := jira.NewWithStorage(disk)
 	if err != nil {
 		return err
 	}
 
-	cid, err := git.Comment(p.ID, issueID, c.Message)
+	ops := journal.New(disk)
+	op, err := ops.Begin(journal.KindCommit, args)
+	if err != nil {
+		return err
+	}
+
+	cid, err := git.Comment(ctx, p.ID, issueID, c.Message)
 	if err != nil {
+		op.Finish()
+		if queue.Unreachable(err) {
+			return enqueue(disk, args)
+		}
 		return err
 	}
+	if err = op.Done(journal.StepGitComment, strconv.Itoa(cid)); err != nil {
+		return err
+	}
+
+	if ticketID == "" {
+		return op.Finish()
+	}
 
-	if err := jira.Comment(ticketID, jiraText); err != nil {
-		fmt.Fprintf(os.Stderr, "can't create Jira ticket: %s", err)
+	body, err := recovery.JiraComment(ctx, op, git, jira, p)
+	if err != nil {
+		return err
+	}
+	jcid, err := jira.Comment(ctx, ticketID, body)
+	if err != nil {
+		if queue.Unreachable(err) {
+			e, qerr := recovery.Postpone(op, disk)
+			if qerr != nil {
+				return errors.Wrapf(qerr, "can't queue operation %d", op.ID)
+			}
+			fmt.Fprintf(os.Stderr,
+				"GitLab comment has been added, Jira one is queued as operation %d, send it with 'jigit queue push'.\n", e.ID)
+			return errors.Wrap(err, "can't create Jira comment")
+		}
 		// rollback gitlab commit
-		if err = git.DeleteComment(p.ID, issueID, cid); err != nil {
-			fmt.Fprintf(os.Stderr, "can't remove GitLab commit: %s", err)
+		if cerr := recovery.Compensate(op, git, jira, disk); cerr != nil {
+			fmt.Fprintf(os.Stderr, "Can't remove GitLab comment: %s\n", cerr)
+			fmt.Fprintf(os.Stderr, "Operation %d has been saved, run 'jigit recover' to finish it.\n", op.ID)
 		}
+		return errors.Wrap(err, "can't create Jira comment")
+	}
+	if err = op.Done(journal.StepJiraComment, jcid); err != nil {
+		return err
 	}
+	return op.Finish()
+}
 
+func enqueue(disk *storage.Storage, args map[string]string) error {
+	e, err := queue.New(disk).Enqueue(journal.KindCommit, args)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Commit has been queued as operation %d, send it with 'jigit queue push'.\n", e.ID)
 	return nil
 }
 
@@ -174,9 +253,3 @@ func promptYN() (bool, error) {
 	}
 	return false, nil
 }
-
-func md2jira(msg string) string {
-	renderer := &bfconf.Renderer{}
-	md := bf.New(bf.WithRenderer(renderer), bf.WithExtensions(bf.CommonExtensions))
-	return bytes.NewBuffer(renderer.Render(md.Parse([]byte(msg)))).String()
-}
@@ -7,6 +7,10 @@ import (
 	"os/user"
 	"path"
 	"strconv"
+	"strings"
+	"time"
+
+	"lib/storage"
 
 	"github.com/BurntSushi/toml"
 )
@@ -14,8 +18,9 @@ import (
 const defaultStoragePath = "/var/lib/jigit/cache"
 
 type Cmd struct {
-	Set bool `long:"set" description:"save key value pair"`
-	Get bool `long:"get" description:"get current config values"`
+	Set    bool `long:"set" description:"save key value pair"`
+	Get    bool `long:"get" description:"get current config values"`
+	Logout bool `long:"logout" description:"forget stored GitLab and Jira credentials"`
 
 	Active bool
 	Argv   []string
@@ -23,17 +28,98 @@ type Cmd struct {
 
 type Config struct {
 	Editor string
+	Pager  string `toml:"pager"`
+	Picker string `toml:"picker"`
 	GitLab struct {
 		Address string `toml:"address"`
+		Timeout int    `toml:"timeout"`
 	} `toml:"gitlab"`
 	Jira struct {
-		Address string `toml:"address"`
+		Address    string `toml:"address"`
+		Timeout    int    `toml:"timeout"`
+		APIVersion int    `toml:"api_version"`
+		Project    string `toml:"project"`
+		IssueType  string `toml:"issue_type"`
+		// GitLab labels by Jira issue types, e.g. "type::bug" = "Bug"
+		Types map[string]string `toml:"types"`
 	} `toml:"jira"`
 	Storage struct {
 		Path         string `toml:"path"`
 		DisableCache bool   `toml:"disable_cache"`
 		Encrypt      bool   `toml:"encrypt"`
 	} `toml:"storage"`
+	Projects map[string]*ProjectDefaults `toml:"projects"`
+	// JQL queries by names, see 'jigit search'
+	Queries map[string]string `toml:"queries"`
+}
+
+const defaultTimeout = 30 * time.Second
+
+// GitLabTimeout returns time limit of a single GitLab request.
+func (c *Config) GitLabTimeout() time.Duration {
+	return timeout(c.GitLab.Timeout)
+}
+
+// JiraTimeout returns time limit of a single Jira request.
+func (c *Config) JiraTimeout() time.Duration {
+	return timeout(c.Jira.Timeout)
+}
+
+// timeout converts seconds from config, zero means default and negative
+// value disables the limit.
+func timeout(seconds int) time.Duration {
+	switch {
+	case seconds == 0:
+		return defaultTimeout
+	case seconds < 0:
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// LabelType returns Jira issue type mapped to the first of GitLab labels
+// which has a mapping. Empty string is returned if none has.
+func (c *Config) LabelType(labels []string) string {
+	for _, l := range labels {
+		for label, issueType := range c.Jira.Types {
+			if strings.EqualFold(label, l) {
+				return issueType
+			}
+		}
+	}
+	return ""
+}
+
+// TypeLabel returns GitLab label mapped to Jira issue type, if any.
+func (c *Config) TypeLabel(issueType string) string {
+	for label, t := range c.Jira.Types {
+		if strings.EqualFold(t, issueType) {
+			return label
+		}
+	}
+	return ""
+}
+
+// ProjectDefaults overrides global Jira settings for tickets
+// of a single GitLab project.
+type ProjectDefaults struct {
+	JiraProject string `toml:"jira_project"`
+	IssueType   string `toml:"issue_type"`
+}
+
+// JiraTarget returns Jira project key and issue type for tickets of GitLab project.
+// Per-project defaults take precedence over global ones.
+func (c *Config) JiraTarget(gitProject string) (projectKey, issueType string) {
+	projectKey, issueType = c.Jira.Project, c.Jira.IssueType
+	if p, ok := c.Projects[gitProject]; ok {
+		if p.JiraProject != "" {
+			projectKey = p.JiraProject
+		}
+		if p.IssueType != "" {
+			issueType = p.IssueType
+		}
+	}
+	return projectKey, issueType
 }
 
 func Process(fl Cmd) error {
@@ -46,12 +132,43 @@ func Process(fl Cmd) error {
 	case fl.Get:
 		fmt.Println("Current config values are:")
 		fmt.Printf("\teditor: %s\n", cfg.Editor)
+		fmt.Printf("\tpager: %s\n", cfg.Pager)
+		fmt.Printf("\tpicker: %s\n", cfg.Picker)
 		fmt.Printf("\tgitlab.address: %s\n", cfg.GitLab.Address)
+		fmt.Printf("\tgitlab.timeout: %s\n", cfg.GitLabTimeout())
 		fmt.Printf("\tjira.address: %s\n", cfg.Jira.Address)
+		fmt.Printf("\tjira.timeout: %s\n", cfg.JiraTimeout())
+		fmt.Printf("\tjira.api_version: %d\n", cfg.Jira.APIVersion)
+		fmt.Printf("\tjira.project: %s\n", cfg.Jira.Project)
+		fmt.Printf("\tjira.issue_type: %s\n", cfg.Jira.IssueType)
+		for label, issueType := range cfg.Jira.Types {
+			fmt.Printf("\tjira.types.%s: %s\n", label, issueType)
+		}
+		for name, jql := range cfg.Queries {
+			fmt.Printf("\tqueries.%s: %s\n", name, jql)
+		}
+		for name, p := range cfg.Projects {
+			fmt.Printf("\tprojects.%s.jira_project: %s\n", name, p.JiraProject)
+			fmt.Printf("\tprojects.%s.issue_type: %s\n", name, p.IssueType)
+		}
 		fmt.Println()
 		fmt.Printf("\tstorage.path: %s\n", cfg.Storage.Path)
 		fmt.Printf("\tstorage.disable_cache: %t\n", cfg.Storage.DisableCache)
 		fmt.Printf("\tstorage.encrypt: %t\n", cfg.Storage.Encrypt)
+	case fl.Logout:
+		disk, err := storage.NewStorage(cfg.Storage.Path)
+		if err != nil {
+			return err
+		}
+		defer disk.Close()
+		keys := [][]byte{storage.KeyGitlabUser, storage.KeyGitlabPass, storage.KeyJiraUser, storage.KeyJiraPass}
+		for _, key := range keys {
+			if err := disk.Delete(storage.BucketAuth, key); err != nil {
+				return err
+			}
+		}
+		fmt.Println("Stored credentials have been removed, you will be asked for them on the next run.")
+		return nil
 	case fl.Set:
 		if len(fl.Argv) < 2 {
 			fmt.Printf("You should provide configuration key and value pair:\n\n" +
@@ -92,14 +209,27 @@ var (
 
 	usages = []string{
 		"URLs configuration\n",
-		"  gitlab.address - <string> address to your GitLab installation",
-		"  jira.address   - <string> address to your JIRA installation",
+		"  gitlab.address   - <string> address to your GitLab installation",
+		"  jira.address     - <string> address to your JIRA installation",
+		"  jira.api_version - <int>    REST API version: 2 for Jira Server, 3 for Jira Cloud",
+		"  gitlab.timeout   - <int>    seconds to wait for GitLab response, 30 by default, -1 waits forever",
+		"  jira.timeout     - <int>    seconds to wait for Jira response, 30 by default, -1 waits forever",
+		"\n Ticket defaults\n",
+		"  jira.project                  - <string> key of Jira project to create tickets in",
+		"  jira.issue_type               - <string> name of Jira issue type, Task by default",
+		"  jira.types.<label>            - <string> Jira issue type of GitLab issues with <label>, e.g. jira.types.type::bug Bug",
+		"  projects.<name>.jira_project  - <string> Jira project for tickets of GitLab project <name>",
+		"  projects.<name>.issue_type    - <string> Jira issue type for tickets of GitLab project <name>",
+		"\n Saved queries\n",
+		"  queries.<name> - <string> JQL query run by 'jigit search @<name>', empty value removes it",
 		"\n Cache and storage configuration\n",
 		"  storage.path      - <string> path to storage storage file",
 		"  storage.encrypt   - <bool>   defines if sensitive data (your tokens at least) should be encrypted",
 		"  storage.off_cache - <bool>   disables projects and issue caches if true",
 		"\n Misc\n",
 		"  editor - <string> same as $EDITOR environment variable",
+		"  pager  - <string> same as $PAGER, overridden by $JIGIT_PAGER, \"cat\" disables paging",
+		"  picker - <string> external finder, e.g. fzf, used when project or issue is omitted",
 	}
 )
 
@@ -107,6 +237,8 @@ func initDefaultConfig() *Config {
 	c := new(Config)
 	c.Storage.Encrypt = true
 	c.Storage.Path = defaultStoragePath
+	c.Jira.APIVersion = 2
+	c.Jira.IssueType = "Task"
 	return c
 }
 
@@ -159,10 +291,37 @@ func (c *Config) setValue(key, value string) error {
 	switch key {
 	case "editor":
 		c.Editor = value
+	case "pager":
+		c.Pager = value
+	case "picker":
+		c.Picker = value
 	case "gitlab.address":
 		c.GitLab.Address = value
 	case "jira.address":
 		c.Jira.Address = value
+	case "gitlab.timeout", "jira.timeout":
+		v, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		if key == "gitlab.timeout" {
+			c.GitLab.Timeout = v
+		} else {
+			c.Jira.Timeout = v
+		}
+	case "jira.api_version":
+		v, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		if v != 2 && v != 3 {
+			return errors.New("jira.api_version should be 2 or 3")
+		}
+		c.Jira.APIVersion = v
+	case "jira.project":
+		c.Jira.Project = strings.ToUpper(value)
+	case "jira.issue_type":
+		c.Jira.IssueType = value
 	case "storage.path":
 		c.Storage.Path = value
 	case "storage.use_cache":
@@ -177,9 +336,88 @@ func (c *Config) setValue(key, value string) error {
 			return err
 		}
 		c.Storage.Encrypt = b
+	default:
+		if strings.HasPrefix(key, "jira.types.") {
+			return c.setTypeLabel(strings.TrimPrefix(key, "jira.types."), value)
+		}
+		if strings.HasPrefix(key, "queries.") {
+			return c.setQuery(strings.TrimPrefix(key, "queries."), value)
+		}
+		return c.setProjectValue(key, value)
+	}
+	return nil
+}
+
+// SaveQuery saves JQL query under the name, empty query removes it.
+func (c *Config) SaveQuery(name, jql string) error {
+	if err := c.setQuery(name, jql); err != nil {
+		return err
+	}
+	return c.save()
+}
+
+func (c *Config) setQuery(name, jql string) error {
+	if name == "" || strings.ContainsAny(name, " @") {
+		return errors.New("query name should be a single word without @")
+	}
+	if c.Queries == nil {
+		c.Queries = make(map[string]string)
+	}
+	if jql == "" {
+		delete(c.Queries, name)
+		return nil
+	}
+	c.Queries[name] = jql
+	return nil
+}
+
+// setTypeLabel maps GitLab label to Jira issue type, empty type removes mapping.
+// Type is mapped to the single label, so sync is symmetric.
+func (c *Config) setTypeLabel(label, issueType string) error {
+	if label == "" {
+		return ErrUnknownKey
+	}
+	if c.Jira.Types == nil {
+		c.Jira.Types = make(map[string]string)
+	}
+	for l, t := range c.Jira.Types {
+		if strings.EqualFold(l, label) || issueType != "" && strings.EqualFold(t, issueType) {
+			delete(c.Jira.Types, l)
+		}
+	}
+	if issueType != "" {
+		c.Jira.Types[label] = issueType
+	}
+	return nil
+}
+
+// setProjectValue sets projects.<name>.<field> keys. Project name may contain dots.
+func (c *Config) setProjectValue(key, value string) error {
+	if !strings.HasPrefix(key, "projects.") {
+		return ErrUnknownKey
+	}
+	rest := strings.TrimPrefix(key, "projects.")
+	i := strings.LastIndex(rest, ".")
+	if i <= 0 {
+		return ErrUnknownKey
+	}
+	name, field := rest[:i], rest[i+1:]
+	if c.Projects == nil {
+		c.Projects = make(map[string]*ProjectDefaults)
+	}
+	p, ok := c.Projects[name]
+	if !ok {
+		p = new(ProjectDefaults)
+	}
+	switch field {
+	case "jira_project":
+		p.JiraProject = strings.ToUpper(value)
+	case "issue_type":
+		p.IssueType = value
 	default:
 		return ErrUnknownKey
 	}
+	c.Projects[name] = p
 	return nil
 }
 
@@ -0,0 +1,112 @@
+package field
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"lib/interrupt"
+	"lib/jira"
+	"lib/picker"
+	"lib/ref"
+	"lib/storage"
+	"lib/util"
+	"subcmd/config"
+	"subcmd/pick"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/pkg/errors"
+)
+
+type Cmd struct {
+	Active bool
+	Argv   []string
+}
+
+func (c *Cmd) Execute(argv []string) error {
+	c.Active, c.Argv = true, argv
+	return process(interrupt.Context(), c)
+}
+
+func process(ctx context.Context, c *Cmd) error {
+	if len(c.Argv) == 0 && !picker.Available() {
+		return util.Usage("Provide Jira ticket and fields to set: jigit field KEY \"Story Points=3\" [...]")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	picker.Configure(cfg.Picker)
+	disk, err := storage.NewStorage(cfg.Storage.Path)
+	if err != nil {
+		return err
+	}
+	defer disk.Close()
+
+	if len(c.Argv) == 0 {
+		picked, err := pick.Issue(ctx, cfg, disk)
+		if err != nil {
+			return err
+		}
+		c.Argv = []string{picked}
+	}
+	values, err := jira.ParseFields(c.Argv[1:])
+	if err != nil {
+		return err
+	}
+
+	// GitLab issue could be used as well, links are symmetric
+	r, err := ref.Parse(c.Argv[0])
+	if err != nil {
+		return err
+	}
+	key, err := ref.NewResolver(disk, nil).Jira(ctx, r)
+	if err != nil {
+		return err
+	}
+
+	jirac, err := jira.NewWithStorage(disk)
+	if err != nil {
+		return err
+	}
+	if len(values) == 0 {
+		return listFields(ctx, jirac, key)
+	}
+	if err := jirac.SetFields(ctx, key, values); err != nil {
+		return err
+	}
+	fmt.Printf("%s has been updated.\n", key)
+	return nil
+}
+
+// listFields prints editable fields of the ticket with their current values.
+func listFields(ctx context.Context, jirac *jira.Jira, key string) error {
+	fields, err := jirac.EditableFields(ctx, key)
+	if err != nil {
+		return err
+	}
+	issue, err := jirac.Issue(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	t := tablewriter.NewWriter(os.Stdout)
+	t.SetAutoWrapText(false)
+	t.SetColumnSeparator("")
+	t.SetBorder(false)
+	for _, f := range fields {
+		kind := f.Type
+		if f.Type == "array" {
+			kind = f.Items + "[]"
+		}
+		required := ""
+		if f.Required {
+			required = "*"
+		}
+		t.Append([]string{f.Name + required, kind, issue.Fields[f.ID], strings.Join(f.Allowed, ", ")})
+	}
+	t.Render()
+	return nil
+}
@@ -0,0 +1,297 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"lib/attach"
+	"lib/git"
+	"lib/jira"
+	"lib/journal"
+	"lib/logging"
+	"lib/storage"
+	"lib/users"
+	"lib/util"
+	"subcmd/config"
+	"subcmd/recovery"
+
+	"github.com/pkg/errors"
+)
+
+const attributionTime = "2006-01-02 15:04"
+
+// fromGitLab creates Jira ticket for every GitLab issue in project which is not linked yet.
+// Every issue is imported as journal operation, so interrupted import is resumed
+// from the last finished step on the next run instead of creating duplicates.
+func fromGitLab(ctx context.Context, c *Cmd) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	disk, err := storage.NewStorage(cfg.Storage.Path)
+	if err != nil {
+		return err
+	}
+	defer disk.Close()
+
+	gitc, err := git.NewWithStorage(disk)
+	if err != nil {
+		return err
+	}
+	jirac, err := jira.NewWithStorage(disk)
+	if err != nil {
+		return err
+	}
+
+	p, err := gitc.ProjectByName(ctx, c.Project, false, false)
+	if err != nil {
+		return err
+	}
+	if err := jiraTarget(ctx, c, cfg, jirac, p); err != nil {
+		return err
+	}
+	catalog, err := jirac.Catalog(ctx, c.ToJira)
+	if err != nil {
+		return err
+	}
+
+	mapping := users.New(disk)
+	jr := journal.New(disk)
+	pending, err := pendingImports(jr, p.Name)
+	if err != nil {
+		return err
+	}
+
+	// oldest issues go first and are imported page by page as they are fetched
+	f := &git.IssueFilter{ProjectID: p.ID, OrderBy: "created_at", Sort: "asc"}
+	if c.Opened {
+		f.State = "opened"
+	}
+	var imported, skipped int
+	err = gitc.EachIssue(ctx, f, func(issues []*git.Issue) error {
+		for _, issue := range issues {
+			ref := fmt.Sprintf("%s#%d", p.Name, issue.IID)
+
+			op, resumed := pending[issue.IID]
+			if !resumed {
+				if _, err := disk.Get(storage.BucketIssueLinks, []byte(ref)); err == nil {
+					skipped++
+					continue
+				}
+			}
+
+			// issue type mapped to labels takes precedence over the default one
+			issueType := c.Type
+			if t := catalog.TypeOf(issue.Labels); t != nil {
+				issueType = t.Name
+			}
+
+			if c.DryRun {
+				fmt.Printf("%s\t%s\t%s\t%s\n", ref, issue.State, issueType, util.TruncateString(issue.Title, 80))
+				imported++
+				continue
+			}
+
+			if !resumed {
+				args := map[string]string{
+					"project": p.Name,
+					"pid":     strconv.Itoa(p.ID),
+					"issue":   strconv.Itoa(issue.IID),
+					"to":      c.ToJira,
+					"type":    issueType,
+				}
+				for id, value := range c.fields {
+					args[recovery.ArgField+id] = value
+				}
+				op, err = jr.Begin(journal.KindImport, args)
+				if err != nil {
+					return err
+				}
+			}
+
+			if err := importGitIssue(ctx, c, cfg, op, p, issue, gitc, jirac, disk, mapping); err != nil {
+				fmt.Fprintf(os.Stderr, "Import has been interrupted on %s, run the same command again to resume.\n", ref)
+				return errors.Wrapf(err, "can't import %s", ref)
+			}
+			fmt.Printf("%s -> %s\n", ref, op.Value(journal.StepJiraIssue))
+			imported++
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if c.DryRun {
+		fmt.Printf("\n%s would be imported, %s already linked.\n",
+			util.Plural(imported, "issue", ""), util.Plural(skipped, "issue", ""))
+		return nil
+	}
+	fmt.Printf("\n%s imported, %s already linked.\n",
+		util.Plural(imported, "issue", ""), util.Plural(skipped, "issue", ""))
+	return nil
+}
+
+// jiraTarget fills Jira project and issue type from config unless they
+// are passed explicitly. Tickets are created unattended, so fields required
+// by issue type are checked up front and keyed by their IDs.
+func jiraTarget(ctx context.Context, c *Cmd, cfg *config.Config, jirac *jira.Jira, p *git.Project) error {
+	key, issueType := cfg.JiraTarget(p.Name)
+	if c.ToJira != "" {
+		key = strings.ToUpper(c.ToJira)
+	}
+	if c.Type != "" {
+		issueType = c.Type
+	}
+	if key == "" {
+		return errors.New("provide Jira project key with --to-jira flag or set the default one " +
+			"with 'jigit config --set jira.project KEY'")
+	}
+	t, err := jirac.FindIssueType(ctx, key, issueType)
+	if err != nil {
+		return err
+	}
+	fields, err := jira.ParseFields(c.Fields)
+	if err != nil {
+		return err
+	}
+	c.fields = make(map[string]string, len(fields))
+	for name, value := range fields {
+		f := t.Field(name)
+		if f == nil {
+			return errors.Errorf("field %s is not available for %s in %s", name, t.Name, key)
+		}
+		if err := f.Validate(value); err != nil {
+			return err
+		}
+		c.fields[f.ID] = value
+	}
+	if missing := t.Missing(&jira.Issue{Fields: fields}); len(missing) > 0 {
+		return errors.Errorf("%s in %s requires field %s, set it with --field \"%s=value\"",
+			t.Name, key, missing[0].Name, missing[0].Name)
+	}
+	c.ToJira, c.Type = key, t.Name
+	return nil
+}
+
+// pendingImports returns interrupted imports of provided project by GitLab issue ID.
+func pendingImports(jr *journal.Journal, project string) (map[int]*journal.Operation, error) {
+	ops, err := jr.Pending()
+	if err != nil {
+		return nil, err
+	}
+	pending := make(map[int]*journal.Operation)
+	for _, op := range ops {
+		if op.Kind != journal.KindImport || op.Args["project"] != project {
+			continue
+		}
+		iid, err := strconv.Atoi(op.Args["issue"])
+		if err != nil {
+			continue
+		}
+		pending[iid] = op
+	}
+	return pending, nil
+}
+
+func importGitIssue(ctx context.Context, c *Cmd, cfg *config.Config, op *journal.Operation, p *git.Project, issue *git.Issue,
+	gitc *git.Git, jirac *jira.Jira, disk *storage.Storage, mapping *users.Map) error {
+
+	if !op.IsDone(journal.StepJiraIssue) {
+		var assignee jira.User
+		if key, ok := mapping.JiraName(issue.AssigneeUsername); ok {
+			assignee = jirac.UserByKey(key)
+		}
+		issueType := op.Args["type"]
+		if issueType == "" {
+			issueType = c.Type
+		}
+		ticket, err := jirac.CreateIssue(ctx, &jira.Issue{
+			ProjectKey:  op.Args["to"],
+			Type:        issueType,
+			Fields:      recovery.JiraFields(op.Args),
+			Summary:     issue.Title,
+			Description: git.AbsoluteUploads(p, importedDescription(p, issue)),
+			Labels:      jiraLabels(cfg, issue.Labels),
+			Assignee:    assignee,
+		})
+		if err != nil {
+			return err
+		}
+		if err := op.Done(journal.StepJiraIssue, ticket.Key); err != nil {
+			return err
+		}
+		if err := attach.Description(ctx, gitc, jirac, p, ticket.Key, importedDescription(p, issue)); err != nil {
+			logging.Warnf("Can't copy attachments to %s: %s", ticket.Key, err)
+		}
+	}
+	key := op.Value(journal.StepJiraIssue)
+
+	notes, err := gitc.ListIssueNotes(ctx, p.ID, issue.IID)
+	if err != nil {
+		return err
+	}
+	sort.Slice(notes, func(i, j int) bool { return notes[i].ID < notes[j].ID })
+
+	last, _ := strconv.Atoi(op.Value(journal.StepComments))
+	for _, note := range notes {
+		if note.System || note.ID <= last {
+			continue
+		}
+		body := fmt.Sprintf("%s _wrote on %s in GitLab:_\n\n%s",
+			mapping.JiraAuthor(note.AuthorUsername, note.AuthorName),
+			note.CreatedAt.Format(attributionTime), note.Body)
+		body, err := attach.ToJira(ctx, gitc, jirac, p, key, body)
+		if err != nil {
+			logging.Warnf("Can't copy attachments to %s: %s", key, err)
+		}
+		if _, err := jirac.Comment(ctx, key, body); err != nil {
+			return err
+		}
+		if err := op.Done(journal.StepComments, strconv.Itoa(note.ID)); err != nil {
+			return err
+		}
+	}
+
+	if issue.State == git.IssueStateClose && !op.IsDone(journal.StepState) {
+		if err := jirac.Transition(ctx, key, c.ClosedStatus); err != nil {
+			// workflow may not allow it, ticket is still worth importing
+			logging.Warnf("Can't move %s to '%s': %s", key, c.ClosedStatus, err)
+		}
+		if err := op.Done(journal.StepState, c.ClosedStatus); err != nil {
+			return err
+		}
+	}
+
+	if !op.IsDone(journal.StepLink) {
+		if err := disk.CreateSymlink(key, p.Name, issue.IID); err != nil {
+			return err
+		}
+		if err := op.Done(journal.StepLink, ""); err != nil {
+			return err
+		}
+	}
+	return op.Finish()
+}
+
+func importedDescription(p *git.Project, issue *git.Issue) string {
+	return fmt.Sprintf("%s\n\n---\n_Imported from GitLab: [%s#%d](%s)_",
+		issue.Description, p.Name, issue.IID, issue.WebURL)
+}
+
+// Jira labels can't contain whitespaces. Labels mapped to issue type
+// are dropped, type itself carries them.
+func jiraLabels(cfg *config.Config, labels []string) []string {
+	res := make([]string, 0, len(labels))
+	for _, l := range labels {
+		if cfg.LabelType([]string{l}) != "" {
+			continue
+		}
+		res = append(res, strings.Join(strings.Fields(l), "_"))
+	}
+	return res
+}
@@ -0,0 +1,53 @@
+package importer
+
+import (
+	"context"
+
+	"lib/interrupt"
+	"lib/util"
+)
+
+const (
+	sourceGitLab = "gitlab"
+	sourceJira   = "jira"
+)
+
+type Cmd struct {
+	From         string   `long:"from" choice:"gitlab" choice:"jira" description:"tracker to import issues from"`
+	Project      string   `short:"p" long:"project" description:"GitLab project name"`
+	ToJira       string   `long:"to-jira" description:"Jira project key to create tickets in, taken from config by default"`
+	Type         string   `long:"type" description:"Jira issue type of created tickets, taken from config by default"`
+	Fields       []string `long:"field" description:"Jira field as \"Name=value\" set to every created ticket. Can be repeated"`
+	JQL          string   `long:"jql" description:"JQL query selecting Jira tickets to import"`
+	Opened       bool     `long:"opened" description:"import only opened issues"`
+	ClosedStatus string   `long:"closed-status" default:"Done" description:"Jira status for issues closed in GitLab"`
+	DryRun       bool     `long:"dry-run" description:"print what would be imported without creating anything"`
+
+	Active bool
+	Argv   []string
+
+	fields map[string]string // values of --field by field ID
+}
+
+func (c *Cmd) Execute(v []string) error {
+	c.Active, c.Argv = true, v
+	return process(interrupt.Context(), c)
+}
+
+func process(ctx context.Context, c *Cmd) error {
+	switch c.From {
+	case sourceGitLab:
+		if c.Project == "" {
+			return util.Usage("You should provide GitLab project with -p flag, Jira project key is taken from config unless --to-jira is set:\n\n" +
+				"\tjigit import --from gitlab -p project --to-jira KEY")
+		}
+		return fromGitLab(ctx, c)
+	case sourceJira:
+		if c.Project == "" || c.JQL == "" {
+			return util.Usage("You should provide JQL query with --jql flag and GitLab project with -p flag:\n\n" +
+				"\tjigit import --from jira --jql \"project = ABC\" -p project")
+		}
+		return fromJira(ctx, c)
+	}
+	return util.Usage("You should specify tracker to import issues from with --from flag. See --help for details.")
+}
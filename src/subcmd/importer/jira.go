@@ -0,0 +1,160 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"lib/attach"
+	"lib/git"
+	"lib/jira"
+	"lib/journal"
+	"lib/logging"
+	"lib/storage"
+	"lib/users"
+	"lib/util"
+	"subcmd/config"
+
+	"github.com/pkg/errors"
+)
+
+const priorityLabelPrefix = "priority::"
+
+// fromJira creates GitLab issue for every Jira ticket matching JQL query which is not linked yet.
+func fromJira(ctx context.Context, c *Cmd) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	disk, err := storage.NewStorage(cfg.Storage.Path)
+	if err != nil {
+		return err
+	}
+	defer disk.Close()
+
+	gitc, err := git.NewWithStorage(disk)
+	if err != nil {
+		return err
+	}
+	jirac, err := jira.NewWithStorage(disk)
+	if err != nil {
+		return err
+	}
+
+	p, err := gitc.ProjectByName(ctx, c.Project, false, false)
+	if err != nil {
+		return err
+	}
+
+	mapping := users.New(disk)
+	jr := journal.New(disk)
+	ops, err := jr.Pending()
+	if err != nil {
+		return err
+	}
+	pending := make(map[string]*journal.Operation)
+	for _, op := range ops {
+		if op.Kind == journal.KindImportJira && op.Args["project"] == p.Name {
+			pending[op.Args["ticket"]] = op
+		}
+	}
+
+	// tickets are imported page by page as they are fetched
+	var imported, skipped int
+	err = jirac.EachIssue(ctx, c.JQL, 0, func(tickets []*jira.Issue, _ int) error {
+		for _, ticket := range tickets {
+			op, resumed := pending[ticket.Key]
+			if !resumed {
+				if _, err := disk.Get(storage.BucketIssueLinks, []byte(ticket.Key)); err == nil {
+					skipped++
+					continue
+				}
+			}
+
+			if c.DryRun {
+				fmt.Printf("%s\t%s\t%s\n", ticket.Key, ticket.StatusName, util.TruncateString(ticket.Summary, 80))
+				imported++
+				continue
+			}
+
+			if !resumed {
+				op, err = jr.Begin(journal.KindImportJira, map[string]string{
+					"project": p.Name,
+					"pid":     strconv.Itoa(p.ID),
+					"ticket":  ticket.Key,
+				})
+				if err != nil {
+					return err
+				}
+			}
+
+			if err := importJiraTicket(ctx, cfg, op, p, ticket, gitc, jirac, disk, mapping); err != nil {
+				fmt.Fprintf(os.Stderr, "Import has been interrupted on %s, run the same command again to resume.\n", ticket.Key)
+				return errors.Wrapf(err, "can't import %s", ticket.Key)
+			}
+			fmt.Printf("%s -> %s#%s\n", ticket.Key, p.Name, op.Value(journal.StepGitIssue))
+			imported++
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if c.DryRun {
+		fmt.Printf("\n%s would be imported, %s already linked.\n",
+			util.Plural(imported, "ticket", ""), util.Plural(skipped, "ticket", ""))
+		return nil
+	}
+	fmt.Printf("\n%s imported, %s already linked.\n",
+		util.Plural(imported, "ticket", ""), util.Plural(skipped, "ticket", ""))
+	return nil
+}
+
+func importJiraTicket(ctx context.Context, cfg *config.Config, op *journal.Operation, p *git.Project, ticket *jira.Issue,
+	gitc *git.Git, jirac *jira.Jira, disk *storage.Storage, mapping *users.Map) error {
+
+	if !op.IsDone(journal.StepGitIssue) {
+		labels := append([]string{}, ticket.Labels...)
+		if ticket.PriorityName != "" {
+			labels = append(labels, priorityLabelPrefix+ticket.PriorityName)
+		}
+		if label := cfg.TypeLabel(ticket.Type); label != "" {
+			labels = append(labels, label)
+		}
+		assignee, _ := mapping.GitLogin(jirac.UserKey(&ticket.Assignee))
+		description, err := attach.ToGit(ctx, gitc, jirac, p.ID, ticket, ticket.Description)
+		if err != nil {
+			logging.Warnf("Can't copy attachments of %s: %s", ticket.Key, err)
+		}
+		issue, err := gitc.CreateIssue(ctx, &git.Issue{
+			ProjectID: p.ID,
+			Title:     ticket.Summary,
+			Description: fmt.Sprintf("%s\n\n---\n_Imported from Jira: [%s](%s)_",
+				description, ticket.Key, jirac.BrowseURL(ticket.Key)),
+			Labels:           labels,
+			AssigneeUsername: assignee,
+		})
+		if err != nil {
+			return err
+		}
+		if err := op.Done(journal.StepGitIssue, strconv.Itoa(issue.IID)); err != nil {
+			return err
+		}
+	}
+
+	if !op.IsDone(journal.StepLink) {
+		iid, err := strconv.Atoi(op.Value(journal.StepGitIssue))
+		if err != nil {
+			return err
+		}
+		if err := disk.CreateSymlink(ticket.Key, p.Name, iid); err != nil {
+			return err
+		}
+		if err := op.Done(journal.StepLink, ""); err != nil {
+			return err
+		}
+	}
+	return op.Finish()
+}
@@ -1,14 +1,18 @@
 package link
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"strconv"
 	"strings"
-	"sync"
 
 	"lib/git"
+	"lib/interrupt"
 	"lib/jira"
+	"lib/output"
+	"lib/parallel"
+	"lib/ref"
 	"lib/storage"
 	"lib/util"
 	"subcmd/config"
@@ -18,24 +22,25 @@ type Cmd struct {
 	Drop bool `short:"d" long:"drop" description:"delete provided link"`
 	List bool `short:"l" long:"list" description:"print all existing links"`
 
+	output.Flags
+
 	Active bool
 	Argv   []string
 }
 
-func usage() {
-	fmt.Fprintf(os.Stderr,
-		"To create link between GitLab issue and Jira ticket, use next syntax:\n"+
-			"  jigit ln JIRA-ID GITLAB_PROJECT_NAME#ISSUE_ID\n\n"+
-			"Use -h or --help flag to see detailed usage.\n")
-	os.Exit(1)
+func usage() error {
+	return util.Usage("To create link between GitLab issue and Jira ticket, use next syntax:\n" +
+		"  jigit ln JIRA-ID GITLAB_PROJECT_NAME#ISSUE_ID\n\n" +
+		"Issues can also be given as group/project#12, #12 for project of the current repository or URLs.\n" +
+		"Use -h or --help flag to see detailed usage.")
 }
 
 func (ln *Cmd) Execute(v []string) error {
 	ln.Active, ln.Argv = true, v
-	return process(ln)
+	return process(interrupt.Context(), ln)
 }
 
-func getLinkedJiraIssue(disk *storage.Storage, id int) (*jira.Issue, error) {
+func getLinkedJiraIssue(ctx context.Context, disk *storage.Storage, id int) (*jira.Issue, error) {
 	issueID, err := disk.GetString(storage.BucketIssueLinks, util.Itob(id))
 	if err != nil {
 		return nil, err
@@ -44,10 +49,10 @@ func getLinkedJiraIssue(disk *storage.Storage, id int) (*jira.Issue, error) {
 	if err != nil {
 		return nil, err
 	}
-	return j.Issue(issueID)
+	return j.Issue(ctx, issueID)
 }
 
-func getLinkedGitIssue(disk *storage.Storage, id string) (*git.Issue, error) {
+func getLinkedGitIssue(ctx context.Context, disk *storage.Storage, id string) (*git.Issue, error) {
 	issueID, err := disk.Get(storage.BucketIssueLinks, []byte(id))
 	if err != nil {
 		return nil, err
@@ -56,31 +61,51 @@ func getLinkedGitIssue(disk *storage.Storage, id string) (*git.Issue, error) {
 	if err != nil {
 		return nil, err
 	}
-	return g.Issue(util.Btoi(issueID))
+	return g.Issue(ctx, util.Btoi(issueID))
 }
 
-func extractIDs(argv []string) (gitlabProject string, issueID int, jiraTicket string) {
-	for i := 0; i < len(argv); i++ {
-		if !strings.Contains(argv[i], "#") {
-			jiraTicket = argv[i]
-			continue
-		}
-		parts := strings.Split(argv[i], "#")
-		gitlabProject = parts[0]
+// Link is a record of --list in machine-readable formats.
+type Link struct {
+	Jira          string `json:"jira"`
+	GitLabProject string `json:"gitlab_project"`
+	GitLabIID     int    `json:"gitlab_iid"`
+}
 
-		var err error
-		issueID, err = strconv.Atoi(parts[1])
+func listLinks(disk *storage.Storage, fl output.Flags) error {
+	printer, err := output.New(os.Stdout, fl)
+	if err != nil {
+		return err
+	}
+	if printer == nil {
+		return disk.ForEach(storage.BucketIssueLinks, func(k, v []byte) error {
+			fmt.Printf("%s %s\n", k, v)
+			return nil
+		})
+	}
+
+	printer.Header("jira", "gitlab_project", "gitlab_iid")
+	err = disk.ForEach(storage.BucketIssueLinks, func(k, v []byte) error {
+		// links are stored both ways, Jira keys are the ones without '#'
+		n := strings.LastIndex(string(v), "#")
+		if strings.Contains(string(k), "#") || n < 0 {
+			return nil
+		}
+		iid, err := strconv.Atoi(string(v[n+1:]))
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Bad issue id '%s': %s\n", parts[1], err)
-			os.Exit(1)
+			return nil
 		}
+		l := Link{Jira: string(k), GitLabProject: string(v[:n]), GitLabIID: iid}
+		return printer.Print(l, []string{l.Jira, l.GitLabProject, strconv.Itoa(iid)})
+	})
+	if err != nil {
+		return err
 	}
-	return
+	return printer.Close()
 }
 
-func process(fl *Cmd) error {
+func process(ctx context.Context, fl *Cmd) error {
 	if len(fl.Argv) == 0 && !fl.List {
-		usage()
+		return usage()
 	}
 
 	cfg, err := config.Load()
@@ -93,76 +118,77 @@ func process(fl *Cmd) error {
 	}
 	defer disk.Close()
 
-	gitProject, gitIID, jiraTicket := extractIDs(fl.Argv)
+	if fl.List {
+		return listLinks(disk, fl.Flags)
+	}
+
+	gitRef, ticketRef, err := ref.ParseAll(fl.Argv)
+	if err != nil {
+		return err
+	}
+	if gitRef == nil || ticketRef == nil {
+		return usage()
+	}
+	if gitRef.Kind != ref.Issue {
+		return util.Usage("%s is a %s, only issues can be linked", gitRef, gitRef.Kind)
+	}
+
+	gitc, err := git.NewWithStorage(disk)
+	if err != nil {
+		return err
+	}
+	res := ref.NewResolver(disk, gitc)
 
 	if fl.Drop {
-		err := disk.DropSymlink(jiraTicket, gitProject, gitIID)
+		r, err := res.Key(ctx, *gitRef)
 		if err != nil {
 			return err
 		}
+		if err := disk.DropSymlink(ticketRef.Key, r.Project, r.IID); err != nil {
+			return err
+		}
 		fmt.Println("Link has been deleted successfully.")
 		return nil
 	}
 
-	if fl.List {
-		return disk.ForEach(storage.BucketIssueLinks, func(k, v []byte) error {
-			fmt.Printf("%s %s\n", k, v)
-			return nil
-		})
+	jirac, err := jira.NewWithStorage(disk)
+	if err != nil {
+		return err
+	}
+	// clients ask for credentials on first use, that can't be done concurrently
+	if err := gitc.InitClient(); err != nil {
+		return err
+	}
+	if err := jirac.InitClient(); err != nil {
+		return err
 	}
 
+	// both sides must exist before they are linked
 	var (
-		wg     sync.WaitGroup
 		issue  *git.Issue
 		ticket *jira.Issue
-		errWg  error
+		named  ref.Ref
 	)
-
-	wg.Add(1)
-	go func(wg *sync.WaitGroup, disk *storage.Storage) {
-		defer wg.Done()
-
-		git, err := git.NewWithStorage(disk)
-		if err != nil {
-			errWg = err
-			return
-		}
-		p, err := git.Project(gitProject)
-		if err != nil {
-			errWg = err
-			return
-		}
-		issue, _, err = git.DetailedProjectIssue(p.ID, gitIID)
-		if err != nil {
-			errWg = err
-			return
-		}
-		// now we are sure that project and issue exists
-	}(&wg, disk)
-
-	wg.Add(1)
-	go func(wg *sync.WaitGroup, disk *storage.Storage) {
-		defer wg.Done()
-
-		jira, err := jira.NewWithStorage(disk)
+	pool := parallel.New(2)
+	pool.Go(func() error {
+		r, p, err := res.GitLab(ctx, *gitRef)
 		if err != nil {
-			errWg = err
-			return
-		}
-		ticket, err = jira.Issue(jiraTicket)
-		if err != nil {
-			errWg = err
-			return
+			return err
 		}
-		// now we are sure that jira ticket exists
-	}(&wg, disk)
-
-	wg.Wait()
-	if errWg != nil {
-		return errWg
+		named = r
+		issue, _, err = gitc.DetailedProjectIssue(ctx, p.ID, r.IID)
+		return err
+	})
+	pool.Go(func() error {
+		var err error
+		ticket, err = jirac.Issue(ctx, ticketRef.Key)
+		return err
+	})
+	if err := pool.Wait(); err != nil {
+		return err
 	}
 
-	if err = disk.CreateSymlink(ticket.Key, gitProject, issue.IID); err != nil {
+	if err = disk.CreateSymlink(ticket.Key, named.Project, issue.IID); err != nil {
 		return err
 	}
 	fmt.Println("Successfully linked.")
@@ -1,7 +1,9 @@
 package list
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"sort"
 	"strconv"
@@ -9,64 +11,196 @@ import (
 	"time"
 
 	"lib/git"
-	"lib/less"
+	"lib/logging"
+	"lib/output"
+	"lib/pager"
+	"lib/parallel"
+	"lib/picker"
+	"lib/ref"
+	"lib/theme"
 	"lib/util"
+	"subcmd/config"
+	"subcmd/pick"
 
 	"github.com/olekukonko/tablewriter"
+	"github.com/pkg/errors"
 )
 
-func proceedGit(fl Cmd) error {
-	git, err := git.New()
+func proceedGit(ctx context.Context, fl Cmd) error {
+	gitc, err := git.New()
 	if err != nil {
 		return err
 	}
-	defer git.Destruct()
+	defer gitc.Destruct()
 
 	if fl.NoCache {
-		util.Debug("[CACHE] invalidating git cache")
+		logging.Debugf("cache: invalidating git cache")
+	}
+	printer, err := output.New(os.Stdout, fl.Flags)
+	if err != nil {
+		return err
 	}
 
 	switch {
 	default:
-		pid, err := git.GetPid(fl.ProjectName, fl.ProjectID)
+		f, err := gitFilter(ctx, gitc, fl)
 		if err != nil {
 			return err
 		}
-		issues, err := git.ListProjectIssues(pid, fl.All)
+		if printer != nil {
+			printer.Header(gitIssueHeader...)
+		}
+		// pages are printed as soon as they arrive, --limit stops fetching
+		first := true
+		err = gitc.EachIssue(ctx, f, func(issues []*git.Issue) error {
+			switch {
+			case printer != nil:
+				return printGitIssues(ctx, gitc, printer, issues, nil)
+			case f.ProjectID != 0:
+				renderGitProjectIssues(issues, first)
+			default:
+				renderGitAssignedIssues(ctx, gitc, issues)
+			}
+			first = false
+			return nil
+		})
 		if err != nil {
 			return err
 		}
-		renderGitProjectIssues(issues)
+		if printer != nil {
+			return printer.Close()
+		}
+		fmt.Printf("\n")
 	case len(fl.IssueID) != 0:
-		issueID, err := parseIssueID(fl.IssueID)
+		project, issueID, err := parseIssueID(fl.IssueID)
 		if err != nil {
 			return err
 		}
-		pid, err := git.GetPid(fl.ProjectName, fl.ProjectID)
-		if err != nil {
-			return err
+		if project != "" {
+			if fl.ProjectName != "" && fl.ProjectName != project || fl.ProjectID != 0 {
+				return util.Usage("project is given both by -p or --pid and by issue %s, keep one of them", project)
+			}
+			fl.ProjectName = project
+		}
+		var pid int
+		if fl.ProjectName == "" && fl.ProjectID == 0 && picker.Available() {
+			pid, err = pick.Project(ctx, gitc)
+		} else {
+			pid, err = gitc.GetPid(ctx, fl.ProjectName, fl.ProjectID)
 		}
-		issue, comments, err := git.DetailedProjectIssue(pid, issueID[0])
 		if err != nil {
 			return err
 		}
-		renderGitDetailedIssue(issue, comments)
-	case fl.Assigned:
-		issues, err := git.ListAssignedIssues(fl.All)
+		issues, comments, err := fetchGitIssues(ctx, gitc, pid, issueID)
 		if err != nil {
 			return err
 		}
-		renderGitAssignedIssues(git, issues)
+		if printer != nil {
+			printer.Header(gitIssueHeader...)
+			if err := printGitIssues(ctx, gitc, printer, issues, comments); err != nil {
+				return err
+			}
+			return printer.Close()
+		}
+		return renderGitDetailedIssues(issues, comments)
 	case fl.Projects:
-		proj, err := git.ListProjects(fl.Limit, fl.NoCache)
+		proj, err := gitc.ListProjects(ctx, fl.Limit, fl.NoCache)
 		if err != nil {
 			return err
 		}
+		if printer != nil {
+			return printGitProjects(printer, proj)
+		}
 		renderGitProjects(proj)
 	}
 	return nil
 }
 
+const filterDate = "2006-01-02"
+
+// gitFilter builds issue filter from flags. Issues are listed in project
+// if it's set, in group if it's set, and globally otherwise.
+func gitFilter(ctx context.Context, gitc *git.Git, fl Cmd) (*git.IssueFilter, error) {
+	f := &git.IssueFilter{
+		Group:     fl.Group,
+		Labels:    fl.Labels,
+		Milestone: fl.Milestone,
+		Author:    fl.Author,
+		Assignee:  fl.Assignee,
+		Search:    fl.Search,
+		Scope:     fl.Scope,
+		Limit:     fl.Limit,
+	}
+	switch {
+	case fl.Confidential && fl.Public:
+		return nil, util.Usage("--confidential and --no-confidential can't be used together")
+	case fl.Confidential || fl.Public:
+		f.Confidential = &fl.Confidential
+	}
+	if !fl.All {
+		f.State = "opened"
+	}
+	if fl.Assigned {
+		f.Scope = git.ScopeAssignedToMe
+	}
+	if fl.ProjectName != "" || fl.ProjectID != 0 {
+		pid, err := gitc.GetPid(ctx, fl.ProjectName, fl.ProjectID)
+		if err != nil {
+			return nil, err
+		}
+		f.ProjectID = pid
+	}
+	if fl.Type != "" {
+		label, err := typeLabel(fl.Type)
+		if err != nil {
+			return nil, err
+		}
+		f.Labels = append(f.Labels, label)
+	}
+	if fl.Sort != "" {
+		f.OrderBy = fl.Sort + "_at"
+		f.Sort = "desc"
+	}
+	if fl.Asc {
+		f.Sort = "asc"
+	}
+
+	dates := []struct {
+		value string
+		into  *time.Time
+	}{
+		{fl.CreatedAfter, &f.CreatedAfter},
+		{fl.CreatedBefore, &f.CreatedBefore},
+		{fl.UpdatedAfter, &f.UpdatedAfter},
+		{fl.UpdatedBefore, &f.UpdatedBefore},
+	}
+	for _, d := range dates {
+		if d.value == "" {
+			continue
+		}
+		t, err := time.ParseInLocation(filterDate, d.value, time.Local)
+		if err != nil {
+			return nil, errors.Errorf("bad date '%s', expected YYYY-MM-DD", d.value)
+		}
+		*d.into = t
+	}
+	return f, nil
+}
+
+// typeLabel returns GitLab label mapped to Jira issue type.
+func typeLabel(issueType string) (string, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return "", err
+	}
+	label := cfg.TypeLabel(issueType)
+	if label == "" {
+		return "", errors.Errorf("issue type '%s' is not mapped to GitLab label, map it with "+
+			"'jigit config --set jira.types.<label> %s'", issueType, issueType)
+	}
+	return label, nil
+}
+
 func renderGitProjects(projects []*git.Project) {
 	out := tablewriter.NewWriter(os.Stdout)
 	out.SetHeader([]string{"ID", "Name", "Description", "Link"})
@@ -80,61 +214,158 @@ func renderGitProjects(projects []*git.Project) {
 	out.Render()
 }
 
-func renderGitProjectIssues(issues []*git.Issue) {
+func printGitProjects(p *output.Printer, projects []*git.Project) error {
+	p.Header(gitProjectHeader...)
+	for _, project := range projects {
+		r := newGitProject(project)
+		if err := p.Print(r, r.row()); err != nil {
+			return err
+		}
+	}
+	return p.Close()
+}
+
+// printGitIssues prints records of issues, detailed ones if comments of
+// every issue are provided.
+func printGitIssues(ctx context.Context, gitc *git.Git, p *output.Printer, issues []*git.Issue,
+	comments [][]*git.Comment) error {
+	pids := make([]interface{}, len(issues))
+	for i, issue := range issues {
+		pids[i] = issue.ProjectID
+	}
+	names := gitc.ProjectNames(ctx, pids)
+	for n, issue := range issues {
+		r := newGitIssue(issue, names[issue.ProjectID])
+		if comments != nil {
+			sort.Sort(GitCommentsTimeSort(comments[n]))
+			r = newGitDetailedIssue(issue, names[issue.ProjectID], comments[n])
+		}
+		if err := p.Print(r, r.row()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renderGitProjectIssues prints page of issues. Cells have fixed widths,
+// so columns of all pages are aligned.
+func renderGitProjectIssues(issues []*git.Issue, header bool) {
 	table := tablewriter.NewWriter(os.Stdout)
 	table.SetAutoWrapText(false)
 	table.SetBorder(false)
-	table.SetHeader([]string{"ID", "State", "Description", "Link"})
+	if header {
+		table.SetHeader([]string{"ID", "State", "Description", "Link"})
+	}
 	for _, issue := range issues {
-		iid := fmt.Sprintf("%d", issue.IID)
-		table.Append([]string{iid, string(issue.State), issue.Title, issue.WebURL})
+		iid := util.PadString(fmt.Sprintf("%d", issue.IID), 6)
+		table.Append([]string{iid, util.PadString(string(issue.State), 8),
+			util.PadString(issue.Title, 60), issue.WebURL})
 	}
 	table.Render()
-	fmt.Printf("\n")
 }
 
-func renderGitAssignedIssues(git *git.Git, issues []*git.Issue) {
+func renderGitAssignedIssues(ctx context.Context, gitc *git.Git, issues []*git.Issue) {
 	table := tablewriter.NewWriter(os.Stdout)
 	table.SetAutoWrapText(false)
 	table.SetBorder(false)
 	table.SetColumnSeparator(" ")
 
+	pids := make([]interface{}, len(issues))
+	for i, issue := range issues {
+		pids[i] = issue.ProjectID
+	}
+	names := gitc.ProjectNames(ctx, pids)
+	// pages are rendered one by one, cells have fixed widths to keep them aligned
 	for _, issue := range issues {
-		pname, _ := git.ProjectNameByID(issue.ProjectID)
-		iid := fmt.Sprintf("%d", issue.IID)
+		pname := util.PadString(names[issue.ProjectID], 24)
+		iid := util.PadString(fmt.Sprintf("%d", issue.IID), 6)
 
-		table.Append([]string{pname, iid, string(issue.State), issue.Title})
+		table.Append([]string{pname, iid, util.PadString(string(issue.State), 8), issue.Title})
 	}
 	table.Render()
-	fmt.Printf("\n")
-
 }
 
-func renderGitDetailedIssue(issue *git.Issue, notes []*git.Comment) {
-	sort.Sort(GitCommentsTimeSort(notes))
+// fetchGitIssues fetches issues with their comments concurrently, keeping
+// the order of IDs.
+func fetchGitIssues(ctx context.Context, gitc *git.Git, pid int, ids []int) ([]*git.Issue, [][]*git.Comment, error) {
+	issues := make([]*git.Issue, len(ids))
+	comments := make([][]*git.Comment, len(ids))
+	pool := parallel.New(0)
+	for n, id := range ids {
+		n, id := n, id
+		pool.Go(func() error {
+			issue, notes, err := gitc.DetailedProjectIssue(ctx, pid, id)
+			if err != nil {
+				return errors.Wrapf(err, "can't fetch issue #%d", id)
+			}
+			issues[n], comments[n] = issue, notes
+			return nil
+		})
+	}
+	if err := pool.Wait(); err != nil {
+		return nil, nil, err
+	}
+	return issues, comments, nil
+}
 
-	out, err := less.NewFile()
-	if err != nil {
-		// fixme shitty error handling
-		//out.File = os.Stdout
+func renderGitDetailedIssues(issues []*git.Issue, comments [][]*git.Comment) error {
+	out := pager.New()
+	for n, issue := range issues {
+		if n > 0 {
+			fmt.Fprintf(out, "\n%s\n", sepIssue())
+		}
+		renderGitDetailedIssue(out, issue, comments[n])
 	}
-	defer out.Close()
+	return out.Close()
+}
+
+func renderGitDetailedIssue(out io.Writer, issue *git.Issue, notes []*git.Comment) {
+	sort.Sort(GitCommentsTimeSort(notes))
 
 	// todo show project name
 	// todo link jira issue
-	fmt.Fprintf(out, " Issue #%d (%s): %s tags: %s\n\n Project:\t%d\n Jira task:\t%d\n"+
+	fmt.Fprintf(out, " Issue %s (%s): %s tags: %s\n\n Project:\t%d\n Jira task:\t%d\n"+
 		" Assignee:\t%s\n Created at:\t%s (%s)\n Link:\t\t%s\n\n%s%s\n",
-		issue.IID, issue.State, issue.Title, issue.Labels,
-		issue.ProjectID, 777, issue.AssigneeName, issue.CreatedAt.Format(time.RFC850),
-		util.RelativeTime(issue.CreatedAt),
-		issue.WebURL, util.StringToFixedWidth(issue.Description, textWidthSize), sepIssue)
+		theme.Key(fmt.Sprintf("#%d", issue.IID)), theme.State(string(issue.State)), issue.Title,
+		theme.Labels(issue.Labels), issue.ProjectID, 777, theme.Author(issue.AssigneeName),
+		issue.CreatedAt.Format(time.RFC850), theme.Time(util.RelativeTime(issue.CreatedAt)),
+		issue.WebURL, util.StringToFixedWidth(issue.Description, theme.Width()), sepIssue())
 	for _, note := range notes {
-		fmt.Fprintf(out, " %s @%s wrote %s [id=%d] %s\n\n%s%s",
-			note.AuthorName, note.AuthorUsername, util.RelativeTime(note.CreatedAt), note.ID,
+		fmt.Fprintf(out, " %s %s wrote %s [id=%d] %s\n\n%s%s",
+			theme.Author(note.AuthorName), theme.Author("@"+note.AuthorUsername),
+			theme.Time(util.RelativeTime(note.CreatedAt)), note.ID,
 			printIfEdited(note.UpdatedAt.Equal(note.CreatedAt)),
-			util.StringToFixedWidth(note.Body, textWidthSize), sepComment)
+			util.StringToFixedWidth(note.Body, theme.Width()), sepComment())
+	}
+	printGitUploads(out, issue, notes)
+}
+
+// printGitUploads lists files uploaded to the project and referenced
+// from the issue, which is the GitLab way of attaching files.
+func printGitUploads(w io.Writer, issue *git.Issue, notes []*git.Comment) {
+	refs := git.FindUploads(issue.Description)
+	for _, note := range notes {
+		refs = append(refs, git.FindUploads(note.Body)...)
+	}
+	if len(refs) == 0 {
+		fmt.Fprintln(w, " No attachments")
+		return
+	}
+
+	// issue link is the only hint of project URL here
+	base := issue.WebURL
+	if i := strings.LastIndex(base, "/issues/"); i >= 0 {
+		base = base[:i]
+	}
+	fmt.Fprintf(w, "\n Attachments:\n")
+	seen := make(map[string]bool)
+	for _, ref := range refs {
+		if seen[ref.URL] {
+			continue
+		}
+		seen[ref.URL] = true
+		fmt.Fprintf(w, " %s\n   %s%s\n", ref.Filename(), base, ref.URL)
 	}
-	out.Run()
 }
 
 func printIfEdited(cond bool) string {
@@ -144,30 +375,38 @@ func printIfEdited(cond bool) string {
 	return "EDITED"
 }
 
-func parseIssueID(iid []string) ([]int, error) {
-	// projectID should be string because JIRA id contains characters
-	// we need to cast ProjectID and IssueID to int's
-	issueID := make([]int, 0)
-	switch len(iid) {
-	case 0:
-		fmt.Fprintln(os.Stderr, "You should provide at least one issue ID to fetch with -i or --issue flag.")
-		os.Exit(1)
-	case 1:
-		// if it some id's but coma-separated?
-		sp := strings.Split(iid[0], ",")
-		if len(sp) != len(iid) {
-			iid = sp
+// parseIssueID parses issue IDs passed either as repeated flags or as
+// comma-separated list. Besides plain IDs, issues may be referenced as
+// project#12, #12 or by URL, then they define the project.
+func parseIssueID(iid []string) (project string, issueID []int, err error) {
+	issueID = make([]int, 0, len(iid))
+	for _, arg := range iid {
+		for _, s := range strings.Split(arg, ",") {
+			if s = strings.TrimSpace(s); s == "" {
+				continue
+			}
+			if id, err := strconv.Atoi(s); err == nil {
+				issueID = append(issueID, id)
+				continue
+			}
+			r, err := ref.Parse(s)
+			if err != nil {
+				return "", nil, err
+			}
+			if r.Kind != ref.Issue {
+				return "", nil, util.Usage("'%s' is a %s, not GitLab issue", s, r.Kind)
+			}
+			if project != "" && project != r.Project {
+				return "", nil, util.Usage("issues are of different projects %s and %s, list them one by one", project, r.Project)
+			}
+			project = r.Project
+			issueID = append(issueID, r.IID)
 		}
 	}
-	for i := 0; i < len(iid); i++ {
-		id, err := strconv.Atoi(strings.TrimSpace(iid[0]))
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Bad issue ID: %s", err)
-			os.Exit(1)
-		}
-		issueID = append(issueID, id)
+	if len(issueID) == 0 {
+		return "", nil, util.Usage("provide at least one issue ID to fetch with -i or --issue flag")
 	}
-	return issueID, nil
+	return project, issueID, nil
 }
 
 type GitCommentsTimeSort []*git.Comment
@@ -1,22 +1,30 @@
 package list
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"sort"
 	"strings"
 	"time"
 
 	"lib/jira"
-	"lib/less"
+	"lib/logging"
+	"lib/output"
+	"lib/pager"
+	"lib/parallel"
+	"lib/ref"
+	"lib/theme"
 	"lib/util"
 
 	"github.com/olekukonko/tablewriter"
+	"github.com/pkg/errors"
 	"os"
 )
 
 const jiraTime = "2006-01-02T15:04:05.999-0700"
 
-func proceedJira(fl Cmd) error {
+func proceedJira(ctx context.Context, fl Cmd) error {
 	jr, err := jira.New()
 	if err != nil {
 		return err
@@ -33,80 +41,334 @@ func proceedJira(fl Cmd) error {
 	default:
 		//listGitProjectIssues(git, fl.ProjectID, fl.IssueID, fl.All)
 		//case fl.Show:
-		if len(fl.IssueID) == 0 {
-			fmt.Fprintln(os.Stderr, "You should provide issue ID to fetch with -i or --issue flag.")
-			os.Exit(1)
+		keys, err := parseIssueKeys(fl.IssueID)
+		if err != nil {
+			return err
 		}
-		issue, err := jr.Issue(fl.IssueID[0])
+		if len(keys) == 0 {
+			return errors.New("provide at least one issue ID to fetch with -i or --issue flag")
+		}
+		issues, err := fetchJiraIssues(ctx, jr, keys)
 		if err != nil {
 			return err
 		}
-		return renderJiraDetailedIssue(issue, jr.Endpoint())
+		// field names are cosmetics, IDs will do without them
+		names, _ := jr.FieldNames(ctx)
+		if !fl.Tabular() {
+			return printJiraDetailedIssues(jr, issues, names, fl.Flags)
+		}
+		return renderJiraDetailedIssues(issues, jr.Endpoint(), names)
 	case fl.Assigned:
-		issues, err := jr.ListAssignedIssues(fl.ProjectName)
+		return renderJiraAssignedIssues(ctx, jr, fl)
+	case fl.Projects:
+		projects, err := jr.ListProjects(ctx)
 		if err != nil {
 			return err
 		}
-		renderJiraAssignedIssues(issues)
-	case fl.Projects:
-		projects, err := jr.ListProjects()
+		printer, err := output.New(os.Stdout, fl.Flags)
 		if err != nil {
 			return err
 		}
+		if printer != nil {
+			return printJiraProjects(printer, projects)
+		}
 		return renderJiraProjects(projects)
 	}
 	return nil
 }
 
-func renderJiraAssignedIssues(li []*jira.Issue) {
-	fmt.Printf("Fetched %s assigned to you.\n\n",
-		util.Plural(len(li), "issue", ""))
+// renderJiraAssignedIssues prints assigned issues page by page as they are
+// fetched, at most fl.Limit of them.
+func renderJiraAssignedIssues(ctx context.Context, jr *jira.Jira, fl Cmd) error {
+	t, err := NewJiraTable(os.Stdout, []string{"key", "status", "summary"}, nil)
+	if err != nil {
+		return err
+	}
+	if err := t.SetOutput(fl.Flags, jr.BrowseURL); err != nil {
+		return err
+	}
+	var shown, total int
+	err = jr.EachIssue(ctx, jira.AssignedJQL(fl.ProjectName, fl.Type), fl.Limit, func(issues []*jira.Issue, n int) error {
+		shown += len(issues)
+		total = n
+		return t.Render(issues)
+	})
+	if err != nil {
+		return err
+	}
+	if err := t.Close(); err != nil {
+		return err
+	}
+	if shown < total {
+		logging.Warnf("Showing %d of %s assigned to you, raise the limit with -n to see more",
+			shown, util.Plural(total, "issue", ""))
+		return nil
+	}
+	logging.Infof("Fetched %s assigned to you", util.Plural(total, "issue", ""))
+	return nil
+}
+
+// DefaultJiraColumns are columns of issue table unless others are asked for.
+var DefaultJiraColumns = []string{"key", "type", "status", "assignee", "summary"}
+
+// jiraColumn is a column of issues, cell is the value shortened for table
+// if it's set. Table cells take width terminal cells, so pages printed one
+// by one are aligned.
+type jiraColumn struct {
+	value func(i *jira.Issue) string
+	cell  func(i *jira.Issue) string
+	width int
+}
+
+var jiraColumns = map[string]jiraColumn{
+	"key":      {value: func(i *jira.Issue) string { return i.Key }, width: 16},
+	"project":  {value: func(i *jira.Issue) string { return i.ProjectKey }, width: 10},
+	"type":     {value: func(i *jira.Issue) string { return i.Type }, width: 12},
+	"status":   {value: func(i *jira.Issue) string { return i.StatusName }, width: 14},
+	"priority": {value: func(i *jira.Issue) string { return i.PriorityName }, width: 10},
+	"assignee": {value: func(i *jira.Issue) string { return i.Assignee.Name }, width: 20},
+	"reporter": {value: func(i *jira.Issue) string { return i.Creator.Name }, width: 20},
+	"parent":   {value: func(i *jira.Issue) string { return i.ParentKey }, width: 16},
+	"labels":   {value: func(i *jira.Issue) string { return strings.Join(i.Labels, ", ") }, width: 30},
+	"created": {
+		value: func(i *jira.Issue) string { return i.Created.Format(time.RFC3339) },
+		cell:  func(i *jira.Issue) string { return util.RelativeTime(i.Created) },
+		width: 16,
+	},
+	"summary": {
+		value: func(i *jira.Issue) string { return i.Summary },
+		width: 80,
+	},
+}
+
+// IsJiraColumn reports if column is known without custom field names.
+func IsJiraColumn(column string) bool {
+	_, ok := jiraColumns[strings.ToLower(column)]
+	return ok
+}
+
+// RenderJiraIssues prints table of issues with provided columns. Column which
+// is not a standard one is looked up in names of custom fields.
+func RenderJiraIssues(w io.Writer, li []*jira.Issue, columns []string, names map[string]string) error {
+	t, err := NewJiraTable(w, columns, names)
+	if err != nil {
+		return err
+	}
+	return t.Render(li)
+}
+
+// JiraTable prints issues page by page as they are fetched, header is
+// printed before the first page only. Issues are printed as records of
+// machine-readable format instead if it's set.
+type JiraTable struct {
+	w       io.Writer
+	columns []string
+	values  []jiraColumn
+	names   map[string]string
+	started bool
+
+	printer *output.Printer
+	link    func(key string) string
+	record  func(i *jira.Issue, link string, names map[string]string) JiraIssue
+}
+
+// NewJiraTable resolves columns, which are not standard ones, in names of
+// custom fields.
+func NewJiraTable(w io.Writer, columns []string, names map[string]string) (*JiraTable, error) {
+	values := make([]jiraColumn, len(columns))
+	for n, c := range columns {
+		if f, ok := jiraColumns[strings.ToLower(c)]; ok {
+			values[n] = f
+			continue
+		}
+		id := ""
+		for fid, name := range names {
+			if strings.EqualFold(name, c) || fid == c {
+				id = fid
+				break
+			}
+		}
+		if id == "" {
+			return nil, errors.Errorf("unknown column '%s'", c)
+		}
+		values[n] = jiraColumn{
+			value: func(i *jira.Issue) string { return i.Fields[id] },
+			width: 40,
+		}
+	}
+	return &JiraTable{w: w, columns: columns, values: values, names: names, record: NewJiraIssue}, nil
+}
+
+// SetOutput switches table to format of fl, link returns web link to issue.
+// CSV has the columns of table with values which aren't shortened.
+func (jt *JiraTable) SetOutput(fl output.Flags, link func(key string) string) error {
+	p, err := output.New(jt.w, fl)
+	if err != nil {
+		return err
+	}
+	if p != nil {
+		p.Header(jt.columns...)
+	}
+	jt.printer, jt.link = p, link
+	return nil
+}
+
+// Tabular reports if issues are printed as table.
+func (jt *JiraTable) Tabular() bool {
+	return jt.printer == nil
+}
+
+// Render prints next page of issues.
+func (jt *JiraTable) Render(li []*jira.Issue) error {
+	if jt.printer != nil {
+		for _, i := range li {
+			row := make([]string, len(jt.values))
+			for n, c := range jt.values {
+				row[n] = c.value(i)
+			}
+			if err := jt.printer.Print(jt.record(i, jt.link(i.Key), jt.names), row); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
 
-	t := tablewriter.NewWriter(os.Stdout)
+	t := tablewriter.NewWriter(jt.w)
 	t.SetAutoWrapText(false)
 	t.SetColumnSeparator("")
 	t.SetBorder(false)
+	if !jt.started {
+		header := make([]string, len(jt.columns))
+		for n, c := range jt.columns {
+			header[n] = strings.TrimSpace(util.PadString(c, jt.values[n].width))
+		}
+		t.SetHeader(header)
+		jt.started = true
+	}
 	for _, i := range li {
-		t.Append([]string{i.Key, i.StatusName, i.Summary})
+		row := make([]string, len(jt.values))
+		for n, c := range jt.values {
+			if c.cell != nil {
+				row[n] = util.PadString(c.cell(i), c.width)
+			} else {
+				row[n] = util.PadString(c.value(i), c.width)
+			}
+		}
+		t.Append(row)
 	}
 	t.Render()
+	return nil
+}
+
+// Close finishes JSON and YAML documents.
+func (jt *JiraTable) Close() error {
+	if jt.printer != nil {
+		return jt.printer.Close()
+	}
+	return nil
 }
-func renderJiraDetailedIssue(i *jira.Issue, endpoint string) error {
-	out, err := less.NewFile()
+
+// parseIssueKeys parses issue keys or URLs passed either as repeated flags
+// or as comma-separated list.
+func parseIssueKeys(args []string) ([]string, error) {
+	keys := make([]string, 0, len(args))
+	for _, arg := range args {
+		for _, s := range strings.Split(arg, ",") {
+			if s = strings.TrimSpace(s); s == "" {
+				continue
+			}
+			r, err := ref.Parse(s)
+			if err != nil {
+				return nil, err
+			}
+			if r.Kind != ref.Ticket {
+				return nil, util.Usage("'%s' is a %s, list it without -j flag", s, r.Kind)
+			}
+			keys = append(keys, r.Key)
+		}
+	}
+	return keys, nil
+}
+
+// fetchJiraIssues fetches issues concurrently, keeping the order of keys.
+func fetchJiraIssues(ctx context.Context, jr *jira.Jira, keys []string) ([]*jira.Issue, error) {
+	if len(keys) > 1 {
+		// client asks for credentials on first use, that can't be done concurrently
+		if err := jr.InitClient(); err != nil {
+			return nil, err
+		}
+	}
+	issues := make([]*jira.Issue, len(keys))
+	pool := parallel.New(0)
+	for n, key := range keys {
+		n, key := n, key
+		pool.Go(func() error {
+			issue, err := jr.Issue(ctx, key)
+			if err != nil {
+				return errors.Wrapf(err, "can't fetch %s", key)
+			}
+			issues[n] = issue
+			return nil
+		})
+	}
+	if err := pool.Wait(); err != nil {
+		return nil, err
+	}
+	return issues, nil
+}
+
+func printJiraDetailedIssues(jr *jira.Jira, issues []*jira.Issue, names map[string]string, fl output.Flags) error {
+	t, err := NewJiraTable(os.Stdout, DefaultJiraColumns, names)
 	if err != nil {
 		return err
 	}
-	defer out.Close()
+	t.record = newJiraDetailedIssue
+	if err := t.SetOutput(fl, jr.BrowseURL); err != nil {
+		return err
+	}
+	if err := t.Render(issues); err != nil {
+		return err
+	}
+	return t.Close()
+}
+
+func renderJiraDetailedIssues(issues []*jira.Issue, endpoint string, names map[string]string) error {
+	out := pager.New()
+	for n, i := range issues {
+		if n > 0 {
+			fmt.Fprintf(out, "\n%s", sepIssue())
+		}
+		renderJiraDetailedIssue(out, i, endpoint, names)
+	}
+	return out.Close()
+}
 
-	fmt.Fprintf(out, "\n %s [%s] %s\n\n", i.Key, i.StatusName, i.Summary)
+func renderJiraDetailedIssue(out io.Writer, i *jira.Issue, endpoint string, names map[string]string) {
+	fmt.Fprintf(out, "\n %s [%s] %s\n\n", theme.Key(i.Key), theme.State(i.StatusName), i.Summary)
 
 	fmt.Fprintf(out, " Created:\t%s (%s)\n",
-		time.Time(i.Created).Format(time.RFC850), util.RelativeTime(time.Time(i.Created)))
-	fmt.Fprintf(out, " Assignee:\t%s (@%s)\n", i.Assignee.DisplayName, i.Assignee.Name)
-	fmt.Fprintf(out, " Author:\t%s (@%s)\n", i.Creator.DisplayName, i.Creator.Name)
+		time.Time(i.Created).Format(time.RFC850), theme.Time(util.RelativeTime(time.Time(i.Created))))
+	fmt.Fprintf(out, " Assignee:\t%s (%s)\n", i.Assignee.DisplayName, theme.Author("@"+i.Assignee.Name))
+	fmt.Fprintf(out, " Author:\t%s (%s)\n", i.Creator.DisplayName, theme.Author("@"+i.Creator.Name))
 	fmt.Fprintf(out, " Parent:\t%s\n", printIfNotEmpty(i.ParentKey))
-	fmt.Fprintf(out, " Priority:\t%s\n", i.PriorityName)
+	fmt.Fprintf(out, " Priority:\t%s\n", theme.Priority(i.PriorityName))
+	if len(i.Labels) > 0 {
+		fmt.Fprintf(out, " Labels:\t%s\n", theme.Labels(i.Labels))
+	}
 	fmt.Fprintf(out, " Link:\t\t%sbrowse/%s\n\n", endpoint, i.Key)
+	printIssueFields(out, i.Fields, names)
 
-	fmt.Fprintf(out, "%s", util.StringToFixedWidth(i.Description, textWidthSize))
-	fmt.Fprintf(out, sepIssue)
+	fmt.Fprintf(out, "%s", util.StringToFixedWidth(i.Description, theme.Width()))
+	fmt.Fprint(out, sepIssue())
 
+	printIssueAttachments(out, i.Attachments)
 	printIssueLinks(out, i.IssueLinks)
 	printIssueSubtasks(out, i.Subtasks)
 	printIssueComments(out, i.Comments)
-
-	out.Run()
-	return nil
 }
 
 func renderJiraProjects(li []*jira.Project) error {
-	out, err := less.NewFile()
-	if err != nil {
-		// todo maybe just print in stdout?
-		return err
-	}
-	defer out.Close()
-
+	out := pager.New()
 	fmt.Fprintf(out, "Fetched %d Jira %s.\n\n", len(li),
 		util.PluralWord(len(li), "project", ""))
 
@@ -120,9 +382,18 @@ func renderJiraProjects(li []*jira.Project) error {
 		table.Append([]string{p.ID, p.Key, p.Name})
 	}
 	table.Render()
+	return out.Close()
+}
 
-	out.Run()
-	return nil
+func printJiraProjects(p *output.Printer, li []*jira.Project) error {
+	p.Header(jiraProjectHeader...)
+	for _, project := range li {
+		r := JiraProject{ID: project.ID, Key: project.Key, Name: project.Name}
+		if err := p.Print(r, r.row()); err != nil {
+			return err
+		}
+	}
+	return p.Close()
 }
 
 // jira helpers
@@ -134,9 +405,43 @@ func printIssueComments(w io.Writer, comments []jira.Comment) {
 	fmt.Fprintf(w, "\n Comments:\n\n")
 	for _, c := range comments {
 		created, _ := time.Parse(jiraTime, c.Created)
-		fmt.Fprintf(w, sepComment)
-		fmt.Fprintf(w, " [%s] %s (@%s) wrote %s\n\n%s", c.ID, c.Author.DisplayName,
-			c.Author.Name, util.RelativeTime(created), util.StringToFixedWidth(c.Body, textWidthSize))
+		fmt.Fprint(w, sepComment())
+		fmt.Fprintf(w, " [%s] %s (%s) wrote %s\n\n%s", c.ID, c.Author.DisplayName,
+			theme.Author("@"+c.Author.Name), theme.Time(util.RelativeTime(created)),
+			util.StringToFixedWidth(c.Body, theme.Width()))
+	}
+	fmt.Fprintf(w, "\n")
+}
+
+func printIssueFields(w io.Writer, fields map[string]string, names map[string]string) {
+	if len(fields) == 0 {
+		return
+	}
+	lines := make([]string, 0, len(fields))
+	for id, value := range fields {
+		name := names[id]
+		if name == "" {
+			name = id
+		}
+		lines = append(lines, fmt.Sprintf(" %s:\t%s\n", name, util.TruncateString(value, theme.Width()-20)))
+	}
+	sort.Strings(lines)
+	for _, l := range lines {
+		fmt.Fprint(w, l)
+	}
+	fmt.Fprintf(w, "\n")
+}
+
+func printIssueAttachments(w io.Writer, a []jira.Attachment) {
+	if len(a) == 0 {
+		fmt.Fprintln(w, " No attachments")
+		return
+	}
+	fmt.Fprintf(w, "\n Attachments:\n")
+	for i := 0; i < len(a); i++ {
+		created, _ := time.Parse(jiraTime, a[i].Created)
+		fmt.Fprintf(w, " %s (%s) by %s %s\n   %s\n", a[i].Filename, util.HumanSize(a[i].Size),
+			theme.Author("@"+a[i].Author.Name), theme.Time(util.RelativeTime(created)), a[i].Content)
 	}
 	fmt.Fprintf(w, "\n")
 }
@@ -147,13 +452,12 @@ func printIssueSubtasks(w io.Writer, s []jira.Subtask) {
 		return
 	}
 	fmt.Fprintf(w, "\n Subtasks:\n")
-	sep := " ++++++++++++++++++++++++++++++++++++++\n"
-	fmt.Fprintf(w, sep)
+	fmt.Fprint(w, sepList())
 	for i := 0; i < len(s); i++ {
-		fmt.Fprintf(w, "%s [%s] %s\n",
-			s[i].Key, s[i].StatusName, util.TruncateString(s[i].Summary, 80))
+		fmt.Fprintf(w, " %s [%s] %s\n", theme.Key(s[i].Key), theme.State(s[i].StatusName),
+			util.TruncateString(s[i].Summary, 80))
 	}
-	fmt.Fprintf(w, sep)
+	fmt.Fprint(w, sepList())
 }
 
 func printIssueLinks(w io.Writer, s []jira.IssueLink) {
@@ -162,9 +466,8 @@ func printIssueLinks(w io.Writer, s []jira.IssueLink) {
 		return
 	}
 
-	sep := " ++++++++++++++++++++++++++++++++++++++\n"
 	fmt.Fprintf(w, "\n Linked issues:\n")
-	fmt.Fprintf(w, sep)
+	fmt.Fprint(w, sepList())
 	for i := 0; i < len(s); i++ {
 		var issue jira.Issue
 		var relation string
@@ -178,10 +481,10 @@ func printIssueLinks(w io.Writer, s []jira.IssueLink) {
 			relation = s[i].Type.Outward
 		}
 
-		fmt.Fprintf(w, " + %s %s [%s] %s\n", relation, issue.Key, issue.StatusName,
+		fmt.Fprintf(w, " + %s %s [%s] %s\n", relation, theme.Key(issue.Key), theme.State(issue.StatusName),
 			util.TruncateString(issue.Summary, 80))
 	}
-	fmt.Fprintf(w, sep)
+	fmt.Fprint(w, sepList())
 	fmt.Fprintf(w, "\n")
 }
 
@@ -1,17 +1,23 @@
 package list
 
 import (
+	"context"
 	"errors"
 	"time"
+
+	"lib/output"
+	"lib/pager"
+	"lib/picker"
+	"lib/theme"
+	"subcmd/config"
 )
 
-const (
-	commentTime   = time.RFC850
-	textWidthSize = 100
+const commentTime = time.RFC850
 
-	sepIssue   = " ======================================\n"
-	sepComment = " --------------------------------------\n"
-)
+// separators of detailed views span the text width
+func sepIssue() string   { return theme.Rule('=') }
+func sepComment() string { return theme.Rule('-') }
+func sepList() string    { return theme.Rule('+') }
 
 var ErrBadAddress = errors.New("bad address provided")
 
@@ -22,12 +28,31 @@ type Cmd struct {
 	ProjectName string   `short:"p" long:"project" description:"project name to get issues on"`
 	ProjectID   int      `long:"pid" description:"project ID to get issues on"`
 	IssueID     []string `short:"i" long:"issue" description:"issue ID for detailed view"`
+	Type        string   `long:"type" description:"show only issues of Jira issue type, GitLab issues are matched by label mapped to the type"`
 
-	// Todo Limit parameter and All parameter
-	Limit   int  `short:"n" default:"20" description:"limit for entities to show"`
+	Limit   int  `short:"n" default:"20" description:"limit for entities to show, 0 shows all of them"`
 	All     bool `short:"S" long:"ignore-state" description:"ignore issue status"`
 	NoCache bool `short:"c" long:"no-cache" description:"invalidate cache and retrieve fresh data from remote"`
 
+	// GitLab issue filters
+	Group         string   `short:"g" long:"group" description:"list issues of GitLab group instead of project"`
+	Labels        []string `short:"l" long:"label" description:"show issues having all of the labels, can be repeated"`
+	Milestone     string   `short:"m" long:"milestone" description:"show issues of the milestone"`
+	Author        string   `long:"author" description:"show issues created by GitLab user"`
+	Assignee      string   `long:"assignee" description:"show issues assigned to GitLab user"`
+	Search        string   `short:"s" long:"search" description:"show issues with the text in title or description"`
+	CreatedAfter  string   `long:"created-after" description:"show issues created after the date (YYYY-MM-DD)"`
+	CreatedBefore string   `long:"created-before" description:"show issues created before the date (YYYY-MM-DD)"`
+	UpdatedAfter  string   `long:"updated-after" description:"show issues updated after the date (YYYY-MM-DD)"`
+	UpdatedBefore string   `long:"updated-before" description:"show issues updated before the date (YYYY-MM-DD)"`
+	Confidential  bool     `long:"confidential" description:"show only confidential issues"`
+	Public        bool     `long:"no-confidential" description:"show only issues which aren't confidential"`
+	Sort          string   `long:"sort" choice:"created" choice:"updated" description:"sort issues by creation or update time, newest first"`
+	Asc           bool     `long:"asc" description:"sort oldest issues first"`
+	Scope         string   `long:"scope" choice:"created_by_me" choice:"assigned_to_me" choice:"all" description:"issues to list when no project or group is set"`
+
+	output.Flags
+
 	Active bool
 	Argv   []string
 }
@@ -37,9 +62,13 @@ func (ls *Cmd) Execute(argv []string) error {
 	return nil
 }
 
-func Process(fl Cmd) error {
+func Process(ctx context.Context, fl Cmd) error {
+	if cfg, err := config.Load(); err == nil {
+		pager.Configure(cfg.Pager)
+		picker.Configure(cfg.Picker)
+	}
 	if fl.JiraMode {
-		return proceedJira(fl)
+		return proceedJira(ctx, fl)
 	}
-	return proceedGit(fl)
+	return proceedGit(ctx, fl)
 }
@@ -0,0 +1,263 @@
+package list
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"lib/git"
+	"lib/jira"
+)
+
+// Records below are printed by --output json, jsonl and yaml formats and
+// are the data of --template. Their keys are stable: scripts rely on them,
+// so new keys may be added, but existing ones are never renamed or removed.
+// Lists marked as detailed are present only in detailed view (ls -i).
+
+// GitProject is a GitLab project.
+type GitProject struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	URL         string `json:"url"`
+}
+
+var gitProjectHeader = []string{"id", "name", "description", "url"}
+
+func newGitProject(p *git.Project) GitProject {
+	return GitProject{ID: p.ID, Name: p.Name, Description: p.Description, URL: p.WebURL}
+}
+
+func (p GitProject) row() []string {
+	return []string{strconv.Itoa(p.ID), p.Name, p.Description, p.URL}
+}
+
+// GitIssue is a GitLab issue.
+type GitIssue struct {
+	ProjectID   int       `json:"project_id"`
+	Project     string    `json:"project"`
+	IID         int       `json:"iid"`
+	Title       string    `json:"title"`
+	State       string    `json:"state"`
+	Labels      []string  `json:"labels"`
+	Assignee    string    `json:"assignee"`
+	CreatedAt   time.Time `json:"created_at"`
+	URL         string    `json:"url"`
+	Description string    `json:"description"`
+	// detailed
+	Comments []GitComment `json:"comments,omitempty"`
+	Uploads  []string     `json:"uploads,omitempty"`
+}
+
+// GitComment is a note on GitLab issue, system notes are included.
+type GitComment struct {
+	ID         int       `json:"id"`
+	Author     string    `json:"author"`
+	AuthorName string    `json:"author_name"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	System     bool      `json:"system"`
+	Body       string    `json:"body"`
+}
+
+var gitIssueHeader = []string{"project", "iid", "state", "title", "assignee", "created_at", "url"}
+
+func newGitIssue(i *git.Issue, project string) GitIssue {
+	return GitIssue{
+		ProjectID:   projectID(i.ProjectID),
+		Project:     project,
+		IID:         i.IID,
+		Title:       i.Title,
+		State:       string(i.State),
+		Labels:      i.Labels,
+		Assignee:    i.AssigneeUsername,
+		CreatedAt:   i.CreatedAt,
+		URL:         i.WebURL,
+		Description: i.Description,
+	}
+}
+
+// projectID converts project ID of issue, which GitLab client keeps untyped,
+// zero is returned if it's not a number.
+func projectID(pid interface{}) int {
+	switch v := pid.(type) {
+	case int:
+		return v
+	case float64:
+		return int(v)
+	case string:
+		id, _ := strconv.Atoi(v)
+		return id
+	}
+	return 0
+}
+
+func (i GitIssue) row() []string {
+	return []string{i.Project, strconv.Itoa(i.IID), i.State, i.Title, i.Assignee,
+		i.CreatedAt.Format(time.RFC3339), i.URL}
+}
+
+func newGitDetailedIssue(i *git.Issue, project string, notes []*git.Comment) GitIssue {
+	r := newGitIssue(i, project)
+	for _, n := range notes {
+		r.Comments = append(r.Comments, GitComment{
+			ID:         n.ID,
+			Author:     n.AuthorUsername,
+			AuthorName: n.AuthorName,
+			CreatedAt:  n.CreatedAt,
+			UpdatedAt:  n.UpdatedAt,
+			System:     n.System,
+			Body:       n.Body,
+		})
+	}
+	base := i.WebURL
+	if n := strings.LastIndex(base, "/issues/"); n >= 0 {
+		base = base[:n]
+	}
+	refs := git.FindUploads(i.Description)
+	for _, n := range notes {
+		refs = append(refs, git.FindUploads(n.Body)...)
+	}
+	for _, ref := range refs {
+		r.Uploads = append(r.Uploads, base+ref.URL)
+	}
+	return r
+}
+
+// JiraProject is a Jira project.
+type JiraProject struct {
+	ID   string `json:"id"`
+	Key  string `json:"key"`
+	Name string `json:"name"`
+}
+
+var jiraProjectHeader = []string{"id", "key", "name"}
+
+func (p JiraProject) row() []string {
+	return []string{p.ID, p.Key, p.Name}
+}
+
+// JiraIssue is a Jira issue. Fields holds values of other fields by their
+// names, or by IDs if names are unknown.
+type JiraIssue struct {
+	Key         string            `json:"key"`
+	Project     string            `json:"project"`
+	Type        string            `json:"type"`
+	Status      string            `json:"status"`
+	Priority    string            `json:"priority"`
+	Summary     string            `json:"summary"`
+	Assignee    string            `json:"assignee"`
+	Reporter    string            `json:"reporter"`
+	Parent      string            `json:"parent"`
+	Labels      []string          `json:"labels"`
+	CreatedAt   time.Time         `json:"created_at"`
+	URL         string            `json:"url"`
+	Description string            `json:"description"`
+	Fields      map[string]string `json:"fields"`
+	// detailed
+	Comments    []JiraComment    `json:"comments,omitempty"`
+	Attachments []JiraAttachment `json:"attachments,omitempty"`
+	Links       []JiraLink       `json:"links,omitempty"`
+	Subtasks    []JiraSubtask    `json:"subtasks,omitempty"`
+}
+
+// JiraComment is a comment on Jira issue.
+type JiraComment struct {
+	ID         string    `json:"id"`
+	Author     string    `json:"author"`
+	AuthorName string    `json:"author_name"`
+	CreatedAt  time.Time `json:"created_at"`
+	Body       string    `json:"body"`
+}
+
+// JiraAttachment is a file attached to Jira issue.
+type JiraAttachment struct {
+	ID        string    `json:"id"`
+	Filename  string    `json:"filename"`
+	MimeType  string    `json:"mime_type"`
+	Size      int       `json:"size"`
+	Author    string    `json:"author"`
+	CreatedAt time.Time `json:"created_at"`
+	URL       string    `json:"url"`
+}
+
+// JiraLink is a relation to another issue, e.g. "blocks".
+type JiraLink struct {
+	Relation string `json:"relation"`
+	Key      string `json:"key"`
+	Status   string `json:"status"`
+	Summary  string `json:"summary"`
+}
+
+// JiraSubtask is a subtask of Jira issue.
+type JiraSubtask struct {
+	Key     string `json:"key"`
+	Status  string `json:"status"`
+	Summary string `json:"summary"`
+}
+
+// NewJiraIssue returns record of issue, link is the web link to it.
+func NewJiraIssue(i *jira.Issue, link string, names map[string]string) JiraIssue {
+	fields := make(map[string]string, len(i.Fields))
+	for id, value := range i.Fields {
+		if name := names[id]; name != "" {
+			id = name
+		}
+		fields[id] = value
+	}
+	return JiraIssue{
+		Key:         i.Key,
+		Project:     i.ProjectKey,
+		Type:        i.Type,
+		Status:      i.StatusName,
+		Priority:    i.PriorityName,
+		Summary:     i.Summary,
+		Assignee:    i.Assignee.Name,
+		Reporter:    i.Creator.Name,
+		Parent:      i.ParentKey,
+		Labels:      i.Labels,
+		CreatedAt:   i.Created,
+		URL:         link,
+		Description: i.Description,
+		Fields:      fields,
+	}
+}
+
+func newJiraDetailedIssue(i *jira.Issue, link string, names map[string]string) JiraIssue {
+	r := NewJiraIssue(i, link, names)
+	for _, c := range i.Comments {
+		created, _ := time.Parse(jiraTime, c.Created)
+		r.Comments = append(r.Comments, JiraComment{
+			ID:         c.ID,
+			Author:     c.Author.Name,
+			AuthorName: c.Author.DisplayName,
+			CreatedAt:  created,
+			Body:       c.Body,
+		})
+	}
+	for _, a := range i.Attachments {
+		created, _ := time.Parse(jiraTime, a.Created)
+		r.Attachments = append(r.Attachments, JiraAttachment{
+			ID:        a.ID,
+			Filename:  a.Filename,
+			MimeType:  a.MimeType,
+			Size:      a.Size,
+			Author:    a.Author.Name,
+			CreatedAt: created,
+			URL:       a.Content,
+		})
+	}
+	for _, l := range i.IssueLinks {
+		link := JiraLink{Relation: l.Type.Outward, Key: l.OutwardIssue.Key,
+			Status: l.OutwardIssue.StatusName, Summary: l.OutwardIssue.Summary}
+		if l.InwardIssue.Key != "" {
+			link = JiraLink{Relation: l.Type.Inward, Key: l.InwardIssue.Key,
+				Status: l.InwardIssue.StatusName, Summary: l.InwardIssue.Summary}
+		}
+		r.Links = append(r.Links, link)
+	}
+	for _, s := range i.Subtasks {
+		r.Subtasks = append(r.Subtasks, JiraSubtask{Key: s.Key, Status: s.StatusName, Summary: s.Summary})
+	}
+	return r
+}
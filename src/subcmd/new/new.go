@@ -3,33 +3,47 @@ package new
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 
 	"lib/editor"
 	libgit "lib/git"
+	"lib/interrupt"
 	libjira "lib/jira"
+	"lib/journal"
+	"lib/logging"
+	"lib/queue"
 	"lib/storage"
+	"lib/users"
+	"lib/util"
 	"subcmd/config"
+	"subcmd/recovery"
 
-	bfconf "github.com/kentaro-m/blackfriday-confluence"
-	bf "gopkg.in/russross/blackfriday.v2"
+	"github.com/pkg/errors"
 )
 
 type Cmd struct {
 	// can be passed via argv?
-	Project string   `short:"p" long:"project" description:"GitLab project name"`
-	Title   string   `short:"t" long:"title" description:"issue title (less than 160 chars)"`
-	Body    string   `short:"b" long:"body" description:"issue body"`
-	Tags    []string `long:"tags" description:"list of coma-separated tags. Will be set to gitlab issue, if exists"`
+	Project  string   `short:"p" long:"project" description:"GitLab project name"`
+	Title    string   `short:"t" long:"title" description:"issue title (less than 160 chars)"`
+	Body     string   `short:"b" long:"body" description:"issue body"`
+	Tags     []string `long:"tags" description:"list of coma-separated GitLab labels. Label mapped to Jira issue type in config sets the type"`
+	Assignee string   `short:"a" long:"assignee" description:"GitLab username of assignee, Jira assignee is taken from 'jigit users'"`
+	Offline  bool     `long:"offline" description:"queue issue and create it later with 'jigit queue push'"`
+
+	JiraProject string   `short:"k" long:"jira-project" description:"key of Jira project to create ticket in, taken from config by default"`
+	IssueType   string   `long:"type" description:"name of Jira issue type, taken from config by default"`
+	Fields      []string `long:"field" description:"Jira field as \"Name=value\", array values are comma-separated. Can be repeated"`
 }
 
 func (o *Cmd) Execute(v []string) error {
-	return exec(o, v)
+	return exec(interrupt.Context(), o, v)
 }
 
-func exec(c *Cmd, argv []string) error {
+func exec(ctx context.Context, c *Cmd, argv []string) error {
 	cfg, err := config.Load()
 	if err != nil {
 		return err
@@ -43,9 +57,7 @@ func exec(c *Cmd, argv []string) error {
 
 	projectName := c.Project
 	if projectName == "" {
-		fmt.Fprintln(os.Stderr,
-			"You should specify GitLab project name with -p or --project flag. See --help for details.")
-		os.Exit(1)
+		return util.Usage("You should specify GitLab project name with -p or --project flag. See --help for details.")
 	}
 
 	if c.Title == "" && cfg.Editor != "" {
@@ -75,8 +87,61 @@ func exec(c *Cmd, argv []string) error {
 
 	c.Body = strings.Trim(c.Body, "\n ")
 	if c.Body == "" {
-		fmt.Fprintln(os.Stderr, "Nothing to create. Provide ticket body via -b or --body flag.")
-		os.Exit(1)
+		return util.Usage("Nothing to create. Provide ticket body via -b or --body flag.")
+	}
+
+	mapping := users.New(disk)
+	args := map[string]string{
+		"project": projectName,
+		"title":   c.Title,
+		"body":    c.Body,
+	}
+	if c.Assignee != "" {
+		args["assignee"] = strings.TrimPrefix(c.Assignee, "@")
+		jiraName, ok := mapping.JiraName(args["assignee"])
+		if !ok {
+			logging.Warnf("Jira user for @%s is unknown, Jira ticket will be assigned to you. "+
+				"Link users with 'jigit users add'.", args["assignee"])
+		}
+		args["jira_assignee"] = jiraName
+	}
+	labels := make([]string, 0, len(c.Tags))
+	for _, tags := range c.Tags {
+		for _, tag := range strings.Split(tags, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				labels = append(labels, tag)
+			}
+		}
+	}
+	args["jira_project"], args["issue_type"] = cfg.JiraTarget(projectName)
+	if c.JiraProject != "" {
+		args["jira_project"] = strings.ToUpper(c.JiraProject)
+	}
+	if t := cfg.LabelType(labels); t != "" {
+		args["issue_type"] = t
+	}
+	if c.IssueType != "" {
+		args["issue_type"] = c.IssueType
+	}
+	if label := cfg.TypeLabel(args["issue_type"]); label != "" && !hasLabel(labels, label) {
+		labels = append(labels, label)
+	}
+	if len(labels) > 0 {
+		args["labels"] = strings.Join(labels, ",")
+	}
+	fields, err := libjira.ParseFields(c.Fields)
+	if err != nil {
+		return err
+	}
+	for name, value := range fields {
+		args[recovery.ArgField+name] = value
+	}
+	if args["jira_project"] == "" {
+		return util.Usage("You should specify Jira project key with -k or --jira-project flag or set the default one:\n\n" +
+			"\tjigit config --set jira.project KEY")
+	}
+	if c.Offline {
+		return enqueue(disk, args)
 	}
 
 	git, err := libgit.NewWithStorage(disk)
@@ -84,60 +149,112 @@ func exec(c *Cmd, argv []string) error {
 		return err
 	}
 
-	p, err := git.ProjectByName(projectName, false, false)
+	p, err := git.ProjectByName(ctx, projectName, false, false)
 	if err != nil {
+		if queue.Unreachable(err) {
+			return enqueue(disk, args)
+		}
 		return err
 	}
-	gitUser, err := git.User()
+	gitUser, err := git.User(ctx)
 	if err != nil {
+		if queue.Unreachable(err) {
+			return enqueue(disk, args)
+		}
 		return err
 	}
 	jira, err := libjira.NewWithStorage(disk)
 	if err != nil {
 		return err
 	}
-	jiraUser, err := jira.User()
+	jiraUser, err := jira.User(ctx)
+	if err != nil {
+		if queue.Unreachable(err) {
+			return enqueue(disk, args)
+		}
+		return err
+	}
+	issueType, err := jira.FindIssueType(ctx, args["jira_project"], args["issue_type"])
 	if err != nil {
+		if queue.Unreachable(err) {
+			return enqueue(disk, args)
+		}
+		return err
+	}
+	args["issue_type"] = issueType.Name
+	if err := resolveFields(issueType, args); err != nil {
+		return err
+	}
+	if err := askMissing(issueType, args); err != nil {
 		return err
 	}
+	args["pid"] = strconv.Itoa(p.ID)
 
-	gitIssue, err := git.CreateIssue(&libgit.Issue{
-		ProjectID:        p.ID,
-		Title:            c.Title,
-		Description:      md2jira(c.Body),
-		AssigneeName:     gitUser.Name,
-		AssigneeUsername: gitUser.Login,
-	})
+	ops := journal.New(disk)
+	op, err := ops.Begin(journal.KindAdd, args)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Can't create GitLab ticket: %s\n", err)
-		os.Exit(1)
+		return err
 	}
 
-	jiraIssue, err := jira.CreateIssue(&libjira.Issue{
-		Summary:     c.Title,
-		Description: c.Body,
-		Assignee:    *jiraUser,
-		Creator:     *jiraUser,
+	assigneeName, assigneeLogin := gitUser.Name, gitUser.Login
+	if args["assignee"] != "" {
+		assigneeName, assigneeLogin = "", args["assignee"]
+	}
+	gitIssue, err := git.CreateIssue(ctx, &libgit.Issue{
+		ProjectID:        p.ID,
+		Title:            c.Title,
+		Description:      args["body"],
+		Labels:           recovery.GitLabels(args),
+		AssigneeName:     assigneeName,
+		AssigneeUsername: assigneeLogin,
 	})
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		op.Finish()
+		if queue.Unreachable(err) {
+			return enqueue(disk, args)
+		}
+		return errors.Wrap(err, "can't create GitLab ticket")
+	}
+	if err = op.Done(journal.StepGitIssue, strconv.Itoa(gitIssue.IID)); err != nil {
+		return err
 	}
 
+	jiraAssignee := *jiraUser
+	if args["jira_assignee"] != "" {
+		jiraAssignee = jira.UserByKey(args["jira_assignee"])
+	}
+	ticket := recovery.JiraIssue(args)
+	ticket.Description = libgit.AbsoluteUploads(p, c.Body)
+	ticket.Assignee = jiraAssignee
+	ticket.Creator = *jiraUser
+	jiraIssue, err := jira.CreateIssue(ctx, ticket)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Can't create Jira ticket: %s\n", err)
-
-		gitIssue.State = libgit.IssueStateClose
-		gitIssue.Description = "Issue closed automatically due to unexpected Jira response."
-		_, err = git.UpdateIssue(gitIssue)
-		if err != nil {
+		if queue.Unreachable(err) {
+			e, qerr := recovery.Postpone(op, disk)
+			if qerr != nil {
+				return errors.Wrapf(qerr, "can't queue operation %d", op.ID)
+			}
 			fmt.Fprintf(os.Stderr,
-				"Can't remove already created git isssue #%d: %s\n", gitIssue.IID, err)
-			os.Exit(1)
+				"GitLab issue #%d has been created, Jira ticket is queued as operation %d, create it with 'jigit queue push'.\n",
+				gitIssue.IID, e.ID)
+			return errors.Wrap(err, "can't create Jira ticket")
 		}
-		fmt.Fprintln(os.Stderr,
-			"Ticket was not created, gitlab issue has been closed successfully.")
-		os.Exit(1)
+		if cerr := recovery.Compensate(op, git, jira, disk); cerr != nil {
+			fmt.Fprintf(os.Stderr,
+				"Can't close already created git isssue #%d: %s\n", gitIssue.IID, cerr)
+			fmt.Fprintf(os.Stderr,
+				"Operation %d has been saved, run 'jigit recover' to finish it.\n", op.ID)
+		} else {
+			fmt.Fprintln(os.Stderr,
+				"Ticket was not created, gitlab issue has been closed successfully.")
+		}
+		return errors.Wrap(err, "can't create Jira ticket")
+	}
+	if err = op.Done(journal.StepJiraIssue, jiraIssue.Key); err != nil {
+		return err
+	}
+	if err := recovery.JiraDescription(ctx, op, git, jira, p); err != nil {
+		logging.Warnf("Can't copy attachments to %s: %s", jiraIssue.Key, err)
 	}
 
 	err = disk.CreateSymlink(jiraIssue.Key, projectName, gitIssue.IID)
@@ -149,13 +266,83 @@ func exec(c *Cmd, argv []string) error {
 			p.Name, gitIssue.IID, jiraIssue.Key)
 		return err
 	}
+	if err = op.Finish(); err != nil {
+		return err
+	}
 	fmt.Printf("Issue '%s#%d'/'%s' has been created and linked successfully.\n",
 		p.Name, gitIssue.IID, jiraIssue.Key)
 	return nil
 }
 
-func md2jira(msg string) string {
-	renderer := &bfconf.Renderer{}
-	md := bf.New(bf.WithRenderer(renderer), bf.WithExtensions(bf.CommonExtensions))
-	return bytes.NewBuffer(renderer.Render(md.Parse([]byte(msg)))).String()
+func hasLabel(labels []string, label string) bool {
+	for _, l := range labels {
+		if strings.EqualFold(l, label) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveFields keys fields typed by name with their IDs and checks values,
+// so ticket is not rejected after GitLab issue is already created.
+func resolveFields(t *libjira.IssueType, args map[string]string) error {
+	for k, value := range args {
+		if !strings.HasPrefix(k, recovery.ArgField) {
+			continue
+		}
+		name := strings.TrimPrefix(k, recovery.ArgField)
+		f := t.Field(name)
+		if f == nil {
+			return errors.Errorf("field %s is not available for %s in %s", name, t.Name, args["jira_project"])
+		}
+		if err := f.Validate(value); err != nil {
+			return err
+		}
+		delete(args, k)
+		args[recovery.ArgField+f.ID] = value
+	}
+	return nil
+}
+
+// askMissing prompts for required Jira fields which are not set yet
+// and keeps typed values in operation arguments.
+func askMissing(t *libjira.IssueType, args map[string]string) error {
+	missing := t.Missing(recovery.JiraIssue(args))
+	if len(missing) == 0 {
+		return nil
+	}
+	fmt.Printf("%s in %s requires more fields:\n", t.Name, args["jira_project"])
+	r := bufio.NewReader(os.Stdin)
+	for _, f := range missing {
+		prompt := f.Name
+		if len(f.Allowed) > 0 {
+			prompt += " (" + strings.Join(f.Allowed, ", ") + ")"
+		}
+		for {
+			fmt.Printf("%s: ", prompt)
+			line, readErr := r.ReadString('\n')
+			value := strings.TrimSpace(line)
+			err := f.Validate(value)
+			if value != "" && err == nil {
+				args[recovery.ArgField+f.ID] = value
+				break
+			}
+			if value != "" {
+				fmt.Fprintln(os.Stderr, err)
+			}
+			if readErr != nil {
+				return errors.Errorf("required field %s is not set", f.Name)
+			}
+		}
+	}
+	return nil
+}
+
+func enqueue(disk *storage.Storage, args map[string]string) error {
+	e, err := queue.New(disk).Enqueue(journal.KindAdd, args)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Issue has been queued as operation %d, create it with 'jigit queue push'.\n", e.ID)
+	return nil
 }
@@ -0,0 +1,87 @@
+// Package pick lets user pick project or issue omitted on command line
+// when jigit is run in terminal.
+package pick
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"lib/git"
+	"lib/jira"
+	"lib/logging"
+	"lib/picker"
+	"lib/storage"
+	"lib/util"
+	"subcmd/config"
+)
+
+// Issue lets user pick one of issues assigned to them in GitLab and
+// Jira. Returns issue as project#id or Jira ticket key.
+func Issue(ctx context.Context, cfg *config.Config, disk *storage.Storage) (string, error) {
+	gitc, err := git.NewWithStorage(disk)
+	if err != nil {
+		return "", err
+	}
+	issues, err := gitc.ListAssignedIssues(ctx, false)
+	if err != nil {
+		return "", err
+	}
+	pids := make([]interface{}, len(issues))
+	for i, issue := range issues {
+		pids[i] = issue.ProjectID
+	}
+	names := gitc.ProjectNames(ctx, pids)
+
+	var items, refs []string
+	for _, issue := range issues {
+		name, ok := names[issue.ProjectID]
+		if !ok {
+			continue
+		}
+		ref := fmt.Sprintf("%s#%d", name, issue.IID)
+		items = append(items, ref+"  "+issue.Title)
+		refs = append(refs, ref)
+	}
+
+	if cfg.Jira.Address != "" {
+		jirac, err := jira.NewWithStorage(disk)
+		if err != nil {
+			return "", err
+		}
+		tickets, err := jirac.ListAssignedIssues(ctx, "", "")
+		if err != nil {
+			logging.Warnf("Can't fetch Jira tickets: %s", err)
+		}
+		for _, t := range tickets {
+			items = append(items, t.Key+"  "+t.Summary)
+			refs = append(refs, t.Key)
+		}
+	}
+
+	i, err := picker.Pick("issue", items)
+	if err != nil {
+		return "", err
+	}
+	return refs[i], nil
+}
+
+// Project lets user pick one of cached projects and returns its ID.
+func Project(ctx context.Context, gitc *git.Git) (int, error) {
+	projects, err := gitc.ListProjects(ctx, 0, false)
+	if err != nil {
+		return 0, err
+	}
+	items := make([]string, len(projects))
+	for i, p := range projects {
+		items[i] = p.Name
+		if p.Description != "" {
+			items[i] += "  " + util.TruncateString(strings.Replace(p.Description, "\n", " ", -1), 60)
+		}
+	}
+	i, err := picker.Pick("project", items)
+	if err != nil {
+		return 0, err
+	}
+	return projects[i].ID, nil
+}
@@ -0,0 +1,315 @@
+package queue
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"lib/editor"
+	"lib/git"
+	"lib/interrupt"
+	"lib/jira"
+	"lib/journal"
+	libqueue "lib/queue"
+	"lib/storage"
+	"lib/util"
+	"subcmd/config"
+	"subcmd/recovery"
+
+	"github.com/pkg/errors"
+)
+
+type Cmd struct {
+	Ls   LsCmd   `command:"ls" description:"list queued operations"`
+	Edit EditCmd `command:"edit" description:"edit message or Jira fields of queued operation"`
+	Drop DropCmd `command:"drop" description:"remove operations from queue"`
+	Push PushCmd `command:"push" description:"send queued operations to GitLab and Jira in order"`
+}
+
+type LsCmd struct{}
+
+func (c *LsCmd) Execute(argv []string) error {
+	return withQueue(func(disk *storage.Storage, q *libqueue.Queue) error {
+		entries, err := q.Entries()
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			fmt.Println("Queue is empty.")
+			return nil
+		}
+		for _, e := range entries {
+			fmt.Printf("%d\t%s\t%s\t%s\n", e.ID, e.Kind, util.RelativeTime(e.Created), describe(e))
+			if e.LastError != "" {
+				fmt.Printf("\tlast push failed: %s\n", e.LastError)
+			}
+		}
+		return nil
+	})
+}
+
+type EditCmd struct {
+	Fields []string `long:"field" description:"set Jira field of queued issue as \"Name=value\" instead of editing message. Can be repeated"`
+}
+
+func (c *EditCmd) Execute(argv []string) error {
+	if len(argv) != 1 {
+		return util.Usage("Provide ID of queued operation to edit: jigit queue edit ID")
+	}
+	id, err := strconv.Atoi(argv[0])
+	if err != nil {
+		return errors.Errorf("bad queue id '%s'", argv[0])
+	}
+	fields, err := jira.ParseFields(c.Fields)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	if cfg.Editor == "" && len(fields) == 0 {
+		return errors.New("editor is not configured, set it with 'jigit config --set editor vim'")
+	}
+
+	return withQueue(func(disk *storage.Storage, q *libqueue.Queue) error {
+		e, err := q.Entry(id)
+		if err != nil {
+			return err
+		}
+		if e.Args[libqueue.ArgOperation] != "" {
+			return errors.Errorf("operation %d is being pushed already and can't be edited", e.ID)
+		}
+
+		if len(fields) > 0 {
+			if e.Kind != journal.KindAdd {
+				return util.Usage("Operation %d doesn't create Jira ticket, it has no fields", e.ID)
+			}
+			for name, value := range fields {
+				e.Args[recovery.ArgField+name] = value
+			}
+			if err := q.Update(e); err != nil {
+				return err
+			}
+			fmt.Printf("Operation %d has been updated.\n", e.ID)
+			return nil
+		}
+
+		v, err := editor.NewFile(cfg.Editor, "queue")
+		if err != nil {
+			return err
+		}
+		if e.Kind == journal.KindAdd {
+			fmt.Fprintf(v, "%s\n\n", e.Args["title"])
+		}
+		fmt.Fprint(v, e.Args["body"])
+
+		if err = v.Run(); err != nil {
+			return err
+		}
+		b, err := v.Contents()
+		if err != nil {
+			return err
+		}
+
+		if e.Kind == journal.KindAdd {
+			r := bufio.NewReader(bytes.NewReader(b))
+			t, _, err := r.ReadLine()
+			if err != nil {
+				return err
+			}
+			e.Args["title"] = string(t)
+			b = b[len(t):]
+		}
+		body := strings.Trim(string(b), "\n ")
+		if body == "" {
+			return errors.New("empty message, use 'jigit queue drop' to remove operation")
+		}
+		e.Args["body"] = body
+
+		if err := q.Update(e); err != nil {
+			return err
+		}
+		fmt.Printf("Operation %d has been updated.\n", e.ID)
+		return nil
+	})
+}
+
+type DropCmd struct{}
+
+func (c *DropCmd) Execute(argv []string) error {
+	if len(argv) == 0 {
+		return util.Usage("Provide IDs of queued operations to drop: jigit queue drop ID [ID...]")
+	}
+	return withQueue(func(disk *storage.Storage, q *libqueue.Queue) error {
+		for _, arg := range argv {
+			id, err := strconv.Atoi(arg)
+			if err != nil {
+				return errors.Errorf("bad queue id '%s'", arg)
+			}
+			if err := q.Drop(id); err != nil {
+				return errors.Wrapf(err, "operation %d", id)
+			}
+			fmt.Printf("Operation %d has been dropped.\n", id)
+		}
+		return nil
+	})
+}
+
+type PushCmd struct{}
+
+func (c *PushCmd) Execute(argv []string) error {
+	ctx := interrupt.Context()
+	return withQueue(func(disk *storage.Storage, q *libqueue.Queue) error {
+		entries, err := q.Entries()
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			fmt.Println("Nothing to push.")
+			return nil
+		}
+
+		gitc, err := git.NewWithStorage(disk)
+		if err != nil {
+			return err
+		}
+		jirac, err := jira.NewWithStorage(disk)
+		if err != nil {
+			return err
+		}
+		jr := journal.New(disk)
+
+		for _, e := range entries {
+			if err := push(ctx, e, q, jr, gitc, jirac, disk); err != nil {
+				e.LastError = err.Error()
+				if uerr := q.Update(e); uerr != nil {
+					return uerr
+				}
+				// operations are pushed strictly in order, so stop on first failure
+				return errors.Wrapf(err, "can't push operation %d", e.ID)
+			}
+			if err := q.Drop(e.ID); err != nil {
+				return err
+			}
+			fmt.Printf("Operation %d (%s) has been pushed.\n", e.ID, describe(e))
+		}
+		return nil
+	})
+}
+
+func push(ctx context.Context, e *libqueue.Entry, q *libqueue.Queue, jr *journal.Journal,
+	gitc *git.Git, jirac *jira.Jira, disk *storage.Storage) error {
+
+	var op *journal.Operation
+	if id, err := strconv.Atoi(e.Args[libqueue.ArgOperation]); err == nil {
+		op, err = jr.Operation(id)
+		if err == journal.ErrNotFound {
+			// finished, but entry wasn't dropped
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if op == nil {
+		if e.Kind == journal.KindAdd {
+			if err := checkFields(ctx, e, jirac); err != nil {
+				return err
+			}
+		}
+		if e.Args["pid"] == "" {
+			p, err := gitc.ProjectByName(ctx, e.Args["project"], false, false)
+			if err != nil {
+				return err
+			}
+			e.Args["pid"] = strconv.Itoa(p.ID)
+		}
+
+		e.Args[libqueue.ArgEntry] = strconv.Itoa(e.ID)
+		var err error
+		op, err = jr.Begin(e.Kind, e.Args)
+		if err != nil {
+			return err
+		}
+		e.Args[libqueue.ArgOperation] = strconv.Itoa(op.ID)
+		if err := q.Update(e); err != nil {
+			return err
+		}
+	}
+
+	err := recovery.Execute(ctx, op, gitc, jirac, disk)
+	if err == nil {
+		return nil
+	}
+	if !op.Compensating {
+		// remote is still unreachable, operation will be resumed on next push
+		return err
+	}
+
+	if rerr := recovery.Compensate(op, gitc, jirac, disk); rerr != nil {
+		return errors.Errorf("%s; rollback failed, run 'jigit recover %d': %s", err, op.ID, rerr)
+	}
+	// rolled back, so the next push will start it from scratch
+	delete(e.Args, libqueue.ArgOperation)
+	return err
+}
+
+// checkFields checks Jira fields of queued issue against creation screen
+// before GitLab issue is created. Issues queued offline were never checked.
+func checkFields(ctx context.Context, e *libqueue.Entry, jirac *jira.Jira) error {
+	t, err := jirac.FindIssueType(ctx, e.Args["jira_project"], e.Args["issue_type"])
+	if err != nil {
+		return err
+	}
+	ticket := recovery.JiraIssue(e.Args)
+	for name, value := range ticket.Fields {
+		f := t.Field(name)
+		if f == nil {
+			return errors.Errorf("field %s is not available for %s in %s", name, t.Name, ticket.ProjectKey)
+		}
+		if err := f.Validate(value); err != nil {
+			return err
+		}
+	}
+	missing := t.Missing(ticket)
+	if len(missing) == 0 {
+		return nil
+	}
+	names := make([]string, len(missing))
+	for i, f := range missing {
+		names[i] = f.Name
+	}
+	return errors.Errorf("%s in %s requires fields %s, set them with 'jigit queue edit %d --field \"Name=value\"'",
+		t.Name, ticket.ProjectKey, strings.Join(names, ", "), e.ID)
+}
+
+func withQueue(fn func(disk *storage.Storage, q *libqueue.Queue) error) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	disk, err := storage.NewStorage(cfg.Storage.Path)
+	if err != nil {
+		return err
+	}
+	defer disk.Close()
+
+	return fn(disk, libqueue.New(disk))
+}
+
+func describe(e *libqueue.Entry) string {
+	switch e.Kind {
+	case journal.KindAdd:
+		return fmt.Sprintf("%s %q", e.Args["project"], e.Args["title"])
+	case journal.KindCommit:
+		return fmt.Sprintf("%s#%s %q", e.Args["project"], e.Args["issue"],
+			util.TruncateString(e.Args["body"], 60))
+	}
+	return ""
+}
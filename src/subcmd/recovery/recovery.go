@@ -0,0 +1,553 @@
+package recovery
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"lib/attach"
+	"lib/git"
+	"lib/interrupt"
+	"lib/jira"
+	"lib/journal"
+	"lib/logging"
+	"lib/queue"
+	"lib/storage"
+	"lib/util"
+	"subcmd/config"
+
+	"github.com/pkg/errors"
+)
+
+type Cmd struct {
+	List     bool `short:"l" long:"list" description:"print interrupted operations without touching them"`
+	Rollback bool `short:"r" long:"rollback" description:"compensate operations instead of resuming them"`
+	Drop     bool `long:"drop" description:"forget about operations without resuming or compensating them"`
+
+	Active bool
+	Argv   []string
+}
+
+func (c *Cmd) Execute(v []string) error {
+	c.Active, c.Argv = true, v
+	return process(interrupt.Context(), c)
+}
+
+func process(ctx context.Context, c *Cmd) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	disk, err := storage.NewStorage(cfg.Storage.Path)
+	if err != nil {
+		return err
+	}
+	defer disk.Close()
+
+	ops, err := selectOperations(journal.New(disk), queue.New(disk), c.Argv)
+	if err != nil {
+		return err
+	}
+	if len(ops) == 0 {
+		fmt.Println("Nothing to recover.")
+		return nil
+	}
+
+	if c.List {
+		for _, op := range ops {
+			fmt.Printf("%d\t%s\t%s\t%s\n", op.ID, op.Kind,
+				util.RelativeTime(op.Created), describe(op))
+		}
+		return nil
+	}
+
+	if c.Drop {
+		for _, op := range ops {
+			if err := op.Finish(); err != nil {
+				return err
+			}
+			fmt.Printf("Operation %d has been dropped.\n", op.ID)
+		}
+		return nil
+	}
+
+	gitc, err := git.NewWithStorage(disk)
+	if err != nil {
+		return err
+	}
+	jirac, err := jira.NewWithStorage(disk)
+	if err != nil {
+		return err
+	}
+
+	for _, op := range ops {
+		if c.Rollback && !op.Compensating {
+			if err := op.Compensate(); err != nil {
+				return err
+			}
+		}
+
+		if op.Compensating {
+			err = Rollback(ctx, op, gitc, jirac, disk)
+		} else {
+			err = Resume(ctx, op, gitc, jirac, disk)
+		}
+		if interrupt.Canceled(err) {
+			// interrupted rollback is finished outside of command context, interrupted
+			// resume keeps its done steps and is left for the next recover, as the
+			// rest of operations
+			if op.Compensating {
+				if cerr := Compensate(op, gitc, jirac, disk); cerr != nil {
+					fmt.Fprintf(os.Stderr, "Operation %d (%s) is still incomplete: %s\n", op.ID, op.Kind, cerr)
+				}
+			} else {
+				fmt.Fprintf(os.Stderr, "Operation %d (%s) is still incomplete, run 'jigit recover %d' to finish it.\n",
+					op.ID, op.Kind, op.ID)
+			}
+			return err
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Operation %d (%s) is still incomplete: %s\n", op.ID, op.Kind, err)
+			continue
+		}
+		fmt.Printf("Operation %d (%s) has been recovered.\n", op.ID, op.Kind)
+	}
+	return nil
+}
+
+// selectOperations returns operations to recover, ones pushed from queue
+// are left to 'jigit queue push'.
+func selectOperations(jr *journal.Journal, q *queue.Queue, argv []string) ([]*journal.Operation, error) {
+	if len(argv) == 0 {
+		pending, err := jr.Pending()
+		if err != nil {
+			return nil, err
+		}
+		ops := make([]*journal.Operation, 0, len(pending))
+		for _, op := range pending {
+			if !q.Owns(op.ID, op.Args) {
+				ops = append(ops, op)
+			}
+		}
+		return ops, nil
+	}
+	ops := make([]*journal.Operation, 0, len(argv))
+	for _, arg := range argv {
+		id, err := strconv.Atoi(arg)
+		if err != nil {
+			return nil, errors.Errorf("bad operation id '%s'", arg)
+		}
+		op, err := jr.Operation(id)
+		if err != nil {
+			return nil, errors.Wrapf(err, "operation %d", id)
+		}
+		if q.Owns(op.ID, op.Args) {
+			return nil, errors.Errorf("operation %d is pushed from queue, run 'jigit queue push' to finish it", id)
+		}
+		ops = append(ops, op)
+	}
+	return ops, nil
+}
+
+func describe(op *journal.Operation) string {
+	state := "resume"
+	if op.Compensating {
+		state = "rollback"
+	}
+	switch op.Kind {
+	case journal.KindAdd:
+		return fmt.Sprintf("%s %q [%s]", op.Args["project"], op.Args["title"], state)
+	case journal.KindCommit, journal.KindImport:
+		return fmt.Sprintf("%s#%s [%s]", op.Args["project"], op.Args["issue"], state)
+	case journal.KindImportJira:
+		return fmt.Sprintf("%s -> %s [%s]", op.Args["ticket"], op.Args["project"], state)
+	}
+	return state
+}
+
+// Resume finishes all steps of operation which were not done yet.
+// Operation which has no finished steps is dropped: there is no way
+// to know if the first request reached remote or not.
+func Resume(ctx context.Context, op *journal.Operation, gitc *git.Git, jirac *jira.Jira, disk *storage.Storage) error {
+	if len(op.Steps) == 0 {
+		return op.Finish()
+	}
+	return Execute(ctx, op, gitc, jirac, disk)
+}
+
+// Execute runs all steps of operation which were not done yet,
+// starting from the very first one.
+func Execute(ctx context.Context, op *journal.Operation, gitc *git.Git, jirac *jira.Jira, disk *storage.Storage) error {
+	switch op.Kind {
+	case journal.KindAdd:
+		return executeAdd(ctx, op, gitc, jirac, disk)
+	case journal.KindCommit:
+		return executeCommit(ctx, op, gitc, jirac)
+	case journal.KindImport, journal.KindImportJira:
+		return errors.Errorf("run 'jigit import' for project %s again to resume it", op.Args["project"])
+	}
+	return errors.Errorf("unknown operation kind '%s'", op.Kind)
+}
+
+// Rollback compensates all already done steps of operation.
+func Rollback(ctx context.Context, op *journal.Operation, gitc *git.Git, jirac *jira.Jira, disk *storage.Storage) error {
+	switch op.Kind {
+	case journal.KindAdd:
+		return rollbackAdd(ctx, op, gitc, jirac, disk)
+	case journal.KindCommit:
+		return rollbackCommit(ctx, op, gitc, jirac)
+	case journal.KindImport, journal.KindImportJira:
+		return rollbackImport(op, disk)
+	}
+	return errors.Errorf("unknown operation kind '%s'", op.Kind)
+}
+
+// rollbackImport keeps already imported issue, but links it with the
+// original one, so the next import doesn't create it again.
+func rollbackImport(op *journal.Operation, disk *storage.Storage) error {
+	if !op.IsDone(journal.StepLink) {
+		key, iid := op.Value(journal.StepJiraIssue), op.Args["issue"]
+		if op.Kind == journal.KindImportJira {
+			key, iid = op.Args["ticket"], op.Value(journal.StepGitIssue)
+		}
+		if key != "" && iid != "" {
+			id, err := strconv.Atoi(iid)
+			if err != nil {
+				return err
+			}
+			if err := disk.CreateSymlink(key, op.Args["project"], id); err != nil {
+				return errors.Wrap(err, "can't link imported issue")
+			}
+		}
+	}
+	return op.Finish()
+}
+
+// Postpone hands operation left half-done by unreachable remote over to
+// queue, so 'jigit queue push' finishes it and recover leaves it alone.
+func Postpone(op *journal.Operation, disk *storage.Storage) (*queue.Entry, error) {
+	args := make(map[string]string, len(op.Args)+1)
+	for k, v := range op.Args {
+		args[k] = v
+	}
+	args[queue.ArgOperation] = strconv.Itoa(op.ID)
+	e, err := queue.New(disk).Enqueue(op.Kind, args)
+	if err != nil {
+		return nil, err
+	}
+	return e, op.SetArg(queue.ArgEntry, strconv.Itoa(e.ID))
+}
+
+// Compensate marks operation as compensating and rolls it back right away.
+// Rollback isn't bound to the command context, so it's done even when the
+// command is interrupted.
+func Compensate(op *journal.Operation, gitc *git.Git, jirac *jira.Jira, disk *storage.Storage) error {
+	if err := op.Compensate(); err != nil {
+		return err
+	}
+	ctx, cancel := interrupt.Cleanup()
+	defer cancel()
+	return Rollback(ctx, op, gitc, jirac, disk)
+}
+
+// ArgField prefixes arguments of add operation which keep values
+// of Jira fields by field ID.
+const ArgField = "field."
+
+// JiraIssue returns Jira ticket described by arguments of add operation.
+func JiraIssue(args map[string]string) *jira.Issue {
+	return &jira.Issue{
+		ProjectKey: args["jira_project"],
+		Type:       args["issue_type"],
+		Summary:    args["title"],
+		Fields:     JiraFields(args),
+	}
+}
+
+// GitLabels returns labels of GitLab issue kept in operation arguments.
+func GitLabels(args map[string]string) []string {
+	if args["labels"] == "" {
+		return nil
+	}
+	return strings.Split(args["labels"], ",")
+}
+
+// JiraFields returns values of Jira fields kept in operation arguments.
+func JiraFields(args map[string]string) map[string]string {
+	fields := make(map[string]string)
+	for k, v := range args {
+		if strings.HasPrefix(k, ArgField) {
+			fields[strings.TrimPrefix(k, ArgField)] = v
+		}
+	}
+	return fields
+}
+
+func executeAdd(ctx context.Context, op *journal.Operation, gitc *git.Git, jirac *jira.Jira, disk *storage.Storage) error {
+	if !op.IsDone(journal.StepGitIssue) {
+		// ticket which Jira would reject is not worth a GitLab issue
+		ticket := JiraIssue(op.Args)
+		t, err := jirac.FindIssueType(ctx, ticket.ProjectKey, ticket.Type)
+		if err != nil {
+			return err
+		}
+		if missing := t.Missing(ticket); len(missing) > 0 {
+			return errors.Errorf("required Jira field %s is not set, add the issue again", missing[0].Name)
+		}
+
+		pid, err := strconv.Atoi(op.Args["pid"])
+		if err != nil {
+			return err
+		}
+		assignee := op.Args["assignee"]
+		if assignee == "" {
+			user, err := gitc.User(ctx)
+			if err != nil {
+				return err
+			}
+			assignee = user.Login
+		}
+		issue, err := gitc.CreateIssue(ctx, &git.Issue{
+			ProjectID:        pid,
+			Title:            op.Args["title"],
+			Description:      op.Args["body"],
+			Labels:           GitLabels(op.Args),
+			AssigneeUsername: assignee,
+		})
+		if err != nil {
+			return errors.Wrap(err, "can't create GitLab issue")
+		}
+		if err := op.Done(journal.StepGitIssue, strconv.Itoa(issue.IID)); err != nil {
+			return err
+		}
+	}
+	iid, err := strconv.Atoi(op.Value(journal.StepGitIssue))
+	if err != nil {
+		return err
+	}
+
+	if !op.IsDone(journal.StepJiraIssue) {
+		user, err := jirac.User(ctx)
+		if err != nil {
+			return err
+		}
+		assignee := *user
+		if op.Args["jira_assignee"] != "" {
+			assignee = jirac.UserByKey(op.Args["jira_assignee"])
+		}
+		p, err := gitc.ProjectByName(ctx, op.Args["project"], false, false)
+		if err != nil {
+			return err
+		}
+		ticket := JiraIssue(op.Args)
+		ticket.Description = git.AbsoluteUploads(p, op.Args["body"])
+		ticket.Assignee = assignee
+		ticket.Creator = *user
+		issue, err := jirac.CreateIssue(ctx, ticket)
+		if err != nil {
+			if err := compensateUnlessUnreachable(op, err); err != nil {
+				return err
+			}
+			return errors.Wrap(err, "can't create Jira ticket")
+		}
+		if err := op.Done(journal.StepJiraIssue, issue.Key); err != nil {
+			return err
+		}
+	}
+
+	if !op.IsDone(journal.StepLink) {
+		p, err := gitc.ProjectByName(ctx, op.Args["project"], false, false)
+		if err != nil {
+			return err
+		}
+		if err := JiraDescription(ctx, op, gitc, jirac, p); err != nil {
+			logging.Warnf("Can't copy attachments to %s: %s", op.Value(journal.StepJiraIssue), err)
+		}
+		err = disk.CreateSymlink(op.Value(journal.StepJiraIssue), op.Args["project"], iid)
+		if err != nil {
+			return errors.Wrap(err, "can't link issues")
+		}
+		if err := op.Done(journal.StepLink, ""); err != nil {
+			return err
+		}
+	}
+	return op.Finish()
+}
+
+// JiraDescription copies GitLab uploads referenced from description of Jira
+// ticket created by add operation. Rewritten description is recorded before
+// it's set, so uploads aren't copied again when operation is resumed.
+func JiraDescription(ctx context.Context, op *journal.Operation, gitc *git.Git, jirac *jira.Jira, p *git.Project) error {
+	key := op.Value(journal.StepJiraIssue)
+	if len(git.FindUploads(op.Args["body"])) == 0 {
+		return nil
+	}
+	var copyErr error
+	if !op.IsDone(journal.StepJiraAttachments) {
+		var text string
+		text, copyErr = attach.ToJira(ctx, gitc, jirac, p, key, op.Args["body"])
+		if err := op.Done(journal.StepJiraAttachments, text); err != nil {
+			return err
+		}
+	}
+	if err := jirac.SetDescription(ctx, key, op.Value(journal.StepJiraAttachments)); err != nil {
+		return err
+	}
+	return copyErr
+}
+
+// JiraComment returns body of Jira comment of commit operation with GitLab
+// uploads copied into attachments. Body is recorded, so uploads aren't
+// copied again when operation is resumed.
+func JiraComment(ctx context.Context, op *journal.Operation, gitc *git.Git, jirac *jira.Jira, p *git.Project) (string, error) {
+	if !op.IsDone(journal.StepJiraAttachments) {
+		body, err := attach.ToJira(ctx, gitc, jirac, p, op.Args["ticket"], op.Args["body"])
+		if err != nil {
+			logging.Warnf("Can't copy attachments to %s: %s", op.Args["ticket"], err)
+		}
+		if err := op.Done(journal.StepJiraAttachments, body); err != nil {
+			return "", err
+		}
+	}
+	return op.Value(journal.StepJiraAttachments), nil
+}
+
+// Jira rejection should roll back whole operation, but if it's just
+// unreachable, operation could be resumed later.
+func compensateUnlessUnreachable(op *journal.Operation, cause error) error {
+	if queue.Unreachable(cause) {
+		return nil
+	}
+	return op.Compensate()
+}
+
+func rollbackAdd(ctx context.Context, op *journal.Operation, gitc *git.Git, jirac *jira.Jira, disk *storage.Storage) error {
+	// steps are compensated in reverse order
+	if op.IsDone(journal.StepLink) {
+		iid, err := strconv.Atoi(op.Value(journal.StepGitIssue))
+		if err != nil {
+			return err
+		}
+		if err := disk.DropSymlink(op.Value(journal.StepJiraIssue), op.Args["project"], iid); err != nil {
+			return errors.Wrap(err, "can't remove link")
+		}
+		if err := op.Undone(journal.StepLink); err != nil {
+			return err
+		}
+	}
+	if op.IsDone(journal.StepJiraIssue) {
+		key := op.Value(journal.StepJiraIssue)
+		if err := jirac.DeleteIssue(ctx, key); err != nil {
+			return errors.Wrapf(err, "can't delete Jira ticket %s", key)
+		}
+		if err := op.Undone(journal.StepJiraIssue); err != nil {
+			return err
+		}
+	}
+	if op.IsDone(journal.StepGitIssue) {
+		pid, err := strconv.Atoi(op.Args["pid"])
+		if err != nil {
+			return err
+		}
+		iid, err := strconv.Atoi(op.Value(journal.StepGitIssue))
+		if err != nil {
+			return err
+		}
+		if err := gitc.InitClient(); err != nil {
+			return err
+		}
+		_, err = gitc.UpdateIssue(ctx, &git.Issue{
+			ProjectID:   pid,
+			IID:         iid,
+			Title:       op.Args["title"],
+			Description: "Issue closed automatically due to unexpected Jira response.",
+			State:       git.IssueStateClose,
+		})
+		if err != nil {
+			return errors.Wrapf(err, "can't close GitLab issue #%d", iid)
+		}
+		if err := op.Undone(journal.StepGitIssue); err != nil {
+			return err
+		}
+	}
+	return op.Finish()
+}
+
+func executeCommit(ctx context.Context, op *journal.Operation, gitc *git.Git, jirac *jira.Jira) error {
+	if !op.IsDone(journal.StepGitComment) {
+		pid, err := strconv.Atoi(op.Args["pid"])
+		if err != nil {
+			return err
+		}
+		iid, err := strconv.Atoi(op.Args["issue"])
+		if err != nil {
+			return err
+		}
+		cid, err := gitc.Comment(ctx, pid, iid, op.Args["body"])
+		if err != nil {
+			return errors.Wrap(err, "can't create GitLab comment")
+		}
+		if err := op.Done(journal.StepGitComment, strconv.Itoa(cid)); err != nil {
+			return err
+		}
+	}
+	if !op.IsDone(journal.StepJiraComment) && op.Args["ticket"] != "" {
+		p, err := gitc.ProjectByName(ctx, op.Args["project"], false, false)
+		if err != nil {
+			return err
+		}
+		body, err := JiraComment(ctx, op, gitc, jirac, p)
+		if err != nil {
+			return err
+		}
+		cid, err := jirac.Comment(ctx, op.Args["ticket"], body)
+		if err != nil {
+			if err := compensateUnlessUnreachable(op, err); err != nil {
+				return err
+			}
+			return errors.Wrap(err, "can't create Jira comment")
+		}
+		if err := op.Done(journal.StepJiraComment, cid); err != nil {
+			return err
+		}
+	}
+	return op.Finish()
+}
+
+func rollbackCommit(ctx context.Context, op *journal.Operation, gitc *git.Git, jirac *jira.Jira) error {
+	if op.IsDone(journal.StepJiraComment) {
+		cid := op.Value(journal.StepJiraComment)
+		if cid == "" {
+			return errors.Errorf("ID of Jira comment on %s is not recorded", op.Args["ticket"])
+		}
+		if err := jirac.DeleteComment(ctx, op.Args["ticket"], cid); err != nil {
+			return errors.Wrap(err, "can't remove Jira comment")
+		}
+		if err := op.Undone(journal.StepJiraComment); err != nil {
+			return err
+		}
+	}
+	if op.IsDone(journal.StepGitComment) {
+		pid, err := strconv.Atoi(op.Args["pid"])
+		if err != nil {
+			return err
+		}
+		iid, err := strconv.Atoi(op.Args["issue"])
+		if err != nil {
+			return err
+		}
+		cid, err := strconv.Atoi(op.Value(journal.StepGitComment))
+		if err != nil {
+			return err
+		}
+		if err := gitc.DeleteComment(ctx, pid, iid, cid); err != nil {
+			return errors.Wrap(err, "can't remove GitLab comment")
+		}
+		if err := op.Undone(journal.StepGitComment); err != nil {
+			return err
+		}
+	}
+	return op.Finish()
+}
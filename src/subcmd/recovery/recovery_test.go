@@ -0,0 +1,138 @@
+package recovery
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"lib/journal"
+	"lib/queue"
+	"lib/storage"
+)
+
+func newStorage(t *testing.T) *storage.Storage {
+	dir, err := ioutil.TempDir("", "jigit-recovery")
+	if err != nil {
+		t.Fatal(err)
+	}
+	disk, err := storage.NewStorage(filepath.Join(dir, "jigit.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		disk.Close()
+		os.RemoveAll(dir)
+	})
+	return disk
+}
+
+func TestRollbackImport(t *testing.T) {
+	tests := []struct {
+		name  string
+		kind  string
+		args  map[string]string
+		steps map[string]string
+		link  string // expected link of backend#4, if any
+	}{
+		{"ticket created", journal.KindImport,
+			map[string]string{"project": "backend", "issue": "4"},
+			map[string]string{journal.StepJiraIssue: "ABC-1"}, "ABC-1"},
+		{"nothing created", journal.KindImport,
+			map[string]string{"project": "backend", "issue": "4"},
+			map[string]string{}, ""},
+		{"issue created", journal.KindImportJira,
+			map[string]string{"project": "backend", "ticket": "ABC-2"},
+			map[string]string{journal.StepGitIssue: "4"}, "ABC-2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			disk := newStorage(t)
+			jr := journal.New(disk)
+			op, err := jr.Begin(tt.kind, tt.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for step, value := range tt.steps {
+				if err := op.Done(step, value); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := Compensate(op, nil, nil, disk); err != nil {
+				t.Fatal(err)
+			}
+			link, _ := disk.GetString(storage.BucketIssueLinks, []byte("backend#4"))
+			if link != tt.link {
+				t.Errorf("backend#4 is linked to %q, expected %q", link, tt.link)
+			}
+			if _, err := jr.Operation(op.ID); err != journal.ErrNotFound {
+				t.Errorf("expected operation to be finished, got %v", err)
+			}
+		})
+	}
+}
+
+// Operation interrupted before its first step can't be resumed, nobody
+// knows if the first request reached remote.
+func TestResumeWithoutSteps(t *testing.T) {
+	disk := newStorage(t)
+	jr := journal.New(disk)
+	op, err := jr.Begin(journal.KindCommit, map[string]string{"body": "text"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Resume(context.Background(), op, nil, nil, disk); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := jr.Operation(op.ID); err != journal.ErrNotFound {
+		t.Errorf("expected operation to be dropped, got %v", err)
+	}
+}
+
+func TestPostpone(t *testing.T) {
+	disk := newStorage(t)
+	jr, q := journal.New(disk), queue.New(disk)
+	own, err := jr.Begin(journal.KindCommit, map[string]string{"project": "backend", "issue": "4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := own.Done(journal.StepGitComment, "10"); err != nil {
+		t.Fatal(err)
+	}
+	postponed, err := jr.Begin(journal.KindCommit, map[string]string{"project": "backend", "issue": "5"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := postponed.Done(journal.StepGitComment, "11"); err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := Postpone(postponed, disk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Args[queue.ArgOperation] != strconv.Itoa(postponed.ID) || e.Args["issue"] != "5" {
+		t.Errorf("unexpected queued arguments %v", e.Args)
+	}
+	stored, err := jr.Operation(postponed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !q.Owns(stored.ID, stored.Args) {
+		t.Error("expected postponed operation to be owned by queue")
+	}
+
+	ops, err := selectOperations(jr, q, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ops) != 1 || ops[0].ID != own.ID {
+		t.Errorf("expected only operation %d to be recovered, got %v", own.ID, ops)
+	}
+	if _, err := selectOperations(jr, q, []string{strconv.Itoa(postponed.ID)}); err == nil {
+		t.Error("expected error for operation owned by queue")
+	}
+}
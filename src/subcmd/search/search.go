@@ -0,0 +1,146 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"lib/interrupt"
+	"lib/jira"
+	"lib/logging"
+	"lib/output"
+	"lib/storage"
+	"lib/util"
+	"subcmd/config"
+	"subcmd/list"
+
+	"github.com/pkg/errors"
+)
+
+type Cmd struct {
+	JQL     string `long:"jql" description:"JQL query, saved one can be passed as @name argument instead"`
+	Columns string `short:"c" long:"columns" description:"comma-separated columns: key, project, type, status, priority, assignee, reporter, parent, labels, created, summary or name of custom field"`
+	Limit   int    `short:"n" long:"limit" description:"show at most n issues, all of them by default"`
+	Save    string `long:"save" description:"save query under the name to run it later as 'jigit search @name'"`
+
+	output.Flags
+
+	Active bool
+	Argv   []string
+}
+
+func (c *Cmd) Execute(argv []string) error {
+	c.Active, c.Argv = true, argv
+	return process(interrupt.Context(), c)
+}
+
+func process(ctx context.Context, c *Cmd) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	jql := c.JQL
+	if jql == "" && len(c.Argv) > 0 {
+		jql = strings.Join(c.Argv, " ")
+	}
+	if strings.HasPrefix(jql, "@") {
+		saved, ok := cfg.Queries[jql[1:]]
+		if !ok {
+			return errors.Errorf("there is no saved query '%s', list them with 'jigit search'", jql[1:])
+		}
+		jql = saved
+	}
+	if jql == "" {
+		printQueries(cfg.Queries)
+		return nil
+	}
+	if c.Save != "" {
+		if err := cfg.SaveQuery(c.Save, jql); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Query has been saved, run it with 'jigit search @%s'.\n", c.Save)
+	}
+
+	columns := list.DefaultJiraColumns
+	if c.Columns != "" {
+		columns = strings.Split(c.Columns, ",")
+		for i := range columns {
+			columns[i] = strings.TrimSpace(columns[i])
+		}
+	}
+
+	disk, err := storage.NewStorage(cfg.Storage.Path)
+	if err != nil {
+		return err
+	}
+	defer disk.Close()
+	jirac, err := jira.NewWithStorage(disk)
+	if err != nil {
+		return err
+	}
+
+	// custom fields are only needed for columns which are not standard,
+	// records name all fields
+	var names map[string]string
+	for _, column := range columns {
+		if !list.IsJiraColumn(column) || !c.Tabular() {
+			if names, err = jirac.FieldNames(ctx); err != nil {
+				return err
+			}
+			break
+		}
+	}
+
+	table, err := list.NewJiraTable(os.Stdout, columns, names)
+	if err != nil {
+		return err
+	}
+	if err := table.SetOutput(c.Flags, jirac.BrowseURL); err != nil {
+		return err
+	}
+	// pages are printed as soon as they arrive, large results take a while
+	var shown, total int
+	err = jirac.EachIssue(ctx, jql, c.Limit, func(issues []*jira.Issue, n int) error {
+		shown += len(issues)
+		total = n
+		return table.Render(issues)
+	})
+	if err != nil {
+		return err
+	}
+	if err := table.Close(); err != nil {
+		return err
+	}
+	if !table.Tabular() {
+		if shown < total {
+			logging.Warnf("Showing %d of %s, raise the limit with -n to see more", shown, util.Plural(total, "issue", ""))
+		}
+		return nil
+	}
+	if shown < total {
+		fmt.Printf("\nShowing %d of %s, raise the limit with -n to see more.\n",
+			shown, util.Plural(total, "issue", ""))
+		return nil
+	}
+	fmt.Printf("\nFound %s.\n", util.Plural(total, "issue", ""))
+	return nil
+}
+
+func printQueries(queries map[string]string) {
+	if len(queries) == 0 {
+		fmt.Println("There are no saved queries. Save one with\n\n" +
+			"\tjigit search --jql \"assignee = currentUser()\" --save mine")
+		return
+	}
+	names := make([]string, 0, len(queries))
+	for name := range queries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("@%s\t%s\n", name, queries[name])
+	}
+}
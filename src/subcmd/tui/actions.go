@@ -0,0 +1,164 @@
+package tui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"lib/editor"
+	"lib/journal"
+	"lib/queue"
+	"lib/ref"
+	"subcmd/recovery"
+
+	"github.com/pkg/errors"
+)
+
+// comment adds comment to GitLab issue and linked Jira ticket, as
+// 'jigit commit' does, so interrupted one can be recovered.
+func (v *issueView) comment(a *app, text string) {
+	if text = strings.TrimSpace(text); text == "" {
+		return
+	}
+	args := map[string]string{
+		"project": v.name,
+		"pid":     strconv.Itoa(v.pid),
+		"issue":   strconv.Itoa(v.iid),
+		"body":    text,
+	}
+	if a.jira != nil {
+		args["ticket"] = v.ticket
+	}
+	op, err := journal.New(a.disk).Begin(journal.KindCommit, args)
+	if err != nil {
+		a.fail(err)
+		return
+	}
+
+	a.busy("Sending comment...")
+	err = recovery.Execute(a.ctx, op, a.git, a.jira, a.disk)
+	switch {
+	case err == nil:
+	case !op.IsDone(journal.StepGitComment):
+		op.Finish()
+		a.fail(err)
+		return
+	case op.Compensating:
+		if cerr := recovery.Compensate(op, a.git, a.jira, a.disk); cerr != nil {
+			err = errors.Errorf("%s; can't remove GitLab comment, run 'jigit recover %d': %s", err, op.ID, cerr)
+		}
+		err = errors.Wrapf(err, "comment is not added to %s", v.ticket)
+	case queue.Unreachable(err):
+		if e, qerr := recovery.Postpone(op, a.disk); qerr != nil {
+			err = errors.Errorf("%s; can't queue it, run 'jigit recover %d': %s", err, op.ID, qerr)
+		} else {
+			err = errors.Wrapf(err, "comment is added to GitLab only, it's queued as operation %d for %s",
+				e.ID, v.ticket)
+		}
+	default:
+		err = errors.Wrapf(err, "comment is added to GitLab only, run 'jigit recover %d' to send it to %s",
+			op.ID, v.ticket)
+	}
+	if args["ticket"] != "" {
+		a.jira.InvalidateCache()
+	}
+	a.reload(v)
+	if err != nil {
+		a.fail(err)
+		return
+	}
+	a.status = "Comment has been added."
+}
+
+// editComment writes comment in editor, the screen is given to it meanwhile.
+func (v *issueView) editComment(a *app) {
+	if a.cfg.Editor == "" {
+		a.status = "Editor is not set, set $EDITOR or run 'jigit config --set editor vim'."
+		return
+	}
+	a.scr.suspend()
+	text, err := edit(a.cfg.Editor)
+	if err := a.scr.resume(); err != nil {
+		a.fail(err)
+		return
+	}
+	if err != nil {
+		a.fail(err)
+		return
+	}
+	v.comment(a, text)
+}
+
+func edit(name string) (string, error) {
+	f, err := editor.NewFile(name, "comment")
+	if err != nil {
+		return "", err
+	}
+	if err := f.Run(); err != nil {
+		return "", err
+	}
+	b, err := f.Contents()
+	return string(b), err
+}
+
+// transition moves linked Jira ticket to the status.
+func (v *issueView) transition(a *app) {
+	if v.ticket == "" || a.jira == nil {
+		a.status = "Issue is not linked to Jira, press l to link it."
+		return
+	}
+	status, ok := a.prompt(fmt.Sprintf("Move %s to status: ", v.ticket), "")
+	if !ok || status == "" {
+		return
+	}
+	a.busy(fmt.Sprintf("Moving %s to %s...", v.ticket, status))
+	if err := a.jira.Transition(a.ctx, v.ticket, status); err != nil {
+		a.fail(err)
+		return
+	}
+	a.jira.InvalidateCache()
+	a.reload(v)
+	a.status = fmt.Sprintf("%s has been moved to %s.", v.ticket, status)
+}
+
+// link links issue with Jira ticket, replacing the previous link.
+func (v *issueView) link(a *app) {
+	if a.jira == nil {
+		a.status = "Jira is not configured, set it with 'jigit config --set jira.address <url>'."
+		return
+	}
+	input, ok := a.prompt(fmt.Sprintf("Link %s to Jira ticket: ", v.ref()), v.ticket)
+	if !ok || strings.TrimSpace(input) == "" {
+		return
+	}
+	r, err := ref.Parse(input)
+	if err == nil && r.Kind != ref.Ticket {
+		err = errors.Errorf("%s is not a Jira ticket", r)
+	}
+	if err != nil {
+		a.fail(err)
+		return
+	}
+	key := r.Key
+	if key == v.ticket {
+		return
+	}
+	a.busy("Fetching " + key + "...")
+	ticket, err := a.jira.Issue(a.ctx, key)
+	if err != nil {
+		a.fail(err)
+		return
+	}
+	if v.ticket != "" {
+		if err := a.disk.DropSymlink(v.ticket, v.name, v.iid); err != nil {
+			a.fail(err)
+			return
+		}
+	}
+	if err := a.disk.CreateSymlink(ticket.Key, v.name, v.iid); err != nil {
+		a.fail(err)
+		return
+	}
+	v.ticket, v.tissue, v.terr = ticket.Key, ticket, nil
+	a.status = fmt.Sprintf("%s has been linked with %s.", v.ref(), ticket.Key)
+}
@@ -0,0 +1,197 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+
+	"lib/git"
+	"lib/jira"
+	"lib/storage"
+	"lib/theme"
+	"lib/util"
+	"subcmd/list"
+)
+
+// sideBySide is the least width to show Jira ticket next to GitLab issue.
+const sideBySide = 100
+
+// issueView shows GitLab issue with its linked Jira ticket.
+type issueView struct {
+	name string
+	pid  int
+	iid  int
+
+	issue  *git.Issue
+	notes  []*git.Comment
+	ticket string
+	tissue *jira.Issue
+	terr   error
+
+	scroll int
+	height int
+}
+
+func newIssueView(p *git.Project, name string, pid, iid int) *issueView {
+	if p != nil {
+		name = p.Name
+	}
+	return &issueView{name: name, pid: pid, iid: iid}
+}
+
+func (v *issueView) title() string {
+	t := fmt.Sprintf("%s#%d", v.name, v.iid)
+	if v.ticket != "" {
+		t += " ⇄ " + v.ticket
+	}
+	return t
+}
+
+func (v *issueView) help() string {
+	return "↑↓ scroll  c comment  e comment in editor  t transition  l link  r refresh"
+}
+
+// ref is the reference links are stored by, as 'jigit ln' does.
+func (v *issueView) ref() string {
+	return fmt.Sprintf("%s#%d", v.name, v.iid)
+}
+
+func (v *issueView) load(a *app) error {
+	issue, notes, err := a.git.DetailedProjectIssue(a.ctx, v.pid, v.iid)
+	if err != nil {
+		return err
+	}
+	sort.Sort(list.GitCommentsTimeSort(notes))
+	v.issue, v.notes = issue, notes
+
+	v.ticket, v.tissue, v.terr = "", nil, nil
+	if key, err := a.disk.GetString(storage.BucketIssueLinks, []byte(v.ref())); err == nil {
+		v.ticket = key
+	}
+	if v.ticket != "" && a.jira != nil {
+		v.tissue, v.terr = a.jira.Issue(a.ctx, v.ticket)
+	}
+	return nil
+}
+
+func (v *issueView) render(a *app, width, height int) []string {
+	v.height = height
+	if v.issue == nil {
+		return nil
+	}
+	var lines []string
+	if width >= sideBySide {
+		half := (width - 3) / 2
+		left, right := v.gitLines(half), v.jiraLines(a, width-half-3)
+		sep := theme.Time(" │ ")
+		for i := 0; i < len(left) || i < len(right); i++ {
+			var l, r string
+			if i < len(left) {
+				l = left[i]
+			}
+			if i < len(right) {
+				r = right[i]
+			}
+			lines = append(lines, fit(l, half)+sep+r)
+		}
+	} else {
+		lines = append(v.gitLines(width), "")
+		lines = append(lines, v.jiraLines(a, width)...)
+	}
+
+	if max := len(lines) - height; v.scroll > max {
+		v.scroll = max
+	}
+	if v.scroll < 0 {
+		v.scroll = 0
+	}
+	return lines[v.scroll:]
+}
+
+func (v *issueView) gitLines(width int) []string {
+	i := v.issue
+	lines := []string{
+		theme.Key(fmt.Sprintf("#%d", i.IID)) + " " + theme.State(string(i.State)),
+	}
+	lines = append(lines, wrap(i.Title, width)...)
+	lines = append(lines, "",
+		"Assignee: "+theme.Author(printIfNotEmpty(i.AssigneeName)),
+		"Created:  "+theme.Time(util.RelativeTime(i.CreatedAt)))
+	if len(i.Labels) > 0 {
+		lines = append(lines, "Labels:   "+theme.Labels(i.Labels))
+	}
+	lines = append(lines, "Link:     "+i.WebURL, "")
+	lines = append(lines, wrap(i.Description, width)...)
+	lines = append(lines, "", theme.Time(fmt.Sprintf("── %s ", util.Plural(len(v.notes), "comment", ""))))
+	for _, n := range v.notes {
+		lines = append(lines, "", theme.Author("@"+n.AuthorUsername)+" "+theme.Time(util.RelativeTime(n.CreatedAt)))
+		lines = append(lines, wrap(n.Body, width)...)
+	}
+	return lines
+}
+
+func (v *issueView) jiraLines(a *app, width int) []string {
+	switch {
+	case a.jira == nil:
+		return []string{theme.Time("Jira is not configured")}
+	case v.ticket == "":
+		return []string{theme.Time("Not linked to Jira, press l to link it")}
+	case v.terr != nil:
+		return append([]string{"Can't fetch " + v.ticket + ":"}, wrap(v.terr.Error(), width)...)
+	}
+	i := v.tissue
+	lines := []string{theme.Key(i.Key) + " " + theme.State(i.StatusName)}
+	lines = append(lines, wrap(i.Summary, width)...)
+	lines = append(lines, "",
+		"Type:     "+i.Type,
+		"Priority: "+theme.Priority(i.PriorityName),
+		"Assignee: "+theme.Author(printIfNotEmpty(i.Assignee.Name)),
+		"Created:  "+theme.Time(util.RelativeTime(i.Created)),
+		"Link:     "+a.jira.BrowseURL(i.Key), "")
+	lines = append(lines, wrap(i.Description, width)...)
+	lines = append(lines, "", theme.Time(fmt.Sprintf("── %s ", util.Plural(len(i.Comments), "comment", ""))))
+	for _, c := range i.Comments {
+		lines = append(lines, "", theme.Author("@"+c.Author.Name))
+		lines = append(lines, wrap(c.Body, width)...)
+	}
+	return lines
+}
+
+func (v *issueView) handle(a *app, k key) bool {
+	switch k {
+	case keyUp, 'k':
+		v.scroll--
+	case keyDown, 'j':
+		v.scroll++
+	case keyPageUp:
+		v.scroll -= v.height
+	case keyPageDown, ' ':
+		v.scroll += v.height
+	case keyHome, 'g':
+		v.scroll = 0
+	case 'c':
+		if text, ok := a.prompt("Comment: ", ""); ok {
+			v.comment(a, text)
+		}
+	case 'e':
+		v.editComment(a)
+	case 't':
+		v.transition(a)
+	case 'l':
+		v.link(a)
+	case 'r':
+		if a.jira != nil {
+			a.jira.InvalidateCache()
+		}
+		a.reload(v)
+	default:
+		return false
+	}
+	return true
+}
+
+func printIfNotEmpty(s string) string {
+	if s == "" {
+		return "--"
+	}
+	return s
+}
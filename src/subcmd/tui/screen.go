@@ -0,0 +1,187 @@
+package tui
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// key is a pressed key, special keys are negative.
+type key rune
+
+const (
+	keyUp key = -1 - iota
+	keyDown
+	keyPageUp
+	keyPageDown
+	keyHome
+	keyEnd
+	keyUnknown
+
+	keyCtrlC     key = 3
+	keyEnter     key = '\r'
+	keyEsc       key = 27
+	keyBackspace key = 127
+)
+
+var escapes = map[string]key{
+	"[A": keyUp, "[B": keyDown, "OA": keyUp, "OB": keyDown,
+	"[5~": keyPageUp, "[6~": keyPageDown,
+	"[H": keyHome, "[F": keyEnd, "[1~": keyHome, "[4~": keyEnd,
+}
+
+// screen owns terminal: it's switched to raw mode and alternate screen,
+// which are restored on close.
+type screen struct {
+	in    int
+	out   *bufio.Writer
+	state *terminal.State
+}
+
+func newScreen() (*screen, error) {
+	in := int(os.Stdin.Fd())
+	if !terminal.IsTerminal(in) || !terminal.IsTerminal(int(os.Stdout.Fd())) {
+		return nil, errors.New("terminal UI needs a terminal, it can't run with redirected input or output")
+	}
+	s := &screen{in: in, out: bufio.NewWriter(os.Stdout)}
+	return s, s.resume()
+}
+
+// resume takes terminal back after suspend.
+func (s *screen) resume() error {
+	state, err := terminal.MakeRaw(s.in)
+	if err != nil {
+		return errors.Wrap(err, "can't switch terminal to raw mode")
+	}
+	s.state = state
+	s.out.WriteString("\x1b[?1049h\x1b[?25l")
+	return s.out.Flush()
+}
+
+// suspend gives terminal to other programs, e.g. editor.
+func (s *screen) suspend() {
+	s.out.WriteString("\x1b[?25h\x1b[?1049l")
+	s.out.Flush()
+	if s.state != nil {
+		terminal.Restore(s.in, s.state)
+		s.state = nil
+	}
+}
+
+func (s *screen) close() {
+	s.suspend()
+}
+
+// size returns columns and rows of terminal.
+func (s *screen) size() (int, int) {
+	w, h, err := terminal.GetSize(int(os.Stdout.Fd()))
+	if err != nil || w <= 0 || h <= 0 {
+		return 80, 24
+	}
+	return w, h
+}
+
+// draw replaces screen contents with lines, each is fit to width.
+func (s *screen) draw(lines []string) error {
+	w, h := s.size()
+	s.out.WriteString("\x1b[H")
+	for i := 0; i < h; i++ {
+		line := ""
+		if i < len(lines) {
+			line = lines[i]
+		}
+		s.out.WriteString(fit(line, w))
+		if i < h-1 {
+			s.out.WriteString("\r\n")
+		}
+	}
+	return s.out.Flush()
+}
+
+// readKey waits for key press.
+func (s *screen) readKey() (key, error) {
+	buf := make([]byte, 16)
+	n, err := os.Stdin.Read(buf)
+	if err != nil {
+		return 0, err
+	}
+	b := buf[:n]
+	if b[0] == byte(keyEsc) && n > 1 {
+		if k, ok := escapes[string(b[1:])]; ok {
+			return k, nil
+		}
+		return keyUnknown, nil
+	}
+	r, _ := utf8.DecodeRune(b)
+	return key(r), nil
+}
+
+// fit cuts or pads line to width cells, color sequences take no space.
+func fit(line string, width int) string {
+	var (
+		b       bytes.Buffer
+		w       int
+		escape  bool
+		colored bool
+	)
+	for _, r := range line {
+		if escape || r == '\x1b' {
+			escape = r != 'm'
+			colored = true
+			b.WriteRune(r)
+			continue
+		}
+		if r == '\t' {
+			r = ' '
+		}
+		rw := runewidth.RuneWidth(r)
+		if w+rw > width {
+			break
+		}
+		b.WriteRune(r)
+		w += rw
+	}
+	if colored {
+		b.WriteString("\x1b[0m")
+	}
+	b.WriteString(strings.Repeat(" ", width-w))
+	return b.String()
+}
+
+// wrap splits text into lines of width cells.
+func wrap(text string, width int) []string {
+	if width < 10 {
+		width = 10
+	}
+	var lines []string
+	for _, l := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		l = strings.TrimRight(l, "\r ")
+		for runewidth.StringWidth(l) > width {
+			cut := cells(l, width)
+			if i := strings.LastIndex(cut, " "); i > 0 {
+				cut = cut[:i]
+			}
+			lines = append(lines, cut)
+			l = strings.TrimLeft(l[len(cut):], " ")
+		}
+		lines = append(lines, l)
+	}
+	return lines
+}
+
+// cells returns prefix of s which takes at most width cells.
+func cells(s string, width int) string {
+	w := 0
+	for i, r := range s {
+		if w += runewidth.RuneWidth(r); w > width {
+			return s[:i]
+		}
+	}
+	return s
+}
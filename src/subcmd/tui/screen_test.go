@@ -0,0 +1,32 @@
+package tui
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFit(t *testing.T) {
+	tests := []struct {
+		line  string
+		width int
+		want  string
+	}{
+		{"abc", 5, "abc  "},
+		{"abcdef", 4, "abcd"},
+		{"日本語", 5, "日本 "},
+		{"\x1b[1mkey\x1b[0m value", 6, "\x1b[1mkey\x1b[0m va\x1b[0m"},
+	}
+	for _, tt := range tests {
+		if got := fit(tt.line, tt.width); got != tt.want {
+			t.Errorf("fit(%q, %d) = %q, expected %q", tt.line, tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestWrap(t *testing.T) {
+	got := wrap("first line is long enough\n\nsecond", 12)
+	want := []string{"first line", "is long", "enough", "", "second"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
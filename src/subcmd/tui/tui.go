@@ -0,0 +1,224 @@
+// Package tui is an interactive terminal UI to browse GitLab projects and
+// issues side by side with linked Jira tickets, and to act on them.
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"lib/git"
+	"lib/interrupt"
+	"lib/jira"
+	"lib/logging"
+	"lib/storage"
+	"lib/theme"
+	"subcmd/config"
+
+	"github.com/mattn/go-runewidth"
+)
+
+type Cmd struct {
+	Project  string `short:"p" long:"project" description:"start at issues of GitLab project"`
+	Assigned bool   `short:"a" long:"assigned" description:"start at GitLab issues assigned to you"`
+}
+
+func (c *Cmd) Execute(argv []string) error {
+	return process(interrupt.Context(), c)
+}
+
+// view is a screen of UI, views are stacked as user goes deeper.
+type view interface {
+	title() string
+	// render returns lines of body, height is the number of lines for it
+	render(a *app, width, height int) []string
+	// handle reacts on key, false is returned if key isn't known to view
+	handle(a *app, k key) bool
+	help() string
+}
+
+type app struct {
+	ctx    context.Context
+	cfg    *config.Config
+	disk   *storage.Storage
+	git    *git.Git
+	jira   *jira.Jira // nil if Jira is not configured
+	scr    *screen
+	views  []view
+	status string
+}
+
+func process(ctx context.Context, c *Cmd) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	disk, err := storage.NewStorage(cfg.Storage.Path)
+	if err != nil {
+		return err
+	}
+	defer disk.Close()
+
+	a := &app{ctx: ctx, cfg: cfg, disk: disk}
+	if a.git, err = git.NewWithStorage(disk); err != nil {
+		return err
+	}
+	// credentials are asked for before terminal is taken
+	if err := a.git.InitClient(); err != nil {
+		return err
+	}
+	if cfg.Jira.Address != "" {
+		if a.jira, err = jira.NewWithStorage(disk); err != nil {
+			return err
+		}
+		if err := a.jira.InitClient(); err != nil {
+			return err
+		}
+	}
+
+	if a.scr, err = newScreen(); err != nil {
+		return err
+	}
+	defer a.scr.close()
+	// warnings would break the screen, they are shown in status line
+	restore := logging.Redirect(statusWriter{a})
+	defer restore()
+
+	switch {
+	case c.Project != "":
+		p, err := a.git.ProjectByName(ctx, c.Project, false, false)
+		if err != nil {
+			return err
+		}
+		a.push(newIssuesView(p))
+	case c.Assigned:
+		a.push(newIssuesView(nil))
+	default:
+		a.push(newProjectsView())
+	}
+	return a.loop()
+}
+
+func (a *app) loop() error {
+	for len(a.views) > 0 {
+		if err := a.draw(); err != nil {
+			return err
+		}
+		k, err := a.scr.readKey()
+		if err != nil {
+			return err
+		}
+		if k == keyCtrlC {
+			return nil
+		}
+		a.status = ""
+		v := a.views[len(a.views)-1]
+		switch {
+		case v.handle(a, k):
+		case k == 'q' || k == keyEsc:
+			a.views = a.views[:len(a.views)-1]
+		case k == 'Q':
+			return nil
+		}
+	}
+	return nil
+}
+
+// push shows view, which is loaded first if it's loadable.
+func (a *app) push(v view) {
+	a.views = append(a.views, v)
+	if l, ok := v.(loader); ok {
+		a.reload(l)
+	}
+}
+
+// loader is a view which fetches its data.
+type loader interface {
+	load(a *app) error
+}
+
+func (a *app) reload(l loader) {
+	const loading = "Loading..."
+	a.busy(loading)
+	if err := l.load(a); err != nil {
+		a.fail(err)
+		return
+	}
+	if a.status == loading {
+		a.status = ""
+	}
+}
+
+func (a *app) draw() error {
+	w, h := a.scr.size()
+	if h < 6 {
+		return a.scr.draw([]string{"terminal is too small"})
+	}
+	v := a.views[len(a.views)-1]
+	lines := []string{theme.Key(" jigit ") + theme.Time("›") + " " + v.title(), ""}
+	lines = append(lines, v.render(a, w, h-4)...)
+	for len(lines) < h-2 {
+		lines = append(lines, "")
+	}
+	lines = append(lines[:h-2], theme.Time(strings.Repeat("─", w)), a.bottom(v))
+	return a.scr.draw(lines)
+}
+
+// bottom returns status if there is one, help otherwise.
+func (a *app) bottom(v view) string {
+	if a.status != "" {
+		return " " + a.status
+	}
+	return theme.Time(" " + v.help() + "  q back  Q quit")
+}
+
+// busy shows status at once, before long operation starts.
+func (a *app) busy(msg string) {
+	a.status = msg
+	a.draw()
+}
+
+func (a *app) fail(err error) {
+	a.status = fmt.Sprintf("error: %s", strings.Replace(err.Error(), "\n", " ", -1))
+}
+
+// prompt reads line of text in status line, false is returned if user
+// cancels it with Esc.
+func (a *app) prompt(label, value string) (string, bool) {
+	input := []rune(value)
+	for {
+		a.status = label + string(input) + "█"
+		if err := a.draw(); err != nil {
+			return "", false
+		}
+		k, err := a.scr.readKey()
+		if err != nil {
+			return "", false
+		}
+		switch {
+		case k == keyEnter:
+			a.status = ""
+			return strings.TrimSpace(string(input)), true
+		case k == keyEsc || k == keyCtrlC:
+			a.status = ""
+			return "", false
+		case k == keyBackspace || k == 8:
+			if len(input) > 0 {
+				input = input[:len(input)-1]
+			}
+		case k >= ' ' && runewidth.RuneWidth(rune(k)) > 0:
+			input = append(input, rune(k))
+		}
+	}
+}
+
+// statusWriter shows log records in status line.
+type statusWriter struct {
+	a *app
+}
+
+func (w statusWriter) Write(b []byte) (int, error) {
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	w.a.status = lines[len(lines)-1]
+	return len(b), nil
+}
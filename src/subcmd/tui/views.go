@@ -0,0 +1,284 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"lib/git"
+	"lib/theme"
+	"lib/util"
+)
+
+// issueLimit keeps listings quick, filters narrow them down.
+const issueLimit = 200
+
+// menu is a scrollable list of rows, text of rows is matched by filter.
+type menu struct {
+	rows   []string
+	texts  []string
+	shown  []int
+	filter string
+	cur    int
+	top    int
+	height int
+}
+
+func (l *menu) set(rows, texts []string) {
+	l.rows, l.texts = rows, texts
+	l.apply()
+}
+
+func (l *menu) apply() {
+	l.shown = l.shown[:0]
+	f := strings.ToLower(l.filter)
+	for i, t := range l.texts {
+		if f == "" || strings.Contains(strings.ToLower(t), f) {
+			l.shown = append(l.shown, i)
+		}
+	}
+	l.move(0)
+}
+
+func (l *menu) move(d int) {
+	l.cur += d
+	if l.cur >= len(l.shown) {
+		l.cur = len(l.shown) - 1
+	}
+	if l.cur < 0 {
+		l.cur = 0
+	}
+}
+
+// selected returns index of selected row, -1 if there are no rows.
+func (l *menu) selected() int {
+	if len(l.shown) == 0 {
+		return -1
+	}
+	return l.shown[l.cur]
+}
+
+func (l *menu) handle(a *app, k key) bool {
+	switch k {
+	case keyUp, 'k':
+		l.move(-1)
+	case keyDown, 'j':
+		l.move(1)
+	case keyPageUp:
+		l.move(-l.height)
+	case keyPageDown:
+		l.move(l.height)
+	case keyHome, 'g':
+		l.move(-len(l.shown))
+	case keyEnd, 'G':
+		l.move(len(l.shown))
+	case '/':
+		if f, ok := a.prompt("Filter: ", l.filter); ok {
+			l.filter = f
+			l.cur = 0
+			l.apply()
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+func (l *menu) render(width, height int) []string {
+	l.height = height
+	if len(l.shown) == 0 {
+		return []string{theme.Time("  nothing to show")}
+	}
+	if l.cur < l.top {
+		l.top = l.cur
+	}
+	if l.cur >= l.top+height {
+		l.top = l.cur - height + 1
+	}
+	lines := make([]string, 0, height)
+	for i := l.top; i < len(l.shown) && i < l.top+height; i++ {
+		n := l.shown[i]
+		if i == l.cur {
+			lines = append(lines, theme.Selected(fit("> "+l.texts[n], width)))
+			continue
+		}
+		lines = append(lines, "  "+l.rows[n])
+	}
+	return lines
+}
+
+// filterHint shows active filter in title.
+func (l *menu) filterHint() string {
+	if l.filter == "" {
+		return ""
+	}
+	return fmt.Sprintf(" matching '%s'", l.filter)
+}
+
+type projectsView struct {
+	projects []*git.Project
+	list     menu
+	fresh    bool
+}
+
+func newProjectsView() *projectsView {
+	return new(projectsView)
+}
+
+func (v *projectsView) title() string {
+	return "GitLab projects" + v.list.filterHint()
+}
+
+func (v *projectsView) help() string {
+	return "↑↓ move  / filter  enter issues  a assigned to me  r refresh"
+}
+
+func (v *projectsView) load(a *app) error {
+	projects, err := a.git.ListProjects(a.ctx, 0, v.fresh)
+	if err != nil {
+		return err
+	}
+	v.projects, v.fresh = projects, false
+	rows := make([]string, len(projects))
+	for i, p := range projects {
+		rows[i] = fmt.Sprintf("%-32s %s", util.TruncateString(p.Name, 29),
+			strings.Replace(p.Description, "\n", " ", -1))
+	}
+	v.list.set(rows, rows)
+	return nil
+}
+
+func (v *projectsView) render(a *app, width, height int) []string {
+	return v.list.render(width, height)
+}
+
+func (v *projectsView) handle(a *app, k key) bool {
+	switch k {
+	case keyEnter:
+		if n := v.list.selected(); n >= 0 {
+			a.push(newIssuesView(v.projects[n]))
+		}
+	case 'a':
+		a.push(newIssuesView(nil))
+	case 'r':
+		v.fresh = true
+		a.reload(v)
+	default:
+		return v.list.handle(a, k)
+	}
+	return true
+}
+
+// issuesView lists issues of project, or issues assigned to user across
+// projects if project is nil.
+type issuesView struct {
+	project *git.Project
+	all     bool
+	mine    bool
+	issues  []*git.Issue
+	names   map[interface{}]string
+	list    menu
+}
+
+func newIssuesView(p *git.Project) *issuesView {
+	return &issuesView{project: p}
+}
+
+func (v *issuesView) title() string {
+	t := "Issues assigned to you"
+	if v.project != nil {
+		t = "Issues of " + v.project.Name
+		if v.mine {
+			t += " assigned to you"
+		}
+	}
+	if !v.all {
+		t = "Open " + strings.ToLower(t[:1]) + t[1:]
+	}
+	return t + v.list.filterHint()
+}
+
+func (v *issuesView) help() string {
+	h := "↑↓ move  / filter  enter open  s all states  r refresh"
+	if v.project != nil {
+		h += "  m mine"
+	}
+	return h
+}
+
+func (v *issuesView) load(a *app) error {
+	f := &git.IssueFilter{State: "opened", Scope: git.ScopeAssignedToMe, Limit: issueLimit}
+	if v.all {
+		f.State = ""
+	}
+	if v.project != nil {
+		f.ProjectID = v.project.ID
+		if !v.mine {
+			f.Scope = ""
+		}
+	}
+	var issues []*git.Issue
+	err := a.git.EachIssue(a.ctx, f, func(page []*git.Issue) error {
+		issues = append(issues, page...)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	pids := make([]interface{}, len(issues))
+	for i, issue := range issues {
+		pids[i] = issue.ProjectID
+	}
+	v.issues, v.names = issues, a.git.ProjectNames(a.ctx, pids)
+
+	rows := make([]string, len(issues))
+	texts := make([]string, len(issues))
+	for i, issue := range issues {
+		prefix := ""
+		if v.project == nil {
+			prefix = fmt.Sprintf("%-24s ", util.TruncateString(v.names[issue.ProjectID], 21))
+		}
+		state := fmt.Sprintf("%-7s", issue.State)
+		labels, colored := "", ""
+		if len(issue.Labels) > 0 {
+			labels = " [" + strings.Join(issue.Labels, ", ") + "]"
+			colored = " [" + theme.Labels(issue.Labels) + "]"
+		}
+		texts[i] = fmt.Sprintf("%s#%-5d %s %s%s", prefix, issue.IID, state, issue.Title, labels)
+		rows[i] = fmt.Sprintf("%s%s %s %s%s", prefix, theme.Key(fmt.Sprintf("#%-5d", issue.IID)),
+			theme.State(string(issue.State))+state[len(issue.State):], issue.Title, colored)
+	}
+	v.list.set(rows, texts)
+	return nil
+}
+
+func (v *issuesView) render(a *app, width, height int) []string {
+	return v.list.render(width, height)
+}
+
+func (v *issuesView) handle(a *app, k key) bool {
+	switch k {
+	case keyEnter:
+		if n := v.list.selected(); n >= 0 {
+			issue := v.issues[n]
+			pid, _ := issue.ProjectID.(int)
+			if v.project != nil {
+				pid = v.project.ID
+			}
+			a.push(newIssueView(v.project, v.names[issue.ProjectID], pid, issue.IID))
+		}
+	case 's':
+		v.all = !v.all
+		a.reload(v)
+	case 'm':
+		if v.project == nil {
+			return false
+		}
+		v.mine = !v.mine
+		a.reload(v)
+	case 'r':
+		a.reload(v)
+	default:
+		return v.list.handle(a, k)
+	}
+	return true
+}
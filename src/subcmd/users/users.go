@@ -0,0 +1,178 @@
+package users
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"lib/git"
+	"lib/interrupt"
+	"lib/jira"
+	"lib/storage"
+	libusers "lib/users"
+	"lib/util"
+	"subcmd/config"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/pkg/errors"
+)
+
+type Cmd struct {
+	Ls   LsCmd   `command:"ls" description:"list known GitLab and Jira user pairs"`
+	Add  AddCmd  `command:"add" description:"link GitLab user with Jira user: jigit users add GITLAB_LOGIN JIRA_NAME (account ID on Jira Cloud)"`
+	Drop DropCmd `command:"drop" description:"remove link of GitLab user: jigit users drop GITLAB_LOGIN"`
+	Sync SyncCmd `command:"sync" description:"link GitLab and Jira users with the same email"`
+}
+
+type LsCmd struct{}
+
+func (c *LsCmd) Execute(argv []string) error {
+	return withMap(func(disk *storage.Storage, m *libusers.Map) error {
+		pairs, err := m.Pairs()
+		if err != nil {
+			return err
+		}
+		if len(pairs) == 0 {
+			fmt.Println("No users linked yet. Use 'jigit users add' or 'jigit users sync'.")
+			return nil
+		}
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"GitLab", "Jira"})
+		table.SetAutoFormatHeaders(false)
+		table.SetBorder(false)
+		for _, p := range pairs {
+			table.Append([]string{"@" + p.GitLogin, p.JiraName})
+		}
+		table.Render()
+		return nil
+	})
+}
+
+type AddCmd struct{}
+
+func (c *AddCmd) Execute(argv []string) error {
+	if len(argv) != 2 {
+		return util.Usage("Provide GitLab username and Jira user name: jigit users add GITLAB_LOGIN JIRA_NAME\n" +
+			"On Jira Cloud users have no names, provide account ID instead.")
+	}
+	login, name := strings.TrimPrefix(argv[0], "@"), argv[1]
+	return withMap(func(disk *storage.Storage, m *libusers.Map) error {
+		if err := m.Set(login, name); err != nil {
+			return err
+		}
+		fmt.Printf("GitLab user @%s has been linked with Jira user %s.\n", login, name)
+		return nil
+	})
+}
+
+type DropCmd struct{}
+
+func (c *DropCmd) Execute(argv []string) error {
+	if len(argv) == 0 {
+		return util.Usage("Provide GitLab username to unlink: jigit users drop GITLAB_LOGIN")
+	}
+	return withMap(func(disk *storage.Storage, m *libusers.Map) error {
+		for _, arg := range argv {
+			login := strings.TrimPrefix(arg, "@")
+			if err := m.Drop(login); err != nil {
+				return errors.Wrapf(err, "can't unlink @%s", login)
+			}
+			fmt.Printf("GitLab user @%s has been unlinked.\n", login)
+		}
+		return nil
+	})
+}
+
+type SyncCmd struct {
+	ByLogin bool `long:"by-login" description:"link users with the same login if email is not visible"`
+	Force   bool `short:"f" long:"force" description:"overwrite already linked users"`
+	DryRun  bool `long:"dry-run" description:"print found pairs without saving them"`
+}
+
+func (c *SyncCmd) Execute(argv []string) error {
+	ctx := interrupt.Context()
+	return withMap(func(disk *storage.Storage, m *libusers.Map) error {
+		gitc, err := git.NewWithStorage(disk)
+		if err != nil {
+			return err
+		}
+		jirac, err := jira.NewWithStorage(disk)
+		if err != nil {
+			return err
+		}
+
+		gitUsers, err := gitc.ListUsers(ctx)
+		if err != nil {
+			return err
+		}
+
+		var linked int
+		for _, u := range gitUsers {
+			if _, ok := m.JiraName(u.Login); ok && !c.Force {
+				continue
+			}
+			match, err := c.match(ctx, jirac, u)
+			if err != nil {
+				return err
+			}
+			if match == nil {
+				continue
+			}
+
+			key := jirac.UserKey(match)
+			fmt.Printf("@%s (%s) -> %s (%s)\n", u.Login, u.Name, key, match.DisplayName)
+			linked++
+			if c.DryRun {
+				continue
+			}
+			if err := m.Set(u.Login, key); err != nil {
+				return err
+			}
+		}
+		fmt.Printf("\n%d of %d GitLab users matched.\n", linked, len(gitUsers))
+		return nil
+	})
+}
+
+// match returns the only Jira user with the same email (or login, if asked).
+func (c *SyncCmd) match(ctx context.Context, jirac *jira.Jira, u *git.User) (*jira.User, error) {
+	if u.Email != "" {
+		found, err := jirac.FindUsers(ctx, u.Email)
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < len(found); i++ {
+			if strings.EqualFold(found[i].EmailAddress, u.Email) {
+				return &found[i], nil
+			}
+		}
+	}
+	if !c.ByLogin {
+		return nil, nil
+	}
+	found, err := jirac.FindUsers(ctx, u.Login)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < len(found); i++ {
+		if found[i].Name == u.Login {
+			return &found[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func withMap(fn func(disk *storage.Storage, m *libusers.Map) error) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	disk, err := storage.NewStorage(cfg.Storage.Path)
+	if err != nil {
+		return err
+	}
+	defer disk.Close()
+
+	return fn(disk, libusers.New(disk))
+}